@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/accrual/emulator"
+)
+
+func main() {
+	var c emulator.Config
+	var address, rules string
+
+	flag.StringVar(&address, "a", "localhost:8081", "emulator address and port")
+	flag.StringVar(
+		&rules, "rules", ":10", "Comma separated suffix:percent reward rules, empty suffix matches every order",
+	)
+	flag.DurationVar(&c.ProcessingDelay, "delay", time.Second*5, "Orders are processed after this duration")
+	flag.Float64Var(&c.NoContentRate, "no-content-rate", 0, "Probability of 204 response")
+	flag.Float64Var(&c.ErrorRate, "error-rate", 0, "Probability of 500 response")
+	flag.IntVar(&c.RateLimit, "rate-limit", 0, "Requests allowed per minute (0 - no limit)")
+	flag.Int64Var(&c.Seed, "seed", time.Now().UnixNano(), "Seed of random responses")
+	flag.Parse()
+
+	parsedRules, parseErr := emulator.ParseRules(rules)
+	if parseErr != nil {
+		log.Fatalln(parseErr)
+	}
+	c.Rules = parsedRules
+
+	log.Printf("Accrual emulator is listening on %s\n", address)
+	log.Fatalln(http.ListenAndServe(address, emulator.New(c).Handler()))
+}
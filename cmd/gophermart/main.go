@@ -1,6 +1,9 @@
 package main
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/bobgromozeka/yp-diploma1/internal/app"
 	"github.com/bobgromozeka/yp-diploma1/internal/server/config"
 )
@@ -9,9 +12,12 @@ func main() {
 	c := config.Get()
 
 	parseFlags(&c)
-	parseEnv(&c)
+	if envErr := parseEnv(&c); envErr != nil {
+		fmt.Println(envErr)
+		os.Exit(1)
+	}
 
 	config.Set(c)
 
-	app.Start(c)
+	os.Exit(app.Start(c))
 }
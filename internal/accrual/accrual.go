@@ -3,54 +3,113 @@ package accrual
 import (
 	"context"
 	"errors"
-	"fmt"
-	"net/http"
+	"net"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/go-resty/resty/v2"
-
 	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	"github.com/bobgromozeka/yp-diploma1/internal/circuit"
 	"github.com/bobgromozeka/yp-diploma1/internal/models"
 	"github.com/bobgromozeka/yp-diploma1/internal/server/config"
 )
 
 type Client struct {
-	d dependencies.D
-	c *resty.Client
+	d    dependencies.D
+	p    AccrualProvider
+	jobs chan job
+	// priorityJobs are taken by workers before regular ones
+	priorityJobs chan job
+	limiter      *aimd
+	breaker      *circuit.Breaker
+	concurrency  int
+}
+
+// job is update of single order. Jobs of the same batch share batch.
+type job struct {
+	order models.Order
+	batch *batch
 }
 
-type accrualOrderResponse struct {
-	Order   string
-	Status  string
-	Accrual *float64
+type batch struct {
+	wg sync.WaitGroup
+	//rest of batch is skipped after accrual system asked to slow down
+	throttled atomic.Bool
 }
 
 const RequestTimeout = time.Second * 5
 const OrdersBatchSize = 100
+const DefaultConcurrency = 10
 
-var ErrTooManyRequests = errors.New("too many requests")
+// Accrual system is not requested during BreakerCooldown after BreakerThreshold failed requests in a row.
+const BreakerThreshold = 5
+const BreakerCooldown = time.Second * 30
 
-func New(d dependencies.D, accrualAddr string) Client {
-	c := resty.New().
-		SetBaseURL(accrualAddr).
-		SetTimeout(RequestTimeout)
+// PollInterval prevents db bombing. When accrual system pushes results to callback endpoint
+// polling only reconciles missed callbacks, so it runs less often.
+const PollInterval = time.Second * 2
+const ReconcileInterval = time.Minute
+
+// Orders which first check was requested earlier than PriorityStaleAfter ago are claimed from db every
+// PriorityClaimInterval, so orders accepted by other (maybe stopped) replicas are checked early as well.
+const PriorityClaimInterval = time.Second
+const PriorityStaleAfter = time.Second * 5
+const PriorityQueueSize = 1024
+
+func New(d dependencies.D, p AccrualProvider) Client {
+	concurrency := config.Get().AccrualConcurrency
+	if concurrency < 1 {
+		concurrency = DefaultConcurrency
+	}
+
+	breaker := d.AccrualBreaker
+	if breaker == nil {
+		breaker = NewBreaker(d)
+	}
 
 	return Client{
-		d: d,
-		c: c,
+		d:            d,
+		p:            p,
+		jobs:         make(chan job),
+		priorityJobs: make(chan job),
+		limiter:      newAIMD(concurrency),
+		breaker:      breaker,
+		concurrency:  concurrency,
 	}
 }
 
-func (ac *Client) Start(shutdownCtx context.Context) {
+// Start polls accrual system until shutdownCtx is done. Started order updates are finished after that
+// unless forceCtx is done as well, so requested results are written to db.
+func (ac *Client) Start(shutdownCtx context.Context, forceCtx context.Context) {
+	stopWorkers := ac.startWorkers(shutdownCtx, forceCtx)
+	defer stopWorkers()
+
+	priorityDone := make(chan struct{})
+	go func() {
+		ac.runPriorityChecks(shutdownCtx)
+		close(priorityDone)
+	}()
+	//workers must be stopped after the last priority batch
+	defer func() { <-priorityDone }()
+
 	for {
 		ac.DoUpdatesIteration(shutdownCtx)
 
-		if shutdownCtx.Err() != nil {
-			break
+		select {
+		case <-shutdownCtx.Done():
+			return
+		case <-time.After(pollInterval()):
 		}
+	}
+}
 
-		time.Sleep(time.Second * 2) //wait 2 seconds before next iteration to prevent db bombing
+// pollInterval is short for push provider, it serves results of callbacks which are applied only by polling.
+func pollInterval() time.Duration {
+	if config.Get().AccrualCallbackSecret != "" && config.Get().AccrualProvider != ProviderPush {
+		return ReconcileInterval
 	}
+
+	return PollInterval
 }
 
 func (ac *Client) DoUpdatesIteration(shutdownCtx context.Context) {
@@ -62,75 +121,234 @@ func (ac *Client) DoUpdatesIteration(shutdownCtx context.Context) {
 	}
 }
 
-func (ac *Client) runOrderUpdates(shutdownCtx context.Context, orders []models.Order) {
-	ordersChan := make(chan models.Order)
-	minRequests := int(time.Duration(60) * time.Second / RequestTimeout) //theoretical minimum requests per minute
-	workersCount := len(orders) / minRequests
-	if len(orders)%minRequests > 0 {
-		workersCount += 1
-	}
+// NewBreaker creates accrual system circuit breaker which logs its state changes.
+func NewBreaker(d dependencies.D) *circuit.Breaker {
+	b := circuit.New(BreakerThreshold, BreakerCooldown)
+	b.OnStateChange(
+		func(from string, to string) {
+			d.Logger.Warnw("Accrual system circuit state changed", "from", from, "to", to)
+		},
+	)
+
+	return b
+}
 
-	for i := 0; i < workersCount; i++ {
+// startWorkers starts long-lived workers. Returned stop func must be called after the last batch and waits
+// for workers to exit.
+func (ac *Client) startWorkers(shutdownCtx context.Context, forceCtx context.Context) (stop func()) {
+	wg := sync.WaitGroup{}
+	for i := 0; i < ac.concurrency; i++ {
+		wg.Add(1)
 		go func() {
-			for order := range ordersChan {
-				if ac.updateOrder(shutdownCtx, order) == ErrTooManyRequests {
-					break
+			defer wg.Done()
+			for {
+				j, ok := ac.nextJob()
+				if !ok {
+					return
 				}
+				ac.process(shutdownCtx, forceCtx, j)
+				j.batch.wg.Done()
 			}
 		}()
 	}
 
+	return func() {
+		close(ac.jobs)
+		wg.Wait()
+	}
+}
+
+// nextJob prefers priority jobs. It returns false after workers are stopped.
+func (ac *Client) nextJob() (job, bool) {
+	select {
+	case j := <-ac.priorityJobs:
+		return j, true
+	default:
+	}
+
+	select {
+	case j := <-ac.priorityJobs:
+		return j, true
+	case j, ok := <-ac.jobs:
+		return j, ok
+	}
+}
+
+// RequestPriorityCheck enqueues uploaded order for early check. It never blocks: orders which don't fit into
+// queue are claimed from db a bit later.
+func RequestPriorityCheck(d dependencies.D, number string) {
+	select {
+	case d.PriorityChecks <- number:
+	default:
+	}
+}
+
+// runPriorityChecks checks orders enqueued on upload ahead of regular polling.
+func (ac *Client) runPriorityChecks(shutdownCtx context.Context) {
+	ticker := time.NewTicker(PriorityClaimInterval)
+	defer ticker.Stop()
+
+	for {
+		var orders []models.Order
+		var claimErr error
+
+		select {
+		case <-shutdownCtx.Done():
+			return
+		case number := <-ac.d.PriorityChecks:
+			numbers := []string{number}
+			//orders uploaded together are claimed and checked together
+		collect:
+			for len(numbers) < OrdersBatchSize {
+				select {
+				case number = <-ac.d.PriorityChecks:
+					numbers = append(numbers, number)
+				default:
+					break collect
+				}
+			}
+			orders, claimErr = ac.d.OrdersStorage.ClaimPriorityChecks(shutdownCtx, numbers)
+		case <-ticker.C:
+			orders, claimErr = ac.d.OrdersStorage.ClaimStalePriorityChecks(
+				shutdownCtx, time.Now().Add(-PriorityStaleAfter), OrdersBatchSize,
+			)
+		}
+
+		if claimErr != nil {
+			if !errors.Is(claimErr, context.Canceled) {
+				ac.d.Logger.Error(claimErr)
+			}
+			continue
+		}
+		ac.runBatch(shutdownCtx, ac.priorityJobs, orders)
+	}
+}
+
+func (ac *Client) runOrderUpdates(shutdownCtx context.Context, orders []models.Order) {
+	ac.runBatch(shutdownCtx, ac.jobs, orders)
+}
+
+// runBatch returns after every order of batch is processed or skipped.
+func (ac *Client) runBatch(shutdownCtx context.Context, queue chan<- job, orders []models.Order) {
+	b := &batch{}
+
 	for _, order := range orders {
-		ordersChan <- order
+		b.wg.Add(1)
+		select {
+		case queue <- job{order: order, batch: b}:
+		case <-shutdownCtx.Done():
+			b.wg.Done()
+			b.wg.Wait()
+			return
+		}
 	}
-	close(ordersChan)
+
+	b.wg.Wait()
 }
 
-func (ac *Client) updateOrder(ctx context.Context, order models.Order) error {
-	orderResponse := accrualOrderResponse{}
-	response, err := ac.c.R().
-		SetResult(&orderResponse).
-		SetContext(ctx).
-		Get("/api/orders/" + order.Number)
-	fmt.Println(orderResponse)
-	if err != nil {
-		ac.d.Logger.Error("Error during requesting accrual system: " + err.Error())
-		return err
+// process skips not started jobs after shutdown. Started ones run with forceCtx.
+func (ac *Client) process(shutdownCtx context.Context, forceCtx context.Context, j job) {
+	if shutdownCtx.Err() != nil || j.batch.throttled.Load() {
+		return
+	}
+	if acquireErr := ac.limiter.Acquire(shutdownCtx); acquireErr != nil {
+		return
 	}
-	defer response.RawBody().Close()
+	if !ac.breaker.Allow() {
+		ac.limiter.Release(false)
+		ac.d.Logger.Debugw("Accrual system circuit is open, order update is skipped", "order_number", j.order.Number)
+		return
+	}
+
+	err := ac.updateOrder(forceCtx, j.order)
+	throttled := errors.Is(err, ErrTooManyRequests)
+	if throttled {
+		j.batch.throttled.Store(true)
+	}
+	var retryAfterErr *RetryAfterError
+	if errors.As(err, &retryAfterErr) {
+		ac.limiter.Pause(time.Now().Add(retryAfterErr.Delay))
+	}
+	ac.limiter.Release(throttled || isTimeout(err))
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		ac.breaker.Ignore()
+	case err == nil, throttled, errors.Is(err, ErrOrderNotRegistered):
+		//accrual system is up even if it asks to slow down or does not know the order
+		ac.breaker.Record(true)
+	default:
+		ac.breaker.Record(false)
+	}
+}
 
-	switch response.StatusCode() {
-	case http.StatusTooManyRequests:
+func isTimeout(err error) bool {
+	var netErr net.Error
+
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout())
+}
+
+func (ac *Client) updateOrder(ctx context.Context, order models.Order) error {
+	result, err := ac.p.GetOrder(ctx, order.Number)
+	switch {
+	case err == nil:
+		if updateErr := Apply(ctx, ac.d, result); updateErr != nil {
+			ac.d.Logger.Errorw("Could not update order from accrual response", "err", updateErr, "response", result)
+		} else if acknowledger, ok := ac.p.(Acknowledger); ok {
+			if ackErr := acknowledger.Ack(ctx, result); ackErr != nil {
+				ac.d.Logger.Errorw("Could not acknowledge applied accrual result", "err", ackErr, "response", result)
+			}
+		}
+	case errors.Is(err, ErrTooManyRequests):
 		ac.d.Logger.Infow("Too many requests to accrual system", "order", order)
-		return ErrTooManyRequests
-	case http.StatusNoContent:
+	case errors.Is(err, ErrOrderNotRegistered):
 		ac.d.Logger.Infof("No order %s in accrual system.", order.Number)
-	case http.StatusInternalServerError:
+	case errors.Is(err, ErrProviderFailure):
 		ac.d.Logger.Infow("Accrual system returned internal server error", "order_number", order.Number)
-	case http.StatusOK:
-		updateErr := ac.d.OrdersStorage.UpdateOrderStatus(
-			ctx, order.Number, orderResponse.Status, orderResponse.Accrual,
-		)
-		if updateErr != nil {
-			ac.d.Logger.Errorw(
-				"Could not update order from accrual response", "err", updateErr, "response", orderResponse,
-			)
-		}
 	default:
-		ac.d.Logger.Errorf("Unknown status - %d", response.StatusCode())
+		ac.d.Logger.Error("Error during requesting accrual system: " + err.Error())
 	}
 
-	return nil
+	return err
 }
 
-func Run(shutdownCtx context.Context, d dependencies.D) {
-	ac := New(d, config.Get().AccrualSystemAddress)
+// Apply updates order from accrual result. It is used for both polled and pushed results.
+func Apply(ctx context.Context, d dependencies.D, r Result) error {
+	return d.OrdersStorage.UpdateOrderStatus(
+		ctx, r.Order, orderStatus(r.Status), r.Accrual, accrualTerms(time.Now()),
+	)
+}
 
-	ac.Start(shutdownCtx)
+// orderStatus maps accrual system status to order status. Registered order is processing already, it must stay
+// in polled statuses until it is final.
+func orderStatus(status string) string {
+	if status == StatusRegistered {
+		return models.OrderStatusProcessing
+	}
 
-	d.Logger.Info("Stopping accrual client.....")
+	return status
 }
 
-func (ac *Client) SetClient(newClient *resty.Client) {
-	ac.c = newClient
+func accrualTerms(accruedAt time.Time) models.AccrualTerms {
+	var terms models.AccrualTerms
+
+	if hold := config.Get().AccrualHoldPeriod; hold > 0 {
+		releaseAt := accruedAt.Add(hold)
+		terms.ReleaseAt = &releaseAt
+	}
+
+	if ttl := config.Get().PointsTTL; ttl > 0 {
+		expiresAt := accruedAt.Add(ttl)
+		terms.ExpiresAt = &expiresAt
+	}
+
+	return terms
+}
+
+func Run(shutdownCtx context.Context, forceCtx context.Context, d dependencies.D, p AccrualProvider) {
+	ac := New(d, p)
+
+	ac.Start(shutdownCtx, forceCtx)
+
+	d.Logger.Info("Stopping accrual client.....")
 }
@@ -2,19 +2,26 @@ package accrual
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"regexp"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/go-resty/resty/v2"
 	"github.com/golang/mock/gomock"
 	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
 
+	"github.com/bobgromozeka/yp-diploma1/internal/accrual/emulator"
 	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	"github.com/bobgromozeka/yp-diploma1/internal/circuit"
 	"github.com/bobgromozeka/yp-diploma1/internal/models"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/config"
 	"github.com/bobgromozeka/yp-diploma1/internal/storage"
 	mock_storage "github.com/bobgromozeka/yp-diploma1/internal/storage/mock"
 	"github.com/bobgromozeka/yp-diploma1/internal/testutils"
@@ -25,9 +32,11 @@ type waitMockOrdersStorage struct {
 	Wg sync.WaitGroup
 }
 
-func (s *waitMockOrdersStorage) UpdateOrderStatus(ctx context.Context, order string, status string, accrual *float64) error {
+func (s *waitMockOrdersStorage) UpdateOrderStatus(
+	ctx context.Context, order string, status string, accrual *float64, terms models.AccrualTerms,
+) error {
 	defer s.Wg.Done()
-	return s.OrdersStorage.UpdateOrderStatus(ctx, order, status, accrual)
+	return s.OrdersStorage.UpdateOrderStatus(ctx, order, status, accrual, terms)
 }
 
 func TestAccrualUpdateOrder(t *testing.T) {
@@ -66,7 +75,10 @@ func TestAccrualUpdateOrder(t *testing.T) {
 		)
 	oStorage.
 		EXPECT().
-		UpdateOrderStatus(testutils.MatchContext(), gomock.Eq(orderNumber), gomock.Eq(newStatus), gomock.Eq(&accrual))
+		UpdateOrderStatus(
+			testutils.MatchContext(), gomock.Eq(orderNumber), gomock.Eq(newStatus), gomock.Eq(&accrual),
+			gomock.Eq(models.AccrualTerms{}),
+		)
 	waitOStorage := waitMockOrdersStorage{
 		oStorage,
 		sync.WaitGroup{},
@@ -81,8 +93,9 @@ func TestAccrualUpdateOrder(t *testing.T) {
 		Logger:             zap.NewExample().Sugar(),
 	}
 
-	ac := New(d, "")
-	ac.SetClient(restyC)
+	ac := New(d, NewHTTPProviderWithClient(restyC))
+	stopWorkers := ac.startWorkers(context.Background(), context.Background())
+	defer stopWorkers()
 
 	ac.DoUpdatesIteration(context.Background())
 
@@ -92,3 +105,569 @@ func TestAccrualUpdateOrder(t *testing.T) {
 	}()
 	waitOStorage.Wg.Wait()
 }
+
+func TestAccrualTerms(t *testing.T) {
+	accruedAt := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	config.Set(config.Config{})
+	assert.Equal(t, models.AccrualTerms{}, accrualTerms(accruedAt))
+
+	config.Set(config.Config{PointsTTL: time.Hour * 24 * 365, AccrualHoldPeriod: time.Hour * 24 * 14})
+	terms := accrualTerms(accruedAt)
+	if assert.NotNil(t, terms.ReleaseAt) && assert.NotNil(t, terms.ExpiresAt) {
+		assert.Equal(t, accruedAt.Add(time.Hour*24*14), *terms.ReleaseAt)
+		assert.Equal(t, accruedAt.Add(time.Hour*24*365), *terms.ExpiresAt)
+	}
+}
+
+func TestAccrualUpdateOrderFromEmulator(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := emulator.NewServer(emulator.Config{Rules: []emulator.Rule{{Percent: 10}}})
+	defer server.Close()
+
+	orderNumber := "4561261212345467"
+	oStorage := mock_storage.NewMockOrdersStorage(ctrl)
+	oStorage.
+		EXPECT().
+		GetLatestUnprocessedOrders(testutils.MatchContext(), gomock.Eq(OrdersBatchSize)).
+		Return([]models.Order{{ID: 1, UserID: 1, Number: orderNumber, Status: "NEW"}}, nil)
+	oStorage.
+		EXPECT().
+		UpdateOrderStatus(
+			testutils.MatchContext(), gomock.Eq(orderNumber), gomock.Eq(models.OrderStatusProcessed), gomock.Not(gomock.Nil()),
+			gomock.Any(),
+		)
+	waitOStorage := waitMockOrdersStorage{
+		oStorage,
+		sync.WaitGroup{},
+	}
+	waitOStorage.Wg.Add(1)
+
+	d := dependencies.D{
+		OrdersStorage: &waitOStorage,
+		Logger:        zap.NewExample().Sugar(),
+	}
+
+	config.Set(config.Config{})
+	ac := New(d, NewHTTPProvider(server.URL))
+	stopWorkers := ac.startWorkers(context.Background(), context.Background())
+	defer stopWorkers()
+
+	ac.DoUpdatesIteration(context.Background())
+
+	go func() {
+		time.Sleep(time.Second * 2)
+		waitOStorage.Wg.Done() //terminate wg if error occurred inside method
+	}()
+	waitOStorage.Wg.Wait()
+}
+
+func TestAccrualRegisteredOrderFromEmulatorIsProcessing(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	//order stays REGISTERED during the first half of delay
+	server := emulator.NewServer(emulator.Config{Rules: []emulator.Rule{{Percent: 10}}, ProcessingDelay: time.Hour})
+	defer server.Close()
+
+	orderNumber := "4561261212345467"
+	oStorage := mock_storage.NewMockOrdersStorage(ctrl)
+	oStorage.
+		EXPECT().
+		GetLatestUnprocessedOrders(testutils.MatchContext(), gomock.Eq(OrdersBatchSize)).
+		Return([]models.Order{{ID: 1, UserID: 1, Number: orderNumber, Status: "NEW"}}, nil)
+	oStorage.
+		EXPECT().
+		UpdateOrderStatus(
+			testutils.MatchContext(), gomock.Eq(orderNumber), gomock.Eq(models.OrderStatusProcessing), gomock.Nil(),
+			gomock.Any(),
+		)
+	waitOStorage := waitMockOrdersStorage{
+		oStorage,
+		sync.WaitGroup{},
+	}
+	waitOStorage.Wg.Add(1)
+
+	d := dependencies.D{
+		OrdersStorage: &waitOStorage,
+		Logger:        zap.NewExample().Sugar(),
+	}
+
+	config.Set(config.Config{})
+	ac := New(d, NewHTTPProvider(server.URL))
+	stopWorkers := ac.startWorkers(context.Background(), context.Background())
+	defer stopWorkers()
+
+	ac.DoUpdatesIteration(context.Background())
+
+	go func() {
+		time.Sleep(time.Second * 2)
+		waitOStorage.Wg.Done() //terminate wg if error occurred inside method
+	}()
+	waitOStorage.Wg.Wait()
+}
+
+func TestHTTPProviderGetOrder(t *testing.T) {
+	type testCase struct {
+		Name   string
+		Status int
+		Err    error
+	}
+
+	testCases := []testCase{
+		{Name: "Found", Status: http.StatusOK, Err: nil},
+		{Name: "Not registered", Status: http.StatusNoContent, Err: ErrOrderNotRegistered},
+		{Name: "Too many requests", Status: http.StatusTooManyRequests, Err: ErrTooManyRequests},
+		{Name: "Failure", Status: http.StatusInternalServerError, Err: ErrProviderFailure},
+	}
+
+	for _, tc := range testCases {
+		t.Run(
+			tc.Name, func(t *testing.T) {
+				mockTransport := httpmock.NewMockTransport()
+				restyC := resty.
+					NewWithClient(&http.Client{Transport: mockTransport}).
+					SetBaseURL("http://localhost")
+				mockTransport.RegisterResponder(
+					"GET", "http://localhost/api/orders/1234",
+					httpmock.NewStringResponder(tc.Status, `{"order":"1234","status":"PROCESSED","accrual":10}`).
+						HeaderAdd(map[string][]string{"Content-Type": {"application/json"}}),
+				)
+
+				result, err := NewHTTPProviderWithClient(restyC).GetOrder(context.Background(), "1234")
+
+				assert.ErrorIs(t, err, tc.Err)
+				if tc.Err == nil {
+					accrual := float64(10)
+					assert.Equal(t, Result{Order: "1234", Status: "PROCESSED", Accrual: &accrual}, result)
+				}
+			},
+		)
+	}
+}
+
+func TestHTTPProviderRetryAfter(t *testing.T) {
+	mockTransport := httpmock.NewMockTransport()
+	restyC := resty.
+		NewWithClient(&http.Client{Transport: mockTransport}).
+		SetBaseURL("http://localhost")
+	mockTransport.RegisterResponder(
+		"GET", "http://localhost/api/orders/1234",
+		httpmock.NewStringResponder(http.StatusTooManyRequests, "No more than N requests per minute allowed").
+			HeaderAdd(map[string][]string{"Retry-After": {"60"}}),
+	)
+
+	_, err := NewHTTPProviderWithClient(restyC).GetOrder(context.Background(), "1234")
+
+	var retryAfterErr *RetryAfterError
+	assert.ErrorIs(t, err, ErrTooManyRequests)
+	assert.ErrorAs(t, err, &retryAfterErr)
+	assert.Equal(t, time.Minute, retryAfterErr.Delay)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	type testCase struct {
+		Name   string
+		Header string
+		Delay  time.Duration
+		Ok     bool
+	}
+
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	testCases := []testCase{
+		{Name: "Seconds", Header: "120", Delay: time.Minute * 2, Ok: true},
+		{Name: "Date", Header: "Sun, 01 Jan 2023 12:00:30 GMT", Delay: time.Second * 30, Ok: true},
+		{Name: "Past date", Header: "Sun, 01 Jan 2023 11:00:00 GMT", Ok: false},
+		{Name: "Negative seconds", Header: "-1", Ok: false},
+		{Name: "Empty", Header: "", Ok: false},
+		{Name: "Garbage", Header: "soon", Ok: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(
+			tc.Name, func(t *testing.T) {
+				delay, ok := parseRetryAfter(tc.Header, now)
+				assert.Equal(t, tc.Ok, ok)
+				if tc.Ok {
+					assert.Equal(t, tc.Delay, delay)
+				}
+			},
+		)
+	}
+}
+
+func TestPushProviderGetOrder(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	accrual := float64(10)
+	processing := models.AccrualResult{Order: "1234", Status: models.OrderStatusProcessing}
+	processed := models.AccrualResult{Order: "1234", Status: models.OrderStatusProcessed, Accrual: &accrual}
+
+	rStorage := mock_storage.NewMockAccrualResultsStorage(ctrl)
+	gomock.InOrder(
+		rStorage.
+			EXPECT().
+			GetAccrualResult(testutils.MatchContext(), gomock.Eq("1234")).
+			Return(models.AccrualResult{}, storage.ErrAccrualResultNotFound),
+		rStorage.
+			EXPECT().
+			SaveAccrualResult(testutils.MatchContext(), gomock.Eq(processing)).
+			Return(nil),
+		rStorage.
+			EXPECT().
+			SaveAccrualResult(testutils.MatchContext(), gomock.Eq(processed)).
+			Return(nil),
+		rStorage.
+			EXPECT().
+			GetAccrualResult(testutils.MatchContext(), gomock.Eq("1234")).
+			Return(processed, nil),
+		rStorage.
+			EXPECT().
+			DeleteAccrualResult(testutils.MatchContext(), gomock.Eq(processed)).
+			Return(nil),
+	)
+
+	p := NewPushProvider(rStorage)
+
+	_, err := p.GetOrder(context.Background(), "1234")
+	assert.ErrorIs(t, err, ErrOrderNotRegistered)
+
+	feed := make(chan Result, 2)
+	feed <- Result{Order: "1234", Status: models.OrderStatusProcessing}
+	close(feed)
+	assert.NoError(t, p.Consume(context.Background(), feed))
+
+	assert.NoError(t, p.Push(context.Background(), Result{Order: "1234", Status: models.OrderStatusProcessed, Accrual: &accrual}))
+
+	result, err := p.GetOrder(context.Background(), "1234")
+	assert.NoError(t, err)
+	assert.Equal(t, Result{Order: "1234", Status: models.OrderStatusProcessed, Accrual: &accrual}, result)
+
+	//not final results are not deleted, they are replaced by the next push
+	assert.NoError(t, p.Ack(context.Background(), Result{Order: "1234", Status: models.OrderStatusProcessing}))
+	assert.NoError(t, p.Ack(context.Background(), result))
+}
+
+func TestPushProviderKeepsNotAppliedResults(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	accrual := float64(10)
+	processed := models.AccrualResult{Order: "1234", Status: models.OrderStatusProcessed, Accrual: &accrual}
+
+	oStorage := mock_storage.NewMockOrdersStorage(ctrl)
+	gomock.InOrder(
+		oStorage.
+			EXPECT().
+			UpdateOrderStatus(
+				testutils.MatchContext(), gomock.Eq("1234"), gomock.Eq(models.OrderStatusProcessed), gomock.Eq(&accrual),
+				gomock.Any(),
+			).
+			Return(errors.New("storage error")),
+		oStorage.
+			EXPECT().
+			UpdateOrderStatus(
+				testutils.MatchContext(), gomock.Eq("1234"), gomock.Eq(models.OrderStatusProcessed), gomock.Eq(&accrual),
+				gomock.Any(),
+			).
+			Return(nil),
+	)
+
+	//result is deleted only after it was applied
+	rStorage := mock_storage.NewMockAccrualResultsStorage(ctrl)
+	rStorage.
+		EXPECT().
+		GetAccrualResult(testutils.MatchContext(), gomock.Eq("1234")).
+		Return(processed, nil).
+		Times(2)
+	rStorage.
+		EXPECT().
+		DeleteAccrualResult(testutils.MatchContext(), gomock.Eq(processed)).
+		Return(nil)
+
+	config.Set(config.Config{})
+	p := NewPushProvider(rStorage)
+	ac := New(dependencies.D{OrdersStorage: oStorage, Logger: zap.NewExample().Sugar()}, p)
+
+	assert.NoError(t, ac.updateOrder(context.Background(), models.Order{Number: "1234"}))
+	assert.NoError(t, ac.updateOrder(context.Background(), models.Order{Number: "1234"}))
+}
+
+type countingProvider struct {
+	calls atomic.Int32
+	err   error
+}
+
+func (p *countingProvider) GetOrder(_ context.Context, number string) (Result, error) {
+	p.calls.Add(1)
+	return Result{Order: number, Status: models.OrderStatusProcessing}, p.err
+}
+
+func TestAIMD(t *testing.T) {
+	a := newAIMD(4)
+	assert.Equal(t, 1, a.Limit())
+
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, a.Acquire(context.Background()))
+		a.Release(false)
+	}
+	assert.Equal(t, 4, a.Limit())
+
+	assert.NoError(t, a.Acquire(context.Background()))
+	a.Release(true)
+	assert.Equal(t, 2, a.Limit())
+
+	assert.NoError(t, a.Acquire(context.Background()))
+	assert.NoError(t, a.Acquire(context.Background()))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	assert.ErrorIs(t, a.Acquire(ctx), context.DeadlineExceeded)
+
+	a.Release(false)
+	assert.NoError(t, a.Acquire(context.Background()))
+}
+
+func TestAccrualBatchThrottled(t *testing.T) {
+	config.Set(config.Config{AccrualConcurrency: 1})
+	p := &countingProvider{err: ErrTooManyRequests}
+	ac := New(dependencies.D{Logger: zap.NewExample().Sugar()}, p)
+	stopWorkers := ac.startWorkers(context.Background(), context.Background())
+	defer stopWorkers()
+
+	ac.runOrderUpdates(context.Background(), []models.Order{{Number: "1"}, {Number: "2"}, {Number: "3"}})
+
+	assert.Equal(t, int32(1), p.calls.Load())
+	assert.Equal(t, 1, ac.limiter.Limit())
+}
+
+func TestAIMDPause(t *testing.T) {
+	a := newAIMD(4)
+	a.Pause(time.Now().Add(time.Millisecond * 100))
+	a.Pause(time.Now())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	assert.ErrorIs(t, a.Acquire(ctx), context.DeadlineExceeded)
+
+	assert.NoError(t, a.Acquire(context.Background()))
+}
+
+func TestAccrualPausedByRetryAfter(t *testing.T) {
+	config.Set(config.Config{AccrualConcurrency: 4})
+	p := &countingProvider{err: &RetryAfterError{Delay: time.Millisecond * 200}}
+	ac := New(dependencies.D{Logger: zap.NewExample().Sugar()}, p)
+	stopWorkers := ac.startWorkers(context.Background(), context.Background())
+	defer stopWorkers()
+
+	ac.runOrderUpdates(context.Background(), []models.Order{{Number: "1"}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	assert.ErrorIs(t, ac.limiter.Acquire(ctx), context.DeadlineExceeded)
+	assert.Equal(t, int32(1), p.calls.Load())
+}
+
+func TestAccrualBatchWaitsForCompletion(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	oStorage := mock_storage.NewMockOrdersStorage(ctrl)
+	oStorage.
+		EXPECT().
+		UpdateOrderStatus(
+			testutils.MatchContext(), gomock.Any(), gomock.Eq(models.OrderStatusProcessing), gomock.Nil(),
+			gomock.Any(),
+		).
+		Times(20)
+
+	config.Set(config.Config{AccrualConcurrency: 4})
+	p := &countingProvider{}
+	ac := New(dependencies.D{OrdersStorage: oStorage, Logger: zap.NewExample().Sugar()}, p)
+	stopWorkers := ac.startWorkers(context.Background(), context.Background())
+
+	orders := make([]models.Order, 20)
+	for i := range orders {
+		orders[i] = models.Order{Number: fmt.Sprint(i)}
+	}
+	ac.runOrderUpdates(context.Background(), orders)
+
+	assert.Equal(t, int32(20), p.calls.Load())
+	stopWorkers()
+}
+
+type shutdownProvider struct {
+	calls    atomic.Int32
+	shutdown context.CancelFunc
+}
+
+func (p *shutdownProvider) GetOrder(_ context.Context, number string) (Result, error) {
+	p.calls.Add(1)
+	p.shutdown()
+	return Result{Order: number, Status: models.OrderStatusProcessed}, nil
+}
+
+func TestAccrualDrainsStartedUpdatesOnShutdown(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	oStorage := mock_storage.NewMockOrdersStorage(ctrl)
+	oStorage.
+		EXPECT().
+		UpdateOrderStatus(
+			gomock.Any(), gomock.Eq("1"), gomock.Eq(models.OrderStatusProcessed), gomock.Nil(), gomock.Any(),
+		).
+		DoAndReturn(
+			func(ctx context.Context, _ string, _ string, _ *float64, _ models.AccrualTerms) error {
+				assert.NoError(t, ctx.Err(), "started update must not be aborted by shutdown")
+				return nil
+			},
+		).
+		Times(1)
+
+	config.Set(config.Config{AccrualConcurrency: 1})
+	shutdownCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p := &shutdownProvider{shutdown: cancel}
+	ac := New(dependencies.D{OrdersStorage: oStorage, Logger: zap.NewExample().Sugar()}, p)
+	stopWorkers := ac.startWorkers(shutdownCtx, context.Background())
+
+	ac.runOrderUpdates(shutdownCtx, []models.Order{{Number: "1"}, {Number: "2"}, {Number: "3"}})
+	stopWorkers()
+
+	assert.Equal(t, int32(1), p.calls.Load())
+}
+
+func TestAccrualStartStopsOnShutdown(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	oStorage := mock_storage.NewMockOrdersStorage(ctrl)
+	oStorage.
+		EXPECT().
+		GetLatestUnprocessedOrders(testutils.MatchContext(), gomock.Eq(OrdersBatchSize)).
+		Return([]models.Order{}, nil).
+		AnyTimes()
+
+	config.Set(config.Config{})
+	ac := New(dependencies.D{OrdersStorage: oStorage, Logger: zap.NewExample().Sugar()}, &countingProvider{})
+
+	shutdownCtx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		ac.Start(shutdownCtx, context.Background())
+		close(stopped)
+	}()
+	cancel()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("accrual client did not stop after shutdown")
+	}
+}
+
+func TestAccrualCircuitBreaker(t *testing.T) {
+	mockTransport := httpmock.NewMockTransport()
+	restyC := resty.
+		NewWithClient(&http.Client{Transport: mockTransport}).
+		SetBaseURL("http://localhost")
+	mockTransport.RegisterRegexpResponder(
+		"GET", regexp.MustCompile(`^http://localhost/api/orders/\d+$`),
+		httpmock.NewStringResponder(http.StatusInternalServerError, ""),
+	)
+
+	config.Set(config.Config{AccrualConcurrency: 1})
+	breaker := circuit.New(3, time.Millisecond*50)
+	d := dependencies.D{
+		AccrualBreaker: breaker,
+		Logger:         zap.NewExample().Sugar(),
+	}
+	ac := New(d, NewHTTPProviderWithClient(restyC))
+	stopWorkers := ac.startWorkers(context.Background(), context.Background())
+	defer stopWorkers()
+
+	orders := []models.Order{{Number: "1"}, {Number: "2"}, {Number: "3"}, {Number: "4"}, {Number: "5"}}
+	ac.runOrderUpdates(context.Background(), orders)
+
+	assert.Equal(t, 3, mockTransport.GetTotalCallCount(), "calls are skipped while circuit is open")
+	assert.Equal(t, circuit.StateOpen, breaker.State())
+
+	time.Sleep(time.Millisecond * 60)
+	ac.runOrderUpdates(context.Background(), orders[:1])
+
+	assert.Equal(t, 4, mockTransport.GetTotalCallCount(), "single probe is made after cooldown")
+	assert.Equal(t, circuit.StateOpen, breaker.State())
+
+	mockTransport.RegisterRegexpResponder(
+		"GET", regexp.MustCompile(`^http://localhost/api/orders/\d+$`),
+		httpmock.NewStringResponder(http.StatusNoContent, ""),
+	)
+	time.Sleep(time.Millisecond * 60)
+	ac.runOrderUpdates(context.Background(), orders)
+
+	assert.Equal(t, 9, mockTransport.GetTotalCallCount())
+	assert.Equal(t, circuit.StateClosed, breaker.State())
+}
+
+func TestAccrualPriorityChecks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	orderNumber := "4561261212345467"
+	oStorage := mock_storage.NewMockOrdersStorage(ctrl)
+	oStorage.
+		EXPECT().
+		GetLatestUnprocessedOrders(testutils.MatchContext(), gomock.Eq(OrdersBatchSize)).
+		Return([]models.Order{}, nil).
+		AnyTimes()
+	oStorage.
+		EXPECT().
+		ClaimStalePriorityChecks(testutils.MatchContext(), gomock.Any(), gomock.Eq(OrdersBatchSize)).
+		Return([]models.Order{}, nil).
+		AnyTimes()
+	oStorage.
+		EXPECT().
+		ClaimPriorityChecks(testutils.MatchContext(), gomock.Eq([]string{orderNumber})).
+		Return([]models.Order{{ID: 1, UserID: 1, Number: orderNumber, Status: models.OrderStatusNew}}, nil)
+
+	checked := make(chan struct{})
+	oStorage.
+		EXPECT().
+		UpdateOrderStatus(
+			testutils.MatchContext(), gomock.Eq(orderNumber), gomock.Eq(models.OrderStatusProcessing), gomock.Nil(),
+			gomock.Any(),
+		).
+		Do(
+			func(context.Context, string, string, *float64, models.AccrualTerms) {
+				close(checked)
+			},
+		)
+
+	config.Set(config.Config{})
+	d := dependencies.D{
+		OrdersStorage:  oStorage,
+		PriorityChecks: make(chan string, 1),
+		Logger:         zap.NewExample().Sugar(),
+	}
+	ac := New(d, &countingProvider{})
+
+	shutdownCtx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		ac.Start(shutdownCtx, context.Background())
+		close(stopped)
+	}()
+
+	d.PriorityChecks <- orderNumber
+	select {
+	case <-checked:
+	case <-time.After(time.Second):
+		t.Error("priority order was not checked")
+	}
+
+	cancel()
+	<-stopped
+}
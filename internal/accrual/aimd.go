@@ -0,0 +1,96 @@
+package accrual
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// aimd limits concurrent requests to accrual system. Limit grows by one after limit successful requests
+// in a row (additive increase) and is halved on throttling or timeout (multiplicative decrease).
+// Requests are not started at all while limiter is paused, see Pause.
+type aimd struct {
+	mu          sync.Mutex
+	limit       float64
+	max         float64
+	inFlight    int
+	pausedUntil time.Time
+	changed     chan struct{}
+}
+
+func newAIMD(max int) *aimd {
+	if max < 1 {
+		max = 1
+	}
+
+	return &aimd{
+		limit:   1,
+		max:     float64(max),
+		changed: make(chan struct{}),
+	}
+}
+
+// Acquire blocks until limiter is not paused and request fits into current limit or ctx is done.
+func (a *aimd) Acquire(ctx context.Context) error {
+	for {
+		a.mu.Lock()
+		pause := time.Until(a.pausedUntil)
+		if pause <= 0 && a.inFlight < a.Limit() {
+			a.inFlight++
+			a.mu.Unlock()
+			return nil
+		}
+		changed := a.changed
+		a.mu.Unlock()
+
+		if pause > 0 {
+			timer := time.NewTimer(pause)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// Release frees acquired slot and adjusts limit by request outcome.
+func (a *aimd) Release(congested bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.inFlight--
+	if congested {
+		a.limit = math.Max(1, a.limit/2)
+	} else {
+		a.limit = math.Min(a.max, a.limit+1/a.limit)
+	}
+
+	close(a.changed)
+	a.changed = make(chan struct{})
+}
+
+// Pause stops starting requests until given time, e.g. when accrual system asks to retry after some delay.
+// Earlier pause does not shorten current one.
+func (a *aimd) Pause(until time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if until.After(a.pausedUntil) {
+		a.pausedUntil = until
+	}
+}
+
+// Limit must be called with mu locked or from tests.
+func (a *aimd) Limit() int {
+	return int(a.limit)
+}
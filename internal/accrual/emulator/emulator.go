@@ -0,0 +1,202 @@
+// Package emulator implements accrual system black box for local development and tests.
+package emulator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/functions"
+)
+
+const (
+	StatusRegistered = "REGISTERED"
+	StatusInvalid    = "INVALID"
+	StatusProcessing = "PROCESSING"
+	StatusProcessed  = "PROCESSED"
+)
+
+const rateLimitWindow = time.Minute
+
+var ErrWrongRule = errors.New("rule must be in suffix:percent format")
+
+// Rule rewards orders which numbers end with Suffix. Empty suffix matches every order.
+type Rule struct {
+	Suffix  string
+	Percent float64
+}
+
+type Config struct {
+	// First matching rule wins. Orders without matching rule are processed without accrual.
+	Rules []Rule
+	// Order is REGISTERED during first half of delay, PROCESSING during second one and PROCESSED after.
+	ProcessingDelay time.Duration
+	// Probabilities of answering 204 (order is unknown) and 500 instead of order status.
+	NoContentRate float64
+	ErrorRate     float64
+	// Requests allowed per minute. Zero means no limit.
+	RateLimit int
+	Seed      int64
+}
+
+type orderResponse struct {
+	Order   string   `json:"order"`
+	Status  string   `json:"status"`
+	Accrual *float64 `json:"accrual,omitempty"`
+}
+
+type Emulator struct {
+	c   Config
+	now func() time.Time
+
+	mu           sync.Mutex
+	rnd          *rand.Rand
+	registeredAt map[string]time.Time
+	windowStart  time.Time
+	windowCount  int
+}
+
+func New(c Config) *Emulator {
+	return &Emulator{
+		c:            c,
+		now:          time.Now,
+		rnd:          rand.New(rand.NewSource(c.Seed)),
+		registeredAt: make(map[string]time.Time),
+	}
+}
+
+// NewServer starts in-process emulator. Server must be closed by caller.
+func NewServer(c Config) *httptest.Server {
+	return httptest.NewServer(New(c).Handler())
+}
+
+func (e *Emulator) Handler() http.Handler {
+	r := chi.NewMux()
+	r.Get("/api/orders/{number}", e.getOrder)
+
+	return r
+}
+
+func (e *Emulator) getOrder(w http.ResponseWriter, r *http.Request) {
+	number := chi.URLParam(r, "number")
+
+	e.mu.Lock()
+	now := e.now()
+	if retryAfter, limited := e.limit(now); limited {
+		e.mu.Unlock()
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		http.Error(
+			w, fmt.Sprintf("No more than %d requests per minute allowed", e.c.RateLimit), http.StatusTooManyRequests,
+		)
+		return
+	}
+	roll := e.rnd.Float64()
+	registeredAt, found := e.registeredAt[number]
+	if !found {
+		registeredAt = now
+		e.registeredAt[number] = now
+	}
+	e.mu.Unlock()
+
+	switch {
+	case roll < e.c.ErrorRate:
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	case roll < e.c.ErrorRate+e.c.NoContentRate:
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	response := orderResponse{
+		Order:  number,
+		Status: e.status(number, now.Sub(registeredAt)),
+	}
+	if response.Status == StatusProcessed {
+		response.Accrual = e.accrual(number)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if encodeErr := json.NewEncoder(w).Encode(response); encodeErr != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+// limit counts request in fixed one minute window and returns time left until window end if limit is reached.
+func (e *Emulator) limit(now time.Time) (time.Duration, bool) {
+	if e.c.RateLimit <= 0 {
+		return 0, false
+	}
+
+	if now.Sub(e.windowStart) >= rateLimitWindow {
+		e.windowStart = now
+		e.windowCount = 0
+	}
+	if e.windowCount >= e.c.RateLimit {
+		return e.windowStart.Add(rateLimitWindow).Sub(now), true
+	}
+	e.windowCount++
+
+	return 0, false
+}
+
+func (e *Emulator) status(number string, elapsed time.Duration) string {
+	switch {
+	case !functions.CheckLuhn(number):
+		return StatusInvalid
+	case elapsed < e.c.ProcessingDelay/2:
+		return StatusRegistered
+	case elapsed < e.c.ProcessingDelay:
+		return StatusProcessing
+	default:
+		return StatusProcessed
+	}
+}
+
+// accrual rewards purchase which sum is derived from order number, so repeated requests get the same accrual.
+func (e *Emulator) accrual(number string) *float64 {
+	for _, rule := range e.c.Rules {
+		if strings.HasSuffix(number, rule.Suffix) {
+			h := fnv.New32a()
+			h.Write([]byte(number))
+			purchase := float64(h.Sum32()%100000) / 100
+			accrual := math.Round(purchase*rule.Percent) / 100
+
+			return &accrual
+		}
+	}
+
+	return nil
+}
+
+// ParseRules parses comma separated suffix:percent rules, e.g. "0:10,:5".
+func ParseRules(s string) ([]Rule, error) {
+	var rules []Rule
+	if s == "" {
+		return rules, nil
+	}
+
+	for _, part := range strings.Split(s, ",") {
+		suffix, percent, found := strings.Cut(strings.TrimSpace(part), ":")
+		if !found {
+			return nil, ErrWrongRule
+		}
+		parsedPercent, parseErr := strconv.ParseFloat(percent, 64)
+		if parseErr != nil {
+			return nil, ErrWrongRule
+		}
+		rules = append(rules, Rule{Suffix: suffix, Percent: parsedPercent})
+	}
+
+	return rules, nil
+}
@@ -0,0 +1,79 @@
+package emulator
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const OrderNumber = "4561261212345467"
+
+func get(e *Emulator, number string) *httptest.ResponseRecorder {
+	httpW := httptest.NewRecorder()
+	e.Handler().ServeHTTP(httpW, httptest.NewRequest("GET", "/api/orders/"+number, nil))
+
+	return httpW
+}
+
+func TestEmulatorProcessing(t *testing.T) {
+	now := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+	e := New(Config{Rules: []Rule{{Suffix: "67", Percent: 10}}, ProcessingDelay: time.Minute})
+	e.now = func() time.Time { return now }
+
+	httpW := get(e, OrderNumber)
+	assert.Equal(t, http.StatusOK, httpW.Code)
+	assert.JSONEq(t, `{"order":"4561261212345467","status":"REGISTERED"}`, httpW.Body.String())
+
+	now = now.Add(time.Second * 40)
+	assert.JSONEq(t, `{"order":"4561261212345467","status":"PROCESSING"}`, get(e, OrderNumber).Body.String())
+
+	now = now.Add(time.Second * 20)
+	processed := get(e, OrderNumber).Body.String()
+	assert.Contains(t, processed, `"status":"PROCESSED"`)
+	assert.Contains(t, processed, `"accrual":`)
+	assert.Equal(t, processed, get(e, OrderNumber).Body.String())
+
+	assert.JSONEq(t, `{"order":"12345","status":"INVALID"}`, get(e, "12345").Body.String())
+}
+
+func TestEmulatorNoMatchingRule(t *testing.T) {
+	e := New(Config{Rules: []Rule{{Suffix: "0", Percent: 10}}})
+
+	assert.JSONEq(t, `{"order":"4561261212345467","status":"PROCESSED"}`, get(e, OrderNumber).Body.String())
+}
+
+func TestEmulatorRandomResponses(t *testing.T) {
+	assert.Equal(t, http.StatusInternalServerError, get(New(Config{ErrorRate: 1}), OrderNumber).Code)
+	assert.Equal(t, http.StatusNoContent, get(New(Config{NoContentRate: 1}), OrderNumber).Code)
+}
+
+func TestEmulatorRateLimit(t *testing.T) {
+	now := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+	e := New(Config{RateLimit: 2})
+	e.now = func() time.Time { return now }
+
+	assert.Equal(t, http.StatusOK, get(e, OrderNumber).Code)
+	assert.Equal(t, http.StatusOK, get(e, OrderNumber).Code)
+
+	now = now.Add(time.Second * 15)
+	httpW := get(e, OrderNumber)
+	assert.Equal(t, http.StatusTooManyRequests, httpW.Code)
+	assert.Equal(t, "45", httpW.Header().Get("Retry-After"))
+
+	now = now.Add(time.Second * 45)
+	assert.Equal(t, http.StatusOK, get(e, OrderNumber).Code)
+}
+
+func TestParseRules(t *testing.T) {
+	rules, parseErr := ParseRules("0:10, :2.5")
+	assert.NoError(t, parseErr)
+	assert.Equal(t, []Rule{{Suffix: "0", Percent: 10}, {Suffix: "", Percent: 2.5}}, rules)
+
+	_, parseErr = ParseRules("10")
+	assert.ErrorIs(t, parseErr, ErrWrongRule)
+	_, parseErr = ParseRules("0:ten")
+	assert.ErrorIs(t, parseErr, ErrWrongRule)
+}
@@ -0,0 +1,73 @@
+package accrual
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// HTTPProvider requests accrual system over HTTP.
+type HTTPProvider struct {
+	c *resty.Client
+}
+
+func NewHTTPProvider(accrualAddr string) HTTPProvider {
+	return NewHTTPProviderWithClient(
+		resty.New().
+			SetBaseURL(accrualAddr).
+			SetTimeout(RequestTimeout),
+	)
+}
+
+func NewHTTPProviderWithClient(c *resty.Client) HTTPProvider {
+	return HTTPProvider{
+		c: c,
+	}
+}
+
+func (p HTTPProvider) GetOrder(ctx context.Context, number string) (Result, error) {
+	result := Result{}
+	response, err := p.c.R().
+		SetResult(&result).
+		SetContext(ctx).
+		Get("/api/orders/" + number)
+	if err != nil {
+		return result, err
+	}
+	defer response.RawBody().Close()
+
+	switch response.StatusCode() {
+	case http.StatusOK:
+		return result, nil
+	case http.StatusTooManyRequests:
+		if delay, ok := parseRetryAfter(response.Header().Get("Retry-After"), time.Now()); ok {
+			return result, &RetryAfterError{Delay: delay}
+		}
+		return result, ErrTooManyRequests
+	case http.StatusNoContent:
+		return result, ErrOrderNotRegistered
+	case http.StatusInternalServerError:
+		return result, ErrProviderFailure
+	default:
+		return result, fmt.Errorf("unknown status - %d", response.StatusCode())
+	}
+}
+
+// parseRetryAfter parses Retry-After header given either in seconds or as HTTP date.
+func parseRetryAfter(header string, now time.Time) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+	if seconds, atoiErr := strconv.Atoi(header); atoiErr == nil {
+		return time.Duration(seconds) * time.Second, seconds >= 0
+	}
+	if date, parseErr := http.ParseTime(header); parseErr == nil {
+		return date.Sub(now), date.After(now)
+	}
+
+	return 0, false
+}
@@ -0,0 +1,54 @@
+package accrual
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Providers which can be selected with config.Config.AccrualProvider.
+const (
+	ProviderHTTP = "http"
+	ProviderPush = "push"
+)
+
+// StatusRegistered is status of order accepted by accrual system which is not processed yet. Orders don't have
+// such status, see Apply.
+const StatusRegistered = "REGISTERED"
+
+var (
+	ErrTooManyRequests    = errors.New("too many requests")
+	ErrOrderNotRegistered = errors.New("order is not registered in accrual system")
+	ErrProviderFailure    = errors.New("accrual system failure")
+)
+
+// RetryAfterError is ErrTooManyRequests with delay requested by accrual system in Retry-After header.
+type RetryAfterError struct {
+	Delay time.Duration
+}
+
+func (e *RetryAfterError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrTooManyRequests, e.Delay)
+}
+
+func (e *RetryAfterError) Unwrap() error {
+	return ErrTooManyRequests
+}
+
+// Result is accrual system view of order.
+type Result struct {
+	Order   string   `json:"order"`
+	Status  string   `json:"status"`
+	Accrual *float64 `json:"accrual,omitempty"`
+}
+
+// AccrualProvider is source of accrual results. Poller and storage don't depend on how results are obtained.
+type AccrualProvider interface {
+	GetOrder(ctx context.Context, number string) (Result, error)
+}
+
+// Acknowledger is implemented by providers which keep results until caller confirms they were applied.
+type Acknowledger interface {
+	Ack(ctx context.Context, r Result) error
+}
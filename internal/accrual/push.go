@@ -0,0 +1,67 @@
+package accrual
+
+import (
+	"context"
+	"errors"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/models"
+	"github.com/bobgromozeka/yp-diploma1/internal/storage"
+)
+
+// PushProvider serves results pushed by accrual system (e.g. consumed from message queue) instead of requesting them.
+// Results are kept in storage, so they survive restarts and are served by whichever instance polls orders.
+// Orders without pushed results are reported as not registered.
+type PushProvider struct {
+	s storage.AccrualResultsStorage
+}
+
+func NewPushProvider(s storage.AccrualResultsStorage) *PushProvider {
+	return &PushProvider{
+		s: s,
+	}
+}
+
+// Push stores latest result of order until it is polled.
+func (p *PushProvider) Push(ctx context.Context, r Result) error {
+	return p.s.SaveAccrualResult(ctx, models.AccrualResult{Order: r.Order, Status: r.Status, Accrual: r.Accrual})
+}
+
+// Consume pushes results from feed until it is closed or ctx is done. It stops on first result which could not be
+// stored, so that feed is not drained further.
+func (p *PushProvider) Consume(ctx context.Context, feed <-chan Result) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case r, ok := <-feed:
+			if !ok {
+				return nil
+			}
+			if pushErr := p.Push(ctx, r); pushErr != nil {
+				return pushErr
+			}
+		}
+	}
+}
+
+func (p *PushProvider) GetOrder(ctx context.Context, number string) (Result, error) {
+	stored, getErr := p.s.GetAccrualResult(ctx, number)
+	if errors.Is(getErr, storage.ErrAccrualResultNotFound) {
+		return Result{}, ErrOrderNotRegistered
+	}
+	if getErr != nil {
+		return Result{}, getErr
+	}
+
+	return Result{Order: stored.Order, Status: stored.Status, Accrual: stored.Accrual}, nil
+}
+
+// Ack forgets final result after it was applied, final results are not changed anymore. Results which were
+// not acknowledged (e.g. storage was unavailable) are served again on the next poll.
+func (p *PushProvider) Ack(ctx context.Context, r Result) error {
+	if r.Status != models.OrderStatusProcessed && r.Status != models.OrderStatusInvalid {
+		return nil
+	}
+
+	return p.s.DeleteAccrualResult(ctx, models.AccrualResult{Order: r.Order, Status: r.Status, Accrual: r.Accrual})
+}
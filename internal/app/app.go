@@ -2,56 +2,256 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
+	"time"
 
 	"github.com/bobgromozeka/yp-diploma1/internal/accrual"
 	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
 	"github.com/bobgromozeka/yp-diploma1/internal/db"
+	"github.com/bobgromozeka/yp-diploma1/internal/events"
+	"github.com/bobgromozeka/yp-diploma1/internal/events/relay"
+	"github.com/bobgromozeka/yp-diploma1/internal/leader"
 	"github.com/bobgromozeka/yp-diploma1/internal/log"
+	"github.com/bobgromozeka/yp-diploma1/internal/models"
+	"github.com/bobgromozeka/yp-diploma1/internal/orderformat"
+	"github.com/bobgromozeka/yp-diploma1/internal/points"
+	"github.com/bobgromozeka/yp-diploma1/internal/rpc"
+	"github.com/bobgromozeka/yp-diploma1/internal/scheduler"
 	"github.com/bobgromozeka/yp-diploma1/internal/server"
 	"github.com/bobgromozeka/yp-diploma1/internal/server/config"
 	"github.com/bobgromozeka/yp-diploma1/internal/storage"
+	"github.com/bobgromozeka/yp-diploma1/internal/stores"
+	"github.com/bobgromozeka/yp-diploma1/internal/webhooks"
 )
 
-func Start(c config.Config) {
+const (
+	ExitClean  = 0
+	ExitForced = 2
+)
+
+// Start runs application until termination signal. On signal new work is stopped and in-flight work is given
+// c.ShutdownTimeout to finish; after that (or on the second signal) it is aborted and ExitForced is returned.
+func Start(c config.Config) int {
 	ctx := context.Background()
 	shutdownCtx, closeShutdownCtx := context.WithCancel(ctx)
+	forceCtx, closeForceCtx := context.WithCancel(ctx)
+	defer closeForceCtx()
+	stopped := make(chan struct{})
 
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
 
 	deps := makeDependencies(c)
 
+	storeLogins, storeLoginsErr := stores.ParseLogins(c.StoreLogins, deps.OrderFormats)
+	if storeLoginsErr != nil {
+		deps.Logger.Fatalln(storeLoginsErr)
+	}
+
+	accrualProvider, providerErr := makeAccrualProvider(c, &deps)
+	if providerErr != nil {
+		deps.Logger.Fatalln(providerErr)
+	}
+
 	bootstrapError := storage.Bootstrap(db.Connection())
 	if bootstrapError != nil {
 		deps.Logger.Fatalln(bootstrapError)
 	}
 
+	syncRoles(ctx, deps, c.AdminLogins, models.RoleAdmin)
+	syncRoles(ctx, deps, c.ServiceLogins, models.RoleService)
+	syncStores(ctx, deps, storeLogins)
+
+	jobs := scheduler.New(deps)
+	if pointsJobsErr := registerPointsJobs(jobs, c, deps); pointsJobsErr != nil {
+		deps.Logger.Fatalln(pointsJobsErr)
+	}
+	jobs.Publish("scheduler")
+
 	go func() {
 		<-sig
 		deps.Logger.Info("Stopping application.....")
 		closeShutdownCtx()
+
+		select {
+		case <-stopped:
+			return
+		case <-sig:
+			deps.Logger.Warn("Second signal received, aborting in-flight work")
+		case <-time.After(c.ShutdownTimeout):
+			deps.Logger.Warnw("Shutdown timeout exceeded, aborting in-flight work", "timeout", c.ShutdownTimeout)
+		}
+		closeForceCtx()
 	}()
 
 	wg := sync.WaitGroup{}
 
 	wg.Add(1)
 	go func() {
-		server.Run(shutdownCtx, deps)
+		if serverErr := server.Run(shutdownCtx, forceCtx, deps); serverErr != nil {
+			closeForceCtx()
+		}
+		wg.Done()
+	}()
+
+	wg.Add(1)
+	go func() {
+		if rpcErr := rpc.Run(shutdownCtx, forceCtx, deps); rpcErr != nil {
+			closeForceCtx()
+		}
+		wg.Done()
+	}()
+
+	//accrual polling and scheduled jobs must not run on several instances at once
+	wg.Add(1)
+	go func() {
+		leader.Run(
+			shutdownCtx, deps, func(leaderCtx context.Context) {
+				singletons := sync.WaitGroup{}
+
+				singletons.Add(1)
+				go func() {
+					accrual.Run(leaderCtx, forceCtx, deps, accrualProvider)
+					singletons.Done()
+				}()
+
+				singletons.Add(1)
+				go func() {
+					jobs.Run(leaderCtx, forceCtx)
+					singletons.Done()
+				}()
+
+				singletons.Wait()
+			},
+		)
+		wg.Done()
+	}()
+
+	wg.Add(1)
+	go func() {
+		relay.Run(shutdownCtx, deps)
 		wg.Done()
 	}()
 
 	wg.Add(1)
 	go func() {
-		accrual.Run(shutdownCtx, deps)
+		webhooks.Run(shutdownCtx, forceCtx, deps)
 		wg.Done()
 	}()
 
 	wg.Wait()
+	close(stopped)
+
+	if forceCtx.Err() != nil {
+		deps.Logger.Warn("Application is stopped forcibly")
+		return ExitForced
+	}
+	deps.Logger.Info("Application is stopped")
+
+	return ExitClean
+}
+
+// syncStores binds configured logins to their stores and unbinds users which were removed from configuration.
+func syncStores(ctx context.Context, d dependencies.D, storeLogins map[string]string) {
+	configuredLogins := make([]string, 0, len(storeLogins))
+	for login := range storeLogins {
+		configuredLogins = append(configuredLogins, login)
+	}
+
+	unboundLogins, unbindErr := d.UsersStorage.UnbindStores(ctx, configuredLogins)
+	if unbindErr != nil {
+		d.Logger.Fatalln(unbindErr)
+	}
+	for _, login := range unboundLogins {
+		d.Logger.Infow("Store is unbound from user removed from configuration", "login", login)
+	}
+
+	for login, store := range storeLogins {
+		storeErr := d.UsersStorage.SetUserStore(ctx, login, store)
+		if errors.Is(storeErr, storage.ErrUserNotFound) {
+			d.Logger.Warnw("Could not bind store to not existing user", "login", login, "store", store)
+		} else if storeErr != nil {
+			d.Logger.Fatalln(storeErr)
+		}
+	}
+}
+
+// syncRoles grants role to configured logins and demotes users which were removed from configuration.
+func syncRoles(ctx context.Context, d dependencies.D, logins string, role string) {
+	configuredLogins := make([]string, 0)
+	for _, login := range strings.Split(logins, ",") {
+		login = strings.TrimSpace(login)
+		if login != "" {
+			configuredLogins = append(configuredLogins, login)
+		}
+	}
+
+	demotedLogins, revokeErr := d.UsersStorage.RevokeRole(ctx, role, configuredLogins)
+	if revokeErr != nil {
+		d.Logger.Fatalln(revokeErr)
+	}
+	for _, login := range demotedLogins {
+		d.Logger.Infow("Role is revoked from user removed from configuration", "login", login, "role", role)
+	}
+
+	for _, login := range configuredLogins {
+		roleErr := d.UsersStorage.SetUserRole(ctx, login, role)
+		if errors.Is(roleErr, storage.ErrUserNotFound) {
+			d.Logger.Warnw("Could not grant role to not existing user", "login", login, "role", role)
+		} else if roleErr != nil {
+			d.Logger.Fatalln(roleErr)
+		}
+	}
+}
+
+// registerPointsJobs registers every points job with its own schedule, so slow expiry does not delay releases.
+func registerPointsJobs(jobs *scheduler.Scheduler, c config.Config, d dependencies.D) error {
+	pointsJobs := []struct {
+		cron string
+		job  func(d dependencies.D, schedule scheduler.Schedule) scheduler.Job
+	}{
+		{c.PendingReleaseCron, points.ReleaseJob},
+		{c.HoldsReleaseCron, points.ReleaseHoldsJob},
+		{c.PointsExpiryCron, points.ExpireJob},
+	}
+
+	for _, pj := range pointsJobs {
+		schedule, scheduleErr := points.Schedule(pj.cron)
+		if scheduleErr != nil {
+			return fmt.Errorf("cron %q: %w", pj.cron, scheduleErr)
+		}
+		jobs.Register(pj.job(d, schedule))
+	}
+
+	return nil
+}
+
+// makeAccrualProvider creates provider selected in config. Push provider is fed by accrual callbacks.
+func makeAccrualProvider(c config.Config, d *dependencies.D) (accrual.AccrualProvider, error) {
+	switch c.AccrualProvider {
+	case accrual.ProviderHTTP, "":
+		return accrual.NewHTTPProvider(c.AccrualSystemAddress), nil
+	case accrual.ProviderPush:
+		if c.AccrualCallbackSecret == "" {
+			return nil, errors.New("push accrual provider requires accrual callback secret")
+		}
+
+		p := accrual.NewPushProvider(d.AccrualResultsStorage)
+		d.PushAccrualResult = func(ctx context.Context, order string, status string, accrualSum *float64) error {
+			return p.Push(ctx, accrual.Result{Order: order, Status: status, Accrual: accrualSum})
+		}
+
+		return p, nil
+	}
+
+	return nil, fmt.Errorf("unknown accrual provider %q", c.AccrualProvider)
 }
 
 func makeDependencies(c config.Config) dependencies.D {
@@ -67,16 +267,50 @@ func makeDependencies(c config.Config) dependencies.D {
 		logger.Fatalln(connErr)
 	}
 
+	orderFormats, formatsErr := orderformat.Parse(c.OrderFormats)
+	if formatsErr != nil {
+		logger.Fatalln(formatsErr)
+	}
+
 	pgStoragesFactory := storage.NewPgFactory(db.Connection())
 	pgUsersStorage := pgStoragesFactory.CreateUsersStorage()
 	pgOrdersStorage := pgStoragesFactory.CreateOrdersStorage()
 	pgWithdrawalsStorage := pgStoragesFactory.CreateWithdrawalsStorage()
+	pgAdjustmentsStorage := pgStoragesFactory.CreateAdjustmentsStorage()
+	pgAuditStorage := pgStoragesFactory.CreateAuditStorage()
+	pgPointsStorage := pgStoragesFactory.CreatePointsStorage()
+	pgHoldsStorage := pgStoragesFactory.CreateHoldsStorage()
+	pgTransfersStorage := pgStoragesFactory.CreateTransfersStorage()
+	pgStatementsStorage := pgStoragesFactory.CreateStatementsStorage()
+	pgEventsStorage := pgStoragesFactory.CreateEventsStorage()
+	pgWebhooksStorage := pgStoragesFactory.CreateWebhooksStorage()
+	pgAccrualResultsStorage := pgStoragesFactory.CreateAccrualResultsStorage()
+	pgLocksStorage := pgStoragesFactory.CreateLocksStorage()
 
-	return dependencies.D{
-		UsersStorage:       pgUsersStorage,
-		OrdersStorage:      pgOrdersStorage,
-		WithdrawalsStorage: pgWithdrawalsStorage,
-		DB:                 db.Connection(),
-		Logger:             logger,
+	d := dependencies.D{
+		UsersStorage:          pgUsersStorage,
+		OrdersStorage:         pgOrdersStorage,
+		WithdrawalsStorage:    pgWithdrawalsStorage,
+		AdjustmentsStorage:    pgAdjustmentsStorage,
+		AuditStorage:          pgAuditStorage,
+		PointsStorage:         pgPointsStorage,
+		HoldsStorage:          pgHoldsStorage,
+		TransfersStorage:      pgTransfersStorage,
+		StatementsStorage:     pgStatementsStorage,
+		EventsStorage:         pgEventsStorage,
+		WebhooksStorage:       pgWebhooksStorage,
+		AccrualResultsStorage: pgAccrualResultsStorage,
+		LocksStorage:          pgLocksStorage,
+		Events:                events.NewBroker(),
+		Leader:                &atomic.Bool{},
+		PriorityChecks:        make(chan string, accrual.PriorityQueueSize),
+		OrderFormats:          orderFormats,
+		DB:                    db.Connection(),
+		Logger:                logger,
 	}
+
+	d.AccrualBreaker = accrual.NewBreaker(d)
+	d.AccrualBreaker.Publish("accrual_circuit")
+
+	return d
 }
@@ -1,17 +1,43 @@
 package dependencies
 
 import (
+	"context"
 	"database/sql"
+	"sync/atomic"
 
 	"go.uber.org/zap"
 
+	"github.com/bobgromozeka/yp-diploma1/internal/circuit"
+	"github.com/bobgromozeka/yp-diploma1/internal/events"
+	"github.com/bobgromozeka/yp-diploma1/internal/orderformat"
 	"github.com/bobgromozeka/yp-diploma1/internal/storage"
 )
 
 type D struct {
-	UsersStorage       storage.UsersStorage
-	OrdersStorage      storage.OrdersStorage
-	WithdrawalsStorage storage.WithdrawalsStorage
-	DB                 *sql.DB
-	Logger             *zap.SugaredLogger
+	UsersStorage          storage.UsersStorage
+	OrdersStorage         storage.OrdersStorage
+	WithdrawalsStorage    storage.WithdrawalsStorage
+	AdjustmentsStorage    storage.AdjustmentsStorage
+	AuditStorage          storage.AuditStorage
+	PointsStorage         storage.PointsStorage
+	HoldsStorage          storage.HoldsStorage
+	TransfersStorage      storage.TransfersStorage
+	StatementsStorage     storage.StatementsStorage
+	EventsStorage         storage.EventsStorage
+	WebhooksStorage       storage.WebhooksStorage
+	AccrualResultsStorage storage.AccrualResultsStorage
+	LocksStorage          storage.LocksStorage
+	Events                *events.Broker
+	AccrualBreaker        *circuit.Breaker
+	// Leader is true while instance runs singleton background jobs.
+	Leader *atomic.Bool
+	// PriorityChecks receives numbers of uploaded orders which should be checked in accrual system first.
+	PriorityChecks chan string
+	// PushAccrualResult is set when accrual results are served by push provider. Callbacks are stored by it
+	// instead of being applied right away.
+	PushAccrualResult func(ctx context.Context, order string, status string, accrual *float64) error
+	// OrderFormats validates order numbers of partner stores.
+	OrderFormats *orderformat.Registry
+	DB           *sql.DB
+	Logger       *zap.SugaredLogger
 }
@@ -0,0 +1,25 @@
+package audit
+
+import (
+	"context"
+)
+
+type metaKey struct{}
+
+// Meta describes request which caused audited action.
+type Meta struct {
+	IP        string
+	UserAgent string
+	RequestID string
+}
+
+func WithMeta(ctx context.Context, meta Meta) context.Context {
+	return context.WithValue(ctx, metaKey{}, meta)
+}
+
+// MetaFromContext returns empty Meta for actions not caused by http request.
+func MetaFromContext(ctx context.Context) Meta {
+	meta, _ := ctx.Value(metaKey{}).(Meta)
+
+	return meta
+}
@@ -0,0 +1,128 @@
+// Package circuit stops calls to failing dependency until it recovers.
+package circuit
+
+import (
+	"expvar"
+	"sync"
+	"time"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/metrics"
+)
+
+const (
+	StateClosed   = "closed"
+	StateOpen     = "open"
+	StateHalfOpen = "half-open"
+)
+
+// Breaker opens after threshold consecutive failures. After cooldown single probe call is allowed (half-open):
+// its success closes breaker, failure opens it for another cooldown.
+type Breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+	onChange  func(from string, to string)
+
+	state    string
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func New(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+		state:     StateClosed,
+	}
+}
+
+// OnStateChange sets callback called on every state change. Callback must not call breaker.
+func (b *Breaker) OnStateChange(fn func(from string, to string)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.onChange = fn
+}
+
+// Allow reports whether call can be made. Every allowed call must be followed by Record or Ignore.
+func (b *Breaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.setState(StateHalfOpen)
+		b.probing = true
+		return true
+	case StateHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+func (b *Breaker) Record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if success {
+		b.failures = 0
+		b.setState(StateClosed)
+		return
+	}
+
+	b.failures++
+	if b.state == StateHalfOpen || b.failures >= b.threshold {
+		b.openedAt = b.now()
+		b.setState(StateOpen)
+	}
+}
+
+// Ignore finishes allowed call which outcome says nothing about dependency health (e.g. canceled one).
+func (b *Breaker) Ignore() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+func (b *Breaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+// Publish exposes breaker state on admin metrics endpoint. Name must be unique within process.
+func (b *Breaker) Publish(name string) {
+	metrics.Publish(
+		name, expvar.Func(
+			func() any {
+				return b.State()
+			},
+		),
+	)
+}
+
+func (b *Breaker) setState(state string) {
+	if b.state == state {
+		return
+	}
+
+	from := b.state
+	b.state = state
+	if b.onChange != nil {
+		b.onChange(from, state)
+	}
+}
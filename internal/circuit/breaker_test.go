@@ -0,0 +1,59 @@
+package circuit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+	b := New(3, time.Second*30)
+	b.now = func() time.Time { return now }
+
+	var changes []string
+	b.OnStateChange(
+		func(from string, to string) {
+			changes = append(changes, from+"->"+to)
+		},
+	)
+
+	for i := 0; i < 2; i++ {
+		assert.True(t, b.Allow())
+		b.Record(false)
+	}
+	assert.True(t, b.Allow())
+	b.Record(true)
+	assert.Equal(t, StateClosed, b.State())
+
+	for i := 0; i < 3; i++ {
+		assert.True(t, b.Allow())
+		b.Record(false)
+	}
+	assert.Equal(t, StateOpen, b.State())
+	assert.False(t, b.Allow())
+
+	now = now.Add(time.Second * 30)
+	assert.True(t, b.Allow())
+	assert.Equal(t, StateHalfOpen, b.State())
+	assert.False(t, b.Allow(), "only one probe is allowed")
+	b.Record(false)
+	assert.Equal(t, StateOpen, b.State())
+	assert.False(t, b.Allow())
+
+	now = now.Add(time.Second * 30)
+	assert.True(t, b.Allow())
+	b.Ignore()
+	assert.True(t, b.Allow())
+	b.Record(true)
+	assert.Equal(t, StateClosed, b.State())
+
+	assert.Equal(
+		t,
+		[]string{
+			"closed->open", "open->half-open", "half-open->open", "open->half-open", "half-open->closed",
+		},
+		changes,
+	)
+}
@@ -0,0 +1,151 @@
+package events
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+const (
+	TypeOrderStatusChanged = "order.status_changed"
+	TypeBalanceCredited    = "balance.credited"
+	TypeBalanceWithdrawn   = "balance.withdrawn"
+	TypeBalanceAdjusted    = "balance.adjusted"
+	TypeBalanceExpired     = "balance.expired"
+	TypeWithdrawalReversed = "balance.withdrawal_reversed"
+	TypeTransferSent       = "balance.transfer_sent"
+	TypeTransferReceived   = "balance.transfer_received"
+)
+
+var Types = []string{
+	TypeOrderStatusChanged, TypeBalanceCredited, TypeBalanceWithdrawn, TypeBalanceAdjusted, TypeBalanceExpired,
+	TypeWithdrawalReversed, TypeTransferSent, TypeTransferReceived,
+}
+
+const subscriberBufferSize = 16
+
+type Event struct {
+	ID        int64
+	UserID    int64
+	Type      string
+	Payload   json.RawMessage
+	CreatedAt time.Time
+}
+
+type OrderStatusChanged struct {
+	Number  string   `json:"number"`
+	Status  string   `json:"status"`
+	Accrual *float64 `json:"accrual,omitempty"`
+}
+
+type BalanceCredited struct {
+	Order  string  `json:"order"`
+	Amount float64 `json:"amount"`
+}
+
+type BalanceWithdrawn struct {
+	Order string  `json:"order"`
+	Sum   float64 `json:"sum"`
+}
+
+type BalanceAdjusted struct {
+	AdjustmentID int64   `json:"adjustment_id"`
+	Amount       float64 `json:"amount"`
+	Reason       string  `json:"reason"`
+}
+
+type WithdrawalReversed struct {
+	Order  string  `json:"order"`
+	Sum    float64 `json:"sum"`
+	Reason string  `json:"reason"`
+}
+
+type TransferSent struct {
+	TransferID int64   `json:"transfer_id"`
+	To         string  `json:"to"`
+	Amount     float64 `json:"amount"`
+}
+
+type TransferReceived struct {
+	TransferID int64   `json:"transfer_id"`
+	From       string  `json:"from"`
+	Amount     float64 `json:"amount"`
+}
+
+type BalanceExpired struct {
+	Order  string  `json:"order"`
+	Amount float64 `json:"amount"`
+}
+
+func (e Event) Decode(v any) error {
+	return json.Unmarshal(e.Payload, v)
+}
+
+type Filter func(e Event) bool
+
+type subscriber struct {
+	ch     chan Event
+	filter Filter
+}
+
+type Broker struct {
+	mu          sync.RWMutex
+	nextID      int64
+	subscribers map[int64]subscriber
+}
+
+func NewBroker() *Broker {
+	return &Broker{
+		subscribers: make(map[int64]subscriber),
+	}
+}
+
+// Subscribe returns channel with events matching filter and function which must be called to unsubscribe.
+// Channel is closed when subscriber falls behind and an event is dropped, subscriber must read missed events
+// from storage then.
+func (b *Broker) Subscribe(filter Filter) (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBufferSize)
+	if b == nil {
+		return ch, func() {}
+	}
+
+	b.mu.Lock()
+	b.nextID++
+	ID := b.nextID
+	b.subscribers[ID] = subscriber{ch: ch, filter: filter}
+	b.mu.Unlock()
+
+	once := sync.Once{}
+	return ch, func() {
+		once.Do(
+			func() {
+				b.mu.Lock()
+				delete(b.subscribers, ID)
+				b.mu.Unlock()
+			},
+		)
+	}
+}
+
+// Publish never blocks: subscribers whose buffer is full are dropped and their channels are closed, so they
+// never skip an event silently.
+func (b *Broker) Publish(e Event) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ID, s := range b.subscribers {
+		if s.filter != nil && !s.filter(e) {
+			continue
+		}
+		select {
+		case s.ch <- e:
+		default:
+			delete(b.subscribers, ID)
+			close(s.ch)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBrokerClosesOverflowedSubscriber(t *testing.T) {
+	b := NewBroker()
+
+	slow, unsubscribeSlow := b.Subscribe(nil)
+	defer unsubscribeSlow()
+	other, unsubscribeOther := b.Subscribe(func(e Event) bool { return e.UserID == 2 })
+	defer unsubscribeOther()
+
+	for i := int64(1); i <= subscriberBufferSize+1; i++ {
+		b.Publish(Event{ID: i, UserID: 1})
+	}
+
+	received := 0
+	for range slow {
+		received++
+	}
+	assert.Equal(t, subscriberBufferSize, received)
+
+	//filtered out subscriber is kept
+	b.Publish(Event{ID: 100, UserID: 2})
+	e, ok := <-other
+	assert.True(t, ok)
+	assert.Equal(t, int64(100), e.ID)
+}
@@ -0,0 +1,44 @@
+package relay
+
+import (
+	"context"
+	"time"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+)
+
+const (
+	minReconnectDelay = time.Second
+	maxReconnectDelay = time.Second * 30
+)
+
+// Run relays events committed by any instance (Postgres LISTEN/NOTIFY) to the in-process broker.
+func Run(shutdownCtx context.Context, d dependencies.D) {
+	delay := minReconnectDelay
+
+	for {
+		startedAt := time.Now()
+		listenErr := d.EventsStorage.Listen(shutdownCtx, d.Events.Publish)
+
+		if shutdownCtx.Err() != nil {
+			break
+		}
+
+		if time.Since(startedAt) > maxReconnectDelay {
+			delay = minReconnectDelay
+		}
+		d.Logger.Errorw("Events listener stopped, reconnecting", "error", listenErr, "delay", delay)
+
+		select {
+		case <-shutdownCtx.Done():
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+	}
+
+	d.Logger.Info("Stopping events relay.....")
+}
@@ -25,9 +25,10 @@ func MakeJWT(secret string, payload map[string]any) (string, error) {
 	return tokenString, nil
 }
 
-func MakeJWTPayload(ID int64) map[string]any {
+func MakeJWTPayload(ID int64, role string) map[string]any {
 	return map[string]any{
-		"ID": ID,
+		"ID":   ID,
+		"role": role,
 	}
 }
 
@@ -0,0 +1,48 @@
+// Package leader elects single instance to run singleton background jobs.
+package leader
+
+import (
+	"context"
+	"time"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+)
+
+const (
+	// LockKey is Postgres advisory lock key held by leader.
+	LockKey = 7_261_001
+	// Leader checks its lock connection every CheckInterval. Followers try to take over every RetryInterval.
+	CheckInterval = time.Second * 5
+	RetryInterval = time.Second * 5
+)
+
+// Run blocks until shutdownCtx is done. While instance is leader jobs are run with ctx which is canceled
+// when leadership is lost, so another instance can take over.
+func Run(shutdownCtx context.Context, d dependencies.D, jobs func(leaderCtx context.Context)) {
+	for {
+		acquired, lockErr := d.LocksStorage.RunWithLock(
+			shutdownCtx, LockKey, CheckInterval, func(leaderCtx context.Context) {
+				d.Logger.Info("Instance became leader")
+				d.Leader.Store(true)
+				jobs(leaderCtx)
+				d.Leader.Store(false)
+			},
+		)
+
+		if shutdownCtx.Err() != nil {
+			break
+		}
+		if lockErr != nil {
+			d.Logger.Errorw("Leadership is lost", "error", lockErr, "acquired", acquired)
+		} else if acquired {
+			d.Logger.Info("Leadership is released")
+		}
+
+		select {
+		case <-shutdownCtx.Done():
+		case <-time.After(RetryInterval):
+		}
+	}
+
+	d.Logger.Info("Stopping leader election.....")
+}
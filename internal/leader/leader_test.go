@@ -0,0 +1,52 @@
+package leader
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	mockstorage "github.com/bobgromozeka/yp-diploma1/internal/storage/mock"
+	"github.com/bobgromozeka/yp-diploma1/internal/testutils"
+)
+
+func TestRunJobsWhileLeader(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	shutdownCtx, shutdown := context.WithCancel(context.Background())
+	defer shutdown()
+
+	lStorage := mockstorage.NewMockLocksStorage(ctrl)
+	lStorage.
+		EXPECT().
+		RunWithLock(testutils.MatchContext(), gomock.Eq(int64(LockKey)), gomock.Eq(CheckInterval), gomock.Any()).
+		DoAndReturn(
+			func(ctx context.Context, _ int64, _ time.Duration, fn func(lockCtx context.Context)) (bool, error) {
+				fn(ctx)
+				return true, nil
+			},
+		)
+
+	d := dependencies.D{
+		LocksStorage: lStorage,
+		Leader:       &atomic.Bool{},
+		Logger:       zap.NewExample().Sugar(),
+	}
+
+	var wasLeader bool
+	Run(
+		shutdownCtx, d, func(leaderCtx context.Context) {
+			wasLeader = d.Leader.Load()
+			shutdown()
+		},
+	)
+
+	assert.True(t, wasLeader)
+	assert.False(t, d.Leader.Load())
+}
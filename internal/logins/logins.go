@@ -0,0 +1,16 @@
+// Package logins holds login steps shared by http and gRPC servers.
+package logins
+
+import (
+	"context"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	"github.com/bobgromozeka/yp-diploma1/internal/models"
+)
+
+// Record does not fail login, audit storage unavailability should not lock users out.
+func Record(ctx context.Context, d dependencies.D, e models.AuditEvent) {
+	if auditErr := d.AuditStorage.RecordAuditEvent(ctx, e); auditErr != nil {
+		d.Logger.Errorw("Record login audit event", "error", auditErr)
+	}
+}
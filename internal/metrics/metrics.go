@@ -0,0 +1,27 @@
+// Package metrics keeps variables shown on admin metrics endpoint. Unlike expvar default handler it does not
+// expose command line and memory stats, command line contains secrets passed as flags.
+package metrics
+
+import (
+	"expvar"
+	"net/http"
+
+	httphelpers "github.com/bobgromozeka/yp-diploma1/internal/http"
+)
+
+var vars = new(expvar.Map)
+
+// Publish adds variable or replaces variable with the same name.
+func Publish(name string, v expvar.Var) {
+	vars.Set(name, v)
+}
+
+// Handler writes published variables as JSON object.
+func Handler() http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", httphelpers.ContentJSON)
+			w.Write([]byte(vars.String()))
+		},
+	)
+}
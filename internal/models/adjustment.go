@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	AdjustmentStatusPending  = "PENDING"
+	AdjustmentStatusApplied  = "APPLIED"
+	AdjustmentStatusRejected = "REJECTED"
+)
+
+const (
+	AdjustmentReasonAccrualCorrection = "accrual_correction"
+	AdjustmentReasonGoodwill          = "goodwill"
+	AdjustmentReasonFraud             = "fraud"
+	AdjustmentReasonOther             = "other"
+)
+
+var AdjustmentReasons = []string{
+	AdjustmentReasonAccrualCorrection, AdjustmentReasonGoodwill, AdjustmentReasonFraud, AdjustmentReasonOther,
+}
+
+// BalanceAdjustment is manual credit (positive amount) or debit (negative amount) made by admin.
+type BalanceAdjustment struct {
+	ID        int64      `json:"id"`
+	UserID    int64      `json:"user_id"`
+	Amount    float64    `json:"amount"`
+	Reason    string     `json:"reason"`
+	Comment   string     `json:"comment,omitempty"`
+	Status    string     `json:"status"`
+	CreatedBy int64      `json:"created_by"`
+	DecidedBy *int64     `json:"decided_by,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	DecidedAt *time.Time `json:"decided_at,omitempty"`
+}
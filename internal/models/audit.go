@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	AuditUserRegistered     = "user.registered"
+	AuditUserLoggedIn       = "user.logged_in"
+	AuditUserLoginFailed    = "user.login_failed"
+	AuditOrderCreated       = "order.created"
+	AuditBalanceWithdrawn   = "balance.withdrawn"
+	AuditWithdrawalReversed = "balance.withdrawal_reversed"
+	AuditBalanceAccrued     = "balance.accrued"
+	AuditBalanceExpired     = "balance.expired"
+	AuditBalanceAdjusted    = "balance.adjusted"
+	AuditPendingAccrued     = "pending.accrued"
+	AuditPendingClawedBack  = "pending.clawed_back"
+	AuditTransferSent       = "balance.transfer_sent"
+	AuditTransferReceived   = "balance.transfer_received"
+)
+
+// AuditEvent is append-only record of security-relevant or financial action.
+// ActorID is empty for actions made by the system itself (e.g. accrual updater).
+type AuditEvent struct {
+	ID           int64     `json:"id"`
+	Action       string    `json:"action"`
+	ActorID      *int64    `json:"actor_id,omitempty"`
+	UserID       *int64    `json:"user_id,omitempty"`
+	Login        string    `json:"login,omitempty"`
+	Order        string    `json:"order,omitempty"`
+	AmountBefore *float64  `json:"amount_before,omitempty"`
+	AmountAfter  *float64  `json:"amount_after,omitempty"`
+	IP           string    `json:"ip,omitempty"`
+	UserAgent    string    `json:"user_agent,omitempty"`
+	RequestID    string    `json:"request_id,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type AuditFilter struct {
+	UserID    *int64
+	Action    string
+	RequestID string
+	From      *time.Time
+	To        *time.Time
+	Limit     int
+}
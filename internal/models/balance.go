@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	PendingAccrualStatusPending    = "PENDING"
+	PendingAccrualStatusReleased   = "RELEASED"
+	PendingAccrualStatusClawedBack = "CLAWED_BACK"
+)
+
+// Balance is spendable (current) points, points reserved by holds (held),
+// accrued points which are not spendable yet (pending) and sum of all withdrawals.
+type Balance struct {
+	Current   float64
+	Held      float64
+	Pending   float64
+	Withdrawn float64
+}
+
+// AccrualTerms describe how accrued points are credited. Nil ReleaseAt means points are spendable right away,
+// nil ExpiresAt means points never expire.
+type AccrualTerms struct {
+	ReleaseAt *time.Time
+	ExpiresAt *time.Time
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	HoldStatusHeld     = "HELD"
+	HoldStatusCaptured = "CAPTURED"
+	HoldStatusReleased = "RELEASED"
+)
+
+// Hold reserves points for order until it is captured (spent) or released.
+type Hold struct {
+	ID          int64      `json:"id"`
+	UserID      int64      `json:"-"`
+	OrderNumber string     `json:"order"`
+	Amount      float64    `json:"amount"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	ClosedAt    *time.Time `json:"closed_at,omitempty"`
+}
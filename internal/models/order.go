@@ -14,6 +14,13 @@ const (
 
 var OrderFirstStatus = OrderStatusNew
 
+const (
+	OrderUploadAccepted       = "accepted"
+	OrderUploadAlreadyCreated = "already_created"
+	OrderUploadForeign        = "foreign"
+	OrderUploadInvalid        = "invalid"
+)
+
 type Order struct {
 	ID         int64        `json:"-"`
 	UserID     int64        `json:"-"`
@@ -23,3 +30,21 @@ type Order struct {
 	UploadedAt time.Time    `json:"uploaded_at"`
 	UpdatedAt  sql.NullTime `json:"-"`
 }
+
+type OrderUploadResult struct {
+	Number string `json:"number"`
+	Status string `json:"status"`
+	// Reason describes why number is invalid.
+	Reason string `json:"reason,omitempty"`
+}
+
+// AccrualResult is order result pushed by accrual system which is not applied yet.
+type AccrualResult struct {
+	Order   string
+	Status  string
+	Accrual *float64
+}
+
+func (o Order) IsFinal() bool {
+	return o.Status == OrderStatusProcessed || o.Status == OrderStatusInvalid
+}
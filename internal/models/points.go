@@ -0,0 +1,11 @@
+package models
+
+import (
+	"time"
+)
+
+// ExpiringPoints is amount of points which expire at the same day.
+type ExpiringPoints struct {
+	Amount    float64   `json:"amount"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
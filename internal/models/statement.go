@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	StatementEntryAccrual     = "accrual"
+	StatementEntryWithdrawal  = "withdrawal"
+	StatementEntryAdjustment  = "adjustment"
+	StatementEntryExpiration  = "expiration"
+	StatementEntryReversal    = "reversal"
+	StatementEntryTransferIn  = "transfer_in"
+	StatementEntryTransferOut = "transfer_out"
+)
+
+// StatementEntry is one balance change. Amount is negative for debits, Balance is balance right after the change.
+type StatementEntry struct {
+	Type    string    `json:"type"`
+	Order   string    `json:"order,omitempty"`
+	Amount  float64   `json:"amount"`
+	Balance float64   `json:"balance"`
+	At      time.Time `json:"at"`
+	Reason  string    `json:"reason,omitempty"`
+	// Counterparty is login of the other side of transfer.
+	Counterparty string `json:"counterparty,omitempty"`
+}
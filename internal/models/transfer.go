@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+// Transfer moves points from one user to another.
+type Transfer struct {
+	ID         int64     `json:"id"`
+	FromUserID int64     `json:"-"`
+	ToUserID   int64     `json:"-"`
+	To         string    `json:"to"`
+	Amount     float64   `json:"amount"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,18 @@
+package models
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+	// RoleService is for trusted integrations like store backend.
+	RoleService = "service"
+)
+
+var Roles = []string{RoleUser, RoleAdmin, RoleService}
+
+type User struct {
+	ID    int64  `json:"id"`
+	Login string `json:"login"`
+	Role  string `json:"role"`
+	// Store is partner store which user uploads orders of, empty means default store.
+	Store string `json:"store,omitempty"`
+}
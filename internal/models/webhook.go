@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/events"
+)
+
+const (
+	WebhookDeliveryPending   = "PENDING"
+	WebhookDeliveryDelivered = "DELIVERED"
+	WebhookDeliveryFailed    = "FAILED"
+)
+
+type WebhookEndpoint struct {
+	ID         int64     `json:"id"`
+	UserID     *int64    `json:"-"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"secret,omitempty"`
+	EventTypes []string  `json:"event_types"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type WebhookDelivery struct {
+	ID       int64
+	Attempts int
+	Endpoint WebhookEndpoint
+	Event    events.Event
+}
+
+type WebhookDeliveryAttempt struct {
+	ID             int64     `json:"-"`
+	DeliveryID     int64     `json:"delivery_id"`
+	EventID        int64     `json:"event_id"`
+	EventType      string    `json:"event_type"`
+	AttemptedAt    time.Time `json:"attempted_at"`
+	ResponseStatus *int      `json:"response_status,omitempty"`
+	Error          string    `json:"error,omitempty"`
+	DurationMs     int64     `json:"duration_ms"`
+}
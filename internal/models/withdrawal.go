@@ -5,9 +5,11 @@ import (
 )
 
 type Withdrawal struct {
-	ID          int64     `json:"-"`
-	UserID      int64     `json:"-"`
-	OrderNumber string    `json:"order"`
-	Sum         float64   `json:"sum"`
-	ProcessedAt time.Time `json:"processed_at"`
+	ID             int64      `json:"-"`
+	UserID         int64      `json:"-"`
+	OrderNumber    string     `json:"order"`
+	Sum            float64    `json:"sum"`
+	ProcessedAt    time.Time  `json:"processed_at"`
+	ReversedAt     *time.Time `json:"reversed_at,omitempty"`
+	ReversalReason string     `json:"reversal_reason,omitempty"`
 }
@@ -0,0 +1,166 @@
+package orderformat
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		number string
+		want   string
+	}{
+		{"Valid Luhn number", "4561261212345467", ""},
+		{"Wrong checksum", "4561261212345464", "order number should pass Luhn checksum"},
+		{"Single zero passes Luhn but is too short", "0", "order number should be from 2 to 32 characters long"},
+		{"Too long", "000000000000000000000000000000000", "order number should be from 2 to 32 characters long"},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				err := NewRegistry().Validate("", tt.number)
+				if tt.want == "" {
+					assert.NoError(t, err)
+					return
+				}
+				var validationErr *ValidationError
+				assert.ErrorAs(t, err, &validationErr)
+				assert.EqualError(t, err, tt.want)
+			},
+		)
+	}
+}
+
+func TestParse(t *testing.T) {
+	r, parseErr := Parse("default=luhn,length:2-19; megastore=prefix:42|43,length:16,luhn;books=regex:^BK[0-9]+$")
+	require.NoError(t, parseErr)
+
+	tests := []struct {
+		name   string
+		store  string
+		number string
+		want   string
+	}{
+		{"Default store", DefaultStore, "79927398713", ""},
+		{"Empty store is default", "", "4561261212345467", ""},
+		{"Default store length", "", "45612612123454670000", "order number should be from 2 to 19 characters long"},
+		{"Prefix", "megastore", "4200000000000000", ""},
+		{"Wrong prefix", "megastore", "4561261212345467", "order number should start with 42 or 43"},
+		{"Exact length", "megastore", "42000000000000006", "order number should be 16 characters long"},
+		{"Regex", "books", "BK123", ""},
+		{"Wrong regex", "books", "123", "order number should match ^BK[0-9]+$"},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				err := r.Validate(tt.store, tt.number)
+				if tt.want == "" {
+					assert.NoError(t, err)
+				} else {
+					assert.EqualError(t, err, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestParseRegexWithSeparators(t *testing.T) {
+	r, parseErr := Parse(`bank=regex:^[0-9]{8,12}$,luhn;tickets=regex:^(AB|CD)[;,(]\,[0-9]+$`)
+	require.NoError(t, parseErr)
+
+	assert.NoError(t, r.Validate("bank", "79927398713"))
+	assert.EqualError(t, r.Validate("bank", "4561261212345467"), "order number should match ^[0-9]{8,12}$")
+	assert.EqualError(t, r.Validate("bank", "79927398710"), "order number should pass Luhn checksum")
+	assert.NoError(t, r.Validate("tickets", "AB(,1"))
+	assert.NoError(t, r.Validate("tickets", "CD;,2"))
+	assert.Error(t, r.Validate("tickets", "AB,1"))
+}
+
+func TestParseKeepsDefaultFormat(t *testing.T) {
+	r, parseErr := Parse("books=digits,length:5-")
+	require.NoError(t, parseErr)
+
+	assert.EqualError(t, r.Validate("", "0"), "order number should be from 2 to 32 characters long")
+	assert.EqualError(t, r.Validate("books", "1234"), "order number should be at least 5 characters long")
+	assert.EqualError(t, r.Validate("books", "1234a"), "order number should contain only digits")
+	assert.NoError(t, r.Validate("books", "12345678901234567890123456789012345"))
+}
+
+func TestParseWrongRules(t *testing.T) {
+	for _, spec := range []string{
+		"luhn",
+		"=luhn",
+		"default=unknown",
+		"default=length:a",
+		"default=length:5-2",
+		"default=prefix:42|",
+		"default=regex:[",
+	} {
+		_, parseErr := Parse(spec)
+		assert.ErrorIs(t, parseErr, ErrWrongRule, spec)
+	}
+}
+
+func TestUnknownStore(t *testing.T) {
+	err := NewRegistry().Validate("megastore", "4561261212345467")
+	assert.ErrorIs(t, err, ErrUnknownStore)
+	assert.EqualError(t, err, `unknown store "megastore"`)
+}
+
+func TestHasPartners(t *testing.T) {
+	var nilRegistry *Registry
+	assert.False(t, nilRegistry.HasPartners())
+	assert.True(t, nilRegistry.Has(DefaultStore))
+	assert.False(t, NewRegistry().HasPartners())
+
+	r, parseErr := Parse("default=digits")
+	require.NoError(t, parseErr)
+	assert.False(t, r.HasPartners())
+
+	r, parseErr = Parse("books=digits")
+	require.NoError(t, parseErr)
+	assert.True(t, r.HasPartners())
+	assert.True(t, r.Has("books"))
+	assert.False(t, r.Has("megastore"))
+}
+
+func TestNilRegistry(t *testing.T) {
+	var r *Registry
+
+	assert.NoError(t, r.Validate("", "4561261212345467"))
+	assert.EqualError(t, r.Validate("", "0"), "order number should be from 2 to 32 characters long")
+	assert.ErrorIs(t, r.Validate("megastore", "4561261212345467"), ErrUnknownStore)
+}
+
+type evenRule struct{}
+
+func (evenRule) Check(number string) error {
+	if number[len(number)-1]%2 != 0 {
+		return &ValidationError{Rule: "even", Reason: "should be even"}
+	}
+
+	return nil
+}
+
+func TestRegisterRule(t *testing.T) {
+	RegisterRule(
+		"even", func(arg string) (Rule, error) {
+			if arg != "" {
+				return nil, errors.New("no argument expected")
+			}
+			return evenRule{}, nil
+		},
+	)
+
+	r, parseErr := Parse("partner=digits,even")
+	require.NoError(t, parseErr)
+	assert.NoError(t, r.Validate("partner", "12"))
+	assert.EqualError(t, r.Validate("partner", "13"), "order number should be even")
+
+	_, parseErr = Parse("partner=even:1")
+	assert.ErrorIs(t, parseErr, ErrWrongRule)
+}
@@ -0,0 +1,147 @@
+package orderformat
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultStore is used for numbers uploaded without store.
+const DefaultStore = "default"
+
+var ErrUnknownStore = errors.New("unknown store")
+
+// DefaultFormat is used unless default store is configured explicitly.
+var DefaultFormat = Format{Luhn{}, Length{Min: 2, Max: 32}}
+
+// Format accepts number which passes every rule.
+type Format []Rule
+
+// Check returns error of the first rule which rejects number.
+func (f Format) Check(number string) error {
+	for _, rule := range f {
+		if checkErr := rule.Check(number); checkErr != nil {
+			return checkErr
+		}
+	}
+
+	return nil
+}
+
+// Registry keeps order number format of every partner store.
+type Registry struct {
+	formats map[string]Format
+}
+
+func NewRegistry() *Registry {
+	return &Registry{formats: map[string]Format{DefaultStore: DefaultFormat}}
+}
+
+func (r *Registry) Set(store string, f Format) {
+	r.formats[store] = f
+}
+
+// Has reports whether format of store is known. Nil registry knows DefaultStore only.
+func (r *Registry) Has(store string) bool {
+	if r == nil {
+		return store == DefaultStore
+	}
+
+	_, found := r.formats[store]
+
+	return found
+}
+
+// HasPartners reports whether formats of stores other than DefaultStore are known.
+func (r *Registry) HasPartners() bool {
+	if r == nil {
+		return false
+	}
+
+	_, hasDefault := r.formats[DefaultStore]
+	if hasDefault {
+		return len(r.formats) > 1
+	}
+
+	return len(r.formats) > 0
+}
+
+// Validate checks number in format of store, empty store means DefaultStore. Nil registry uses DefaultFormat.
+func (r *Registry) Validate(store string, number string) error {
+	if store == "" {
+		store = DefaultStore
+	}
+	if r == nil {
+		if store != DefaultStore {
+			return fmt.Errorf("%w %q", ErrUnknownStore, store)
+		}
+		return DefaultFormat.Check(number)
+	}
+
+	f, found := r.formats[store]
+	if !found {
+		return fmt.Errorf("%w %q", ErrUnknownStore, store)
+	}
+
+	return f.Check(number)
+}
+
+// Parse reads formats like "default=luhn,length:2-19;megastore=prefix:42|43,length:16,luhn;books=regex:^BK[0-9]+$".
+// Stores are separated by ";", rules by ",", rule argument follows ":". Separators inside brackets, braces,
+// parentheses or escaped by "\" belong to argument, so regex like "^[0-9]{8,12}$" is kept whole. Missing default
+// store keeps DefaultFormat.
+func Parse(spec string) (*Registry, error) {
+	r := NewRegistry()
+
+	for _, storeSpec := range splitTopLevel(spec, ';') {
+		storeSpec = strings.TrimSpace(storeSpec)
+		if storeSpec == "" {
+			continue
+		}
+
+		store, rulesSpec, found := strings.Cut(storeSpec, "=")
+		store = strings.TrimSpace(store)
+		if !found || store == "" {
+			return nil, fmt.Errorf("%w: %q should be store=rules", ErrWrongRule, storeSpec)
+		}
+
+		var f Format
+		for _, ruleSpec := range splitTopLevel(rulesSpec, ',') {
+			name, arg, _ := strings.Cut(strings.TrimSpace(ruleSpec), ":")
+			rule, ruleErr := makeRule(name, arg)
+			if ruleErr != nil {
+				return nil, fmt.Errorf("store %s: %w", store, ruleErr)
+			}
+			f = append(f, rule)
+		}
+		r.Set(store, f)
+	}
+
+	return r, nil
+}
+
+// splitTopLevel splits s by sep which is not escaped and not nested in (), {} or character class [].
+func splitTopLevel(s string, sep byte) []string {
+	var parts []string
+	depth, start, inClass := 0, 0, false
+
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\':
+			i++
+		case inClass:
+			inClass = s[i] != ']'
+		case s[i] == '[':
+			inClass = true
+		case s[i] == '(' || s[i] == '{':
+			depth++
+		case (s[i] == ')' || s[i] == '}') && depth > 0:
+			depth--
+		case s[i] == sep && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, s[start:])
+}
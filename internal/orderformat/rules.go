@@ -0,0 +1,181 @@
+package orderformat
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/functions"
+)
+
+var ErrWrongRule = errors.New("wrong order format rule")
+
+// ValidationError describes why order number is rejected, Error() is ready to be shown to users.
+type ValidationError struct {
+	Rule   string
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return "order number " + e.Reason
+}
+
+// Rule returns *ValidationError for numbers it rejects.
+type Rule interface {
+	Check(number string) error
+}
+
+// RuleFactory makes rule from its argument, e.g. "2-19" of "length:2-19". Argument is empty for rules without it.
+type RuleFactory func(arg string) (Rule, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = map[string]RuleFactory{
+		"luhn":   func(string) (Rule, error) { return Luhn{}, nil },
+		"digits": func(string) (Rule, error) { return Digits{}, nil },
+		"length": parseLength,
+		"prefix": parsePrefix,
+		"regex":  parseRegex,
+	}
+)
+
+// RegisterRule makes rule available for Parse under name. Built-in rules may be replaced.
+func RegisterRule(name string, factory RuleFactory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+
+	factories[name] = factory
+}
+
+func makeRule(name string, arg string) (Rule, error) {
+	factoriesMu.RLock()
+	factory, found := factories[name]
+	factoriesMu.RUnlock()
+	if !found {
+		return nil, fmt.Errorf("%w: unknown rule %q", ErrWrongRule, name)
+	}
+
+	rule, ruleErr := factory(arg)
+	if ruleErr != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrWrongRule, name, ruleErr)
+	}
+
+	return rule, nil
+}
+
+type Luhn struct{}
+
+func (Luhn) Check(number string) error {
+	if !functions.CheckLuhn(number) {
+		return &ValidationError{Rule: "luhn", Reason: "should pass Luhn checksum"}
+	}
+
+	return nil
+}
+
+type Digits struct{}
+
+func (Digits) Check(number string) error {
+	if number == "" || strings.IndexFunc(number, func(r rune) bool { return r < '0' || r > '9' }) >= 0 {
+		return &ValidationError{Rule: "digits", Reason: "should contain only digits"}
+	}
+
+	return nil
+}
+
+// Length limits number of characters, zero Max means no upper limit.
+type Length struct {
+	Min int
+	Max int
+}
+
+func (l Length) Check(number string) error {
+	if len(number) >= l.Min && (l.Max == 0 || len(number) <= l.Max) {
+		return nil
+	}
+
+	var reason string
+	switch {
+	case l.Min == l.Max:
+		reason = fmt.Sprintf("should be %d characters long", l.Min)
+	case l.Max == 0:
+		reason = fmt.Sprintf("should be at least %d characters long", l.Min)
+	default:
+		reason = fmt.Sprintf("should be from %d to %d characters long", l.Min, l.Max)
+	}
+
+	return &ValidationError{Rule: "length", Reason: reason}
+}
+
+// parseLength accepts "N", "MIN-MAX" and "MIN-".
+func parseLength(arg string) (Rule, error) {
+	rawMin, rawMax, isRange := strings.Cut(arg, "-")
+	if !isRange {
+		rawMax = rawMin
+	}
+
+	minLength, minErr := strconv.Atoi(rawMin)
+	if minErr != nil || minLength < 0 {
+		return nil, errors.New("wrong minimum length")
+	}
+	maxLength := 0
+	if rawMax != "" {
+		var maxErr error
+		maxLength, maxErr = strconv.Atoi(rawMax)
+		if maxErr != nil || maxLength < minLength {
+			return nil, errors.New("wrong maximum length")
+		}
+	}
+
+	return Length{Min: minLength, Max: maxLength}, nil
+}
+
+type Prefix struct {
+	Prefixes []string
+}
+
+func (p Prefix) Check(number string) error {
+	for _, prefix := range p.Prefixes {
+		if strings.HasPrefix(number, prefix) {
+			return nil
+		}
+	}
+
+	return &ValidationError{Rule: "prefix", Reason: "should start with " + strings.Join(p.Prefixes, " or ")}
+}
+
+// parsePrefix accepts "|" separated prefixes.
+func parsePrefix(arg string) (Rule, error) {
+	prefixes := strings.Split(arg, "|")
+	for _, prefix := range prefixes {
+		if prefix == "" {
+			return nil, errors.New("empty prefix")
+		}
+	}
+
+	return Prefix{Prefixes: prefixes}, nil
+}
+
+type Regex struct {
+	Expr *regexp.Regexp
+}
+
+func (r Regex) Check(number string) error {
+	if !r.Expr.MatchString(number) {
+		return &ValidationError{Rule: "regex", Reason: "should match " + r.Expr.String()}
+	}
+
+	return nil
+}
+
+func parseRegex(arg string) (Rule, error) {
+	expr, compileErr := regexp.Compile(arg)
+	if compileErr != nil {
+		return nil, compileErr
+	}
+
+	return Regex{Expr: expr}, nil
+}
@@ -0,0 +1,118 @@
+package points
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	"github.com/bobgromozeka/yp-diploma1/internal/scheduler"
+)
+
+const JobInterval = time.Minute
+const JobJitter = time.Second * 5
+const JobTimeout = time.Minute * 10
+const JobBatch = 500
+
+// Expire expires all due lots in batches.
+func Expire(ctx context.Context, d dependencies.D, now time.Time) (int, error) {
+	return inBatches(ctx, now, d.PointsStorage.ExpireLots)
+}
+
+// ReleaseHolds releases all expired balance holds in batches.
+func ReleaseHolds(ctx context.Context, d dependencies.D, now time.Time) (int, error) {
+	return inBatches(ctx, now, d.HoldsStorage.ReleaseExpiredHolds)
+}
+
+// Release makes all matured pending accruals spendable in batches.
+func Release(ctx context.Context, d dependencies.D, now time.Time) (int, error) {
+	return inBatches(ctx, now, d.PointsStorage.ReleasePendingAccruals)
+}
+
+func inBatches(
+	ctx context.Context, now time.Time, job func(ctx context.Context, now time.Time, limit int) (int, error),
+) (int, error) {
+	total := 0
+	for {
+		processed, jobErr := job(ctx, now, JobBatch)
+		total += processed
+		if jobErr != nil {
+			return total, jobErr
+		}
+		if processed < JobBatch {
+			return total, nil
+		}
+	}
+}
+
+// Schedule parses cron expression of points job, see scheduler.Cron. Empty expression runs job every JobInterval.
+func Schedule(expr string) (scheduler.Schedule, error) {
+	if expr == "" {
+		return scheduler.Every(JobInterval), nil
+	}
+
+	return scheduler.Cron(expr)
+}
+
+// ReleaseJob makes matured pending accruals spendable. Points released after their expiry time are expired
+// by the next ExpireJob run.
+func ReleaseJob(d dependencies.D, schedule scheduler.Schedule) scheduler.Job {
+	return job(
+		"points_release", schedule, func(ctx context.Context) error {
+			released, releaseErr := Release(ctx, d, time.Now())
+			if releaseErr != nil {
+				return fmt.Errorf("could not release pending points: %w", releaseErr)
+			}
+			if released > 0 {
+				d.Logger.Infow("Pending points released", "accruals", released)
+			}
+
+			return nil
+		},
+	)
+}
+
+// ReleaseHoldsJob releases expired balance holds.
+func ReleaseHoldsJob(d dependencies.D, schedule scheduler.Schedule) scheduler.Job {
+	return job(
+		"holds_release", schedule, func(ctx context.Context) error {
+			releasedHolds, releaseHoldsErr := ReleaseHolds(ctx, d, time.Now())
+			if releaseHoldsErr != nil {
+				return fmt.Errorf("could not release expired holds: %w", releaseHoldsErr)
+			}
+			if releasedHolds > 0 {
+				d.Logger.Infow("Expired holds released", "holds", releasedHolds)
+			}
+
+			return nil
+		},
+	)
+}
+
+// ExpireJob expires due points.
+func ExpireJob(d dependencies.D, schedule scheduler.Schedule) scheduler.Job {
+	return job(
+		"points_expire", schedule, func(ctx context.Context) error {
+			expired, expireErr := Expire(ctx, d, time.Now())
+			if expireErr != nil {
+				return fmt.Errorf("could not expire points: %w", expireErr)
+			}
+			if expired > 0 {
+				d.Logger.Infow("Points expired", "lots", expired)
+			}
+
+			return nil
+		},
+	)
+}
+
+func job(name string, schedule scheduler.Schedule, run func(ctx context.Context) error) scheduler.Job {
+	return scheduler.Job{
+		Name:       name,
+		Schedule:   schedule,
+		Jitter:     JobJitter,
+		Timeout:    JobTimeout,
+		RunOnStart: true,
+		Run:        run,
+	}
+}
@@ -0,0 +1,137 @@
+package points
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	"github.com/bobgromozeka/yp-diploma1/internal/scheduler"
+	mockstorage "github.com/bobgromozeka/yp-diploma1/internal/storage/mock"
+	"github.com/bobgromozeka/yp-diploma1/internal/testutils"
+)
+
+func TestExpireUntilBatchIsNotFull(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Now()
+	pStorage := mockstorage.NewMockPointsStorage(ctrl)
+	gomock.InOrder(
+		pStorage.
+			EXPECT().
+			ExpireLots(testutils.MatchContext(), gomock.Eq(now), gomock.Eq(JobBatch)).
+			Return(JobBatch, nil),
+		pStorage.
+			EXPECT().
+			ExpireLots(testutils.MatchContext(), gomock.Eq(now), gomock.Eq(JobBatch)).
+			Return(3, nil),
+	)
+
+	d := dependencies.D{
+		PointsStorage: pStorage,
+		Logger:        zap.NewExample().Sugar(),
+	}
+
+	expired, expireErr := Expire(context.Background(), d, now)
+
+	assert.NoError(t, expireErr)
+	assert.Equal(t, JobBatch+3, expired)
+}
+
+func TestExpireStopsOnError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Now()
+	storageErr := errors.New("storage error")
+	pStorage := mockstorage.NewMockPointsStorage(ctrl)
+	pStorage.
+		EXPECT().
+		ExpireLots(testutils.MatchContext(), gomock.Eq(now), gomock.Eq(JobBatch)).
+		Return(2, storageErr)
+
+	d := dependencies.D{
+		PointsStorage: pStorage,
+		Logger:        zap.NewExample().Sugar(),
+	}
+
+	expired, expireErr := Expire(context.Background(), d, now)
+
+	assert.ErrorIs(t, expireErr, storageErr)
+	assert.Equal(t, 2, expired)
+}
+
+func TestRelease(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Now()
+	pStorage := mockstorage.NewMockPointsStorage(ctrl)
+	pStorage.
+		EXPECT().
+		ReleasePendingAccruals(testutils.MatchContext(), gomock.Eq(now), gomock.Eq(JobBatch)).
+		Return(7, nil)
+
+	d := dependencies.D{
+		PointsStorage: pStorage,
+		Logger:        zap.NewExample().Sugar(),
+	}
+
+	released, releaseErr := Release(context.Background(), d, now)
+
+	assert.NoError(t, releaseErr)
+	assert.Equal(t, 7, released)
+}
+
+func TestSchedule(t *testing.T) {
+	now := time.Date(2023, 1, 1, 12, 0, 30, 0, time.UTC)
+
+	every, everyErr := Schedule("")
+	assert.NoError(t, everyErr)
+	assert.Equal(t, now.Add(JobInterval), every.Next(now))
+
+	cron, cronErr := Schedule("0 3 * * *")
+	assert.NoError(t, cronErr)
+	assert.Equal(t, time.Date(2023, 1, 2, 3, 0, 0, 0, time.UTC), cron.Next(now))
+
+	_, wrongErr := Schedule("every minute")
+	assert.ErrorIs(t, wrongErr, scheduler.ErrWrongCron)
+}
+
+func TestJobsRunOwnWork(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storageErr := errors.New("storage error")
+	pStorage := mockstorage.NewMockPointsStorage(ctrl)
+	hStorage := mockstorage.NewMockHoldsStorage(ctrl)
+	pStorage.
+		EXPECT().
+		ReleasePendingAccruals(testutils.MatchContext(), gomock.Any(), gomock.Eq(JobBatch)).
+		Return(1, nil)
+	hStorage.
+		EXPECT().
+		ReleaseExpiredHolds(testutils.MatchContext(), gomock.Any(), gomock.Eq(JobBatch)).
+		Return(0, storageErr)
+	pStorage.
+		EXPECT().
+		ExpireLots(testutils.MatchContext(), gomock.Any(), gomock.Eq(JobBatch)).
+		Return(2, nil)
+
+	d := dependencies.D{
+		PointsStorage: pStorage,
+		HoldsStorage:  hStorage,
+		Logger:        zap.NewExample().Sugar(),
+	}
+	schedule := scheduler.Every(JobInterval)
+
+	assert.NoError(t, ReleaseJob(d, schedule).Run(context.Background()))
+	assert.ErrorIs(t, ReleaseHoldsJob(d, schedule).Run(context.Background()), storageErr)
+	assert.NoError(t, ExpireJob(d, schedule).Run(context.Background()))
+}
@@ -0,0 +1,223 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/accrual"
+	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	"github.com/bobgromozeka/yp-diploma1/internal/jwt"
+	"github.com/bobgromozeka/yp-diploma1/internal/logins"
+	"github.com/bobgromozeka/yp-diploma1/internal/models"
+	"github.com/bobgromozeka/yp-diploma1/internal/orderformat"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/config"
+	"github.com/bobgromozeka/yp-diploma1/internal/storage"
+	"github.com/bobgromozeka/yp-diploma1/internal/stores"
+)
+
+var errInternal = status.Error(codes.Internal, "Internal server error")
+
+type service struct {
+	d dependencies.D
+}
+
+func (s service) Register(ctx context.Context, in *RegisterRequest) (*AuthResponse, error) {
+	if in.Login == "" || in.Password == "" {
+		return nil, status.Error(codes.InvalidArgument, "Login and password are required")
+	}
+
+	createUserErr := s.d.UsersStorage.CreateUser(ctx, in.Login, in.Password)
+	if errors.Is(createUserErr, storage.ErrUserAlreadyExists) {
+		return nil, status.Error(codes.AlreadyExists, "User already exists")
+	} else if createUserErr != nil {
+		s.d.Logger.Error(createUserErr)
+		return nil, errInternal
+	}
+
+	user, authErr := s.d.UsersStorage.AuthUser(ctx, in.Login, in.Password)
+	if authErr != nil {
+		s.d.Logger.Error(authErr)
+		return nil, errInternal
+	}
+
+	return s.issueToken(user)
+}
+
+func (s service) Login(ctx context.Context, in *LoginRequest) (*AuthResponse, error) {
+	if in.Login == "" || in.Password == "" {
+		return nil, status.Error(codes.InvalidArgument, "Login and password are required")
+	}
+
+	user, authErr := s.d.UsersStorage.AuthUser(ctx, in.Login, in.Password)
+	if errors.Is(authErr, storage.ErrUserNotFound) {
+		logins.Record(ctx, s.d, models.AuditEvent{Action: models.AuditUserLoginFailed, Login: in.Login})
+		return nil, status.Error(codes.Unauthenticated, "Wrong login or password")
+	} else if authErr != nil {
+		s.d.Logger.Error(authErr)
+		return nil, errInternal
+	}
+
+	logins.Record(
+		ctx, s.d, models.AuditEvent{
+			Action:  models.AuditUserLoggedIn,
+			ActorID: &user.ID,
+			UserID:  &user.ID,
+			Login:   user.Login,
+		},
+	)
+
+	return s.issueToken(user)
+}
+
+func (s service) issueToken(user models.User) (*AuthResponse, error) {
+	token, jwtErr := jwt.MakeJWT(config.Get().JWTSecret, jwt.MakeJWTPayload(user.ID, user.Role))
+	if jwtErr != nil {
+		s.d.Logger.Error(jwtErr)
+		return nil, errInternal
+	}
+
+	return &AuthResponse{Token: token}, nil
+}
+
+func (s service) UploadOrder(ctx context.Context, in *UploadOrderRequest) (*UploadOrderResponse, error) {
+	userID, userIDErr := jwt.GetUserID(ctx)
+	if userIDErr != nil {
+		s.d.Logger.Error(userIDErr)
+		return nil, errInternal
+	}
+
+	if formatErr := s.checkOrderFormat(ctx, userID, in.Number); formatErr != nil {
+		return nil, formatErr
+	}
+
+	createOrderErr := s.d.OrdersStorage.CreateOrder(ctx, in.Number, userID)
+	switch {
+	case errors.Is(createOrderErr, storage.ErrOrderAlreadyCreated):
+		return &UploadOrderResponse{Accepted: false}, nil
+	case errors.Is(createOrderErr, storage.ErrOrderForeign):
+		return nil, status.Error(codes.AlreadyExists, "Order created by another user")
+	case createOrderErr != nil:
+		s.d.Logger.Error(createOrderErr)
+		return nil, errInternal
+	}
+
+	accrual.RequestPriorityCheck(s.d, in.Number)
+
+	return &UploadOrderResponse{Accepted: true}, nil
+}
+
+// checkOrderFormat returns status error if number is not in format of store bound to user.
+func (s service) checkOrderFormat(ctx context.Context, userID int64, number string) error {
+	store, storeErr := stores.Of(ctx, s.d, userID)
+	if storeErr != nil {
+		s.d.Logger.Error(storeErr)
+		return errInternal
+	}
+
+	formatErr := s.d.OrderFormats.Validate(store, number)
+	if formatErr == nil {
+		return nil
+	}
+	if errors.Is(formatErr, orderformat.ErrUnknownStore) {
+		//stores of accounts are checked on start, so it is a configuration mismatch between instances
+		s.d.Logger.Errorw("Store of user is not configured", "user_id", userID, "error", formatErr)
+		return errInternal
+	}
+
+	return status.Error(codes.InvalidArgument, "Wrong order format: "+formatErr.Error())
+}
+
+func (s service) ListOrders(ctx context.Context, _ *Empty) (*OrdersResponse, error) {
+	userID, userIDErr := jwt.GetUserID(ctx)
+	if userIDErr != nil {
+		s.d.Logger.Error(userIDErr)
+		return nil, errInternal
+	}
+
+	orders, ordersErr := s.d.OrdersStorage.GetUserOrders(ctx, userID)
+	if ordersErr != nil {
+		s.d.Logger.Error(ordersErr)
+		return nil, errInternal
+	}
+
+	return &OrdersResponse{Orders: orders}, nil
+}
+
+func (s service) GetBalance(ctx context.Context, _ *Empty) (*BalanceResponse, error) {
+	userID, userIDErr := jwt.GetUserID(ctx)
+	if userIDErr != nil {
+		s.d.Logger.Error(userIDErr)
+		return nil, errInternal
+	}
+
+	balance, balanceErr := s.d.WithdrawalsStorage.GetUserBalance(ctx, userID)
+	if balanceErr != nil {
+		s.d.Logger.Error(balanceErr)
+		return nil, errInternal
+	}
+
+	response := &BalanceResponse{
+		Current:   balance.Current,
+		Held:      balance.Held,
+		Pending:   balance.Pending,
+		Withdrawn: balance.Withdrawn,
+	}
+
+	if config.Get().PointsTTL > 0 {
+		expiringPoints, expiringErr := s.d.PointsStorage.GetExpiringPoints(
+			ctx, userID, time.Now().Add(config.Get().ExpiringSoonWindow),
+		)
+		if expiringErr != nil {
+			s.d.Logger.Error(expiringErr)
+			return nil, errInternal
+		}
+		response.ExpiringSoon = expiringPoints
+	}
+
+	return response, nil
+}
+
+func (s service) Withdraw(ctx context.Context, in *WithdrawRequest) (*Empty, error) {
+	if in.Sum <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "Sum must be positive")
+	}
+	userID, userIDErr := jwt.GetUserID(ctx)
+	if userIDErr != nil {
+		s.d.Logger.Error(userIDErr)
+		return nil, errInternal
+	}
+
+	if formatErr := s.checkOrderFormat(ctx, userID, in.Order); formatErr != nil {
+		return nil, formatErr
+	}
+
+	withdrawErr := s.d.WithdrawalsStorage.Withdraw(ctx, userID, in.Order, in.Sum)
+	if errors.Is(withdrawErr, storage.ErrInsufficientFunds) {
+		return nil, status.Error(codes.FailedPrecondition, "Insufficient funds")
+	} else if withdrawErr != nil {
+		s.d.Logger.Error(withdrawErr)
+		return nil, errInternal
+	}
+
+	return &Empty{}, nil
+}
+
+func (s service) ListWithdrawals(ctx context.Context, _ *Empty) (*WithdrawalsResponse, error) {
+	userID, userIDErr := jwt.GetUserID(ctx)
+	if userIDErr != nil {
+		s.d.Logger.Error(userIDErr)
+		return nil, errInternal
+	}
+
+	withdrawals, withdrawalsErr := s.d.WithdrawalsStorage.GetUserWithdrawals(ctx, userID)
+	if withdrawalsErr != nil {
+		s.d.Logger.Error(withdrawalsErr)
+		return nil, errInternal
+	}
+
+	return &WithdrawalsResponse{Withdrawals: withdrawals}, nil
+}
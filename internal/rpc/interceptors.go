@@ -0,0 +1,90 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/go-chi/jwtauth/v5"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/audit"
+	"github.com/bobgromozeka/yp-diploma1/internal/constants"
+	"github.com/bobgromozeka/yp-diploma1/internal/jwt"
+)
+
+const requestIDMetadata = "x-request-id"
+
+// publicMethods are called without token.
+var publicMethods = map[string]bool{
+	fullMethod("Register"): true,
+	fullMethod("Login"):    true,
+}
+
+func recoverer(logger *zap.SugaredLogger) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler,
+	) (resp any, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.Errorw("gRPC handler panicked", "method", info.FullMethod, "panic", r)
+				err = errInternal
+			}
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+// auditMeta does the same as middlewares.AuditMeta.
+func auditMeta(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	meta := audit.Meta{}
+
+	if p, found := peer.FromContext(ctx); found {
+		ip, _, splitErr := net.SplitHostPort(p.Addr.String())
+		if splitErr != nil {
+			ip = p.Addr.String()
+		}
+		meta.IP = ip
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	if userAgent := md.Get("user-agent"); len(userAgent) > 0 {
+		meta.UserAgent = userAgent[0]
+	}
+	if requestID := md.Get(requestIDMetadata); len(requestID) > 0 {
+		meta.RequestID = requestID[0]
+	}
+
+	return handler(audit.WithMeta(ctx, meta), req)
+}
+
+// authenticator verifies "authorization" metadata the same way as jwtauth.Verifier with jwtauth.Authenticator do
+// for HTTP API, so jwt.GetUserID works in handlers.
+func authenticator(secret string) grpc.UnaryServerInterceptor {
+	tokenAuth := jwt.GetTokenAuth(secret)
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if publicMethods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get(constants.AuthorizationHeader)
+		if len(values) < 1 || len(values[0]) < 7 || !strings.EqualFold(values[0][:7], "Bearer ") {
+			return nil, status.Error(codes.Unauthenticated, "No token")
+		}
+
+		token, verifyErr := jwtauth.VerifyToken(tokenAuth, values[0][7:])
+		if verifyErr != nil {
+			return nil, status.Error(codes.Unauthenticated, verifyErr.Error())
+		}
+
+		return handler(jwtauth.NewContext(ctx, token, nil), req)
+	}
+}
@@ -0,0 +1,319 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	"github.com/bobgromozeka/yp-diploma1/internal/models"
+	"github.com/bobgromozeka/yp-diploma1/internal/orderformat"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/config"
+	"github.com/bobgromozeka/yp-diploma1/internal/storage"
+	mockstorage "github.com/bobgromozeka/yp-diploma1/internal/storage/mock"
+	"github.com/bobgromozeka/yp-diploma1/internal/testutils"
+)
+
+const JWTSecret = "secret"
+const JWT = "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.eyJJRCI6MX0.iWW0C3KN-mB8g2Bd_aBabGuaaIz-7Mh3tPdLFM5_9eQ" //for user_id = 1
+const UserID = 1
+const OrderNumber = "4561261212345467"
+
+// startServer serves API on in-memory listener and stops it when test is finished.
+func startServer(t *testing.T, d dependencies.D) Client {
+	config.Set(config.Config{JWTSecret: JWTSecret})
+	d.Logger = zap.NewExample().Sugar()
+
+	listener := bufconn.Listen(1024 * 1024)
+	shutdownCtx, shutdown := context.WithCancel(context.Background())
+	served := make(chan error)
+	go func() {
+		served <- serve(shutdownCtx, context.Background(), d, listener)
+	}()
+
+	conn, dialErr := grpc.Dial(
+		"bufnet",
+		grpc.WithContextDialer(
+			func(ctx context.Context, _ string) (net.Conn, error) {
+				return listener.DialContext(ctx)
+			},
+		),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, dialErr)
+
+	t.Cleanup(
+		func() {
+			conn.Close()
+			shutdown()
+			assert.NoError(t, <-served)
+		},
+	)
+
+	return NewClient(conn)
+}
+
+func withToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func TestRegister(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	uStorage := mockstorage.NewMockUsersStorage(ctrl)
+	uStorage.
+		EXPECT().
+		CreateUser(testutils.MatchContext(), gomock.Eq("user"), gomock.Eq("password")).
+		Return(nil)
+	uStorage.
+		EXPECT().
+		AuthUser(testutils.MatchContext(), gomock.Eq("user"), gomock.Eq("password")).
+		Return(models.User{ID: UserID, Login: "user", Role: models.RoleUser}, nil)
+	oStorage := mockstorage.NewMockOrdersStorage(ctrl)
+	oStorage.
+		EXPECT().
+		GetUserOrders(testutils.MatchContext(), gomock.Eq(int64(UserID))).
+		Return([]models.Order{}, nil)
+
+	c := startServer(t, dependencies.D{UsersStorage: uStorage, OrdersStorage: oStorage})
+
+	resp, err := c.Register(context.Background(), &RegisterRequest{Login: "user", Password: "password"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, resp.Token)
+
+	//issued token is accepted by authenticated methods
+	_, ordersErr := c.ListOrders(withToken(resp.Token))
+	assert.NoError(t, ordersErr)
+}
+
+func TestRegisterUserExists(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	uStorage := mockstorage.NewMockUsersStorage(ctrl)
+	uStorage.
+		EXPECT().
+		CreateUser(testutils.MatchContext(), gomock.Eq("user"), gomock.Eq("password")).
+		Return(storage.ErrUserAlreadyExists)
+
+	c := startServer(t, dependencies.D{UsersStorage: uStorage})
+
+	_, err := c.Register(context.Background(), &RegisterRequest{Login: "user", Password: "password"})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	_, err = c.Register(context.Background(), &RegisterRequest{Login: "user"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestLoginWrongPassword(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	uStorage := mockstorage.NewMockUsersStorage(ctrl)
+	uStorage.
+		EXPECT().
+		AuthUser(testutils.MatchContext(), gomock.Eq("user"), gomock.Eq("wrong")).
+		Return(models.User{}, storage.ErrUserNotFound)
+	aStorage := mockstorage.NewMockAuditStorage(ctrl)
+	aStorage.
+		EXPECT().
+		RecordAuditEvent(testutils.MatchContext(), gomock.Any()).
+		DoAndReturn(
+			func(_ context.Context, e models.AuditEvent) error {
+				assert.Equal(t, models.AuditUserLoginFailed, e.Action)
+				return nil
+			},
+		)
+
+	c := startServer(t, dependencies.D{UsersStorage: uStorage, AuditStorage: aStorage})
+
+	_, err := c.Login(context.Background(), &LoginRequest{Login: "user", Password: "wrong"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestAuthenticationRequired(t *testing.T) {
+	c := startServer(t, dependencies.D{})
+
+	_, err := c.ListOrders(context.Background())
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = c.ListOrders(withToken("wrong"))
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestUploadOrder(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	oStorage := mockstorage.NewMockOrdersStorage(ctrl)
+	gomock.InOrder(
+		oStorage.
+			EXPECT().
+			CreateOrder(testutils.MatchContext(), gomock.Eq(OrderNumber), gomock.Eq(int64(UserID))).
+			Return(nil),
+		oStorage.
+			EXPECT().
+			CreateOrder(testutils.MatchContext(), gomock.Eq(OrderNumber), gomock.Eq(int64(UserID))).
+			Return(storage.ErrOrderAlreadyCreated),
+		oStorage.
+			EXPECT().
+			CreateOrder(testutils.MatchContext(), gomock.Eq(OrderNumber), gomock.Eq(int64(UserID))).
+			Return(storage.ErrOrderForeign),
+	)
+
+	priorityChecks := make(chan string, 1)
+	c := startServer(t, dependencies.D{OrdersStorage: oStorage, PriorityChecks: priorityChecks})
+
+	resp, err := c.UploadOrder(withToken(JWT), &UploadOrderRequest{Number: OrderNumber})
+	require.NoError(t, err)
+	assert.True(t, resp.Accepted)
+	assert.Equal(t, OrderNumber, <-priorityChecks)
+
+	resp, err = c.UploadOrder(withToken(JWT), &UploadOrderRequest{Number: OrderNumber})
+	require.NoError(t, err)
+	assert.False(t, resp.Accepted)
+
+	_, err = c.UploadOrder(withToken(JWT), &UploadOrderRequest{Number: OrderNumber})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	_, err = c.UploadOrder(withToken(JWT), &UploadOrderRequest{Number: "12345"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestUploadOrderInBoundStore(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	uStorage := mockstorage.NewMockUsersStorage(ctrl)
+	uStorage.
+		EXPECT().
+		GetUser(testutils.MatchContext(), gomock.Eq(int64(UserID))).
+		Return(models.User{ID: UserID, Login: "partner", Role: models.RoleUser, Store: "books"}, nil).
+		Times(2)
+
+	oStorage := mockstorage.NewMockOrdersStorage(ctrl)
+	oStorage.
+		EXPECT().
+		CreateOrder(testutils.MatchContext(), gomock.Eq("BK123"), gomock.Eq(int64(UserID))).
+		Return(nil)
+
+	formats, parseErr := orderformat.Parse("books=regex:^BK[0-9]+$")
+	require.NoError(t, parseErr)
+
+	c := startServer(
+		t, dependencies.D{
+			UsersStorage:   uStorage,
+			OrdersStorage:  oStorage,
+			OrderFormats:   formats,
+			PriorityChecks: make(chan string, 1),
+		},
+	)
+
+	resp, err := c.UploadOrder(withToken(JWT), &UploadOrderRequest{Number: "BK123"})
+	require.NoError(t, err)
+	assert.True(t, resp.Accepted)
+
+	_, err = c.UploadOrder(withToken(JWT), &UploadOrderRequest{Number: OrderNumber})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestListOrders(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	accrual := 500.0
+	uploadedAt := time.Date(2023, 8, 1, 12, 0, 0, 0, time.UTC)
+	oStorage := mockstorage.NewMockOrdersStorage(ctrl)
+	oStorage.
+		EXPECT().
+		GetUserOrders(testutils.MatchContext(), gomock.Eq(int64(UserID))).
+		Return(
+			[]models.Order{
+				{
+					Number:     OrderNumber,
+					Status:     models.OrderStatusProcessed,
+					Accrual:    &accrual,
+					UploadedAt: uploadedAt,
+				},
+			}, nil,
+		)
+
+	c := startServer(t, dependencies.D{OrdersStorage: oStorage})
+
+	resp, err := c.ListOrders(withToken(JWT))
+	require.NoError(t, err)
+	require.Len(t, resp.Orders, 1)
+	assert.Equal(t, OrderNumber, resp.Orders[0].Number)
+	assert.Equal(t, models.OrderStatusProcessed, resp.Orders[0].Status)
+	assert.Equal(t, accrual, *resp.Orders[0].Accrual)
+	assert.True(t, uploadedAt.Equal(resp.Orders[0].UploadedAt))
+}
+
+func TestBalanceAndWithdraw(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	wStorage := mockstorage.NewMockWithdrawalsStorage(ctrl)
+	wStorage.
+		EXPECT().
+		GetUserBalance(testutils.MatchContext(), gomock.Eq(int64(UserID))).
+		Return(models.Balance{Current: 100, Withdrawn: 50}, nil)
+	gomock.InOrder(
+		wStorage.
+			EXPECT().
+			Withdraw(testutils.MatchContext(), gomock.Eq(int64(UserID)), gomock.Eq(OrderNumber), gomock.Eq(float64(30))).
+			Return(nil),
+		wStorage.
+			EXPECT().
+			Withdraw(testutils.MatchContext(), gomock.Eq(int64(UserID)), gomock.Eq(OrderNumber), gomock.Eq(float64(300))).
+			Return(storage.ErrInsufficientFunds),
+	)
+	wStorage.
+		EXPECT().
+		GetUserWithdrawals(testutils.MatchContext(), gomock.Eq(int64(UserID))).
+		Return([]models.Withdrawal{{OrderNumber: OrderNumber, Sum: 30}}, nil)
+
+	c := startServer(t, dependencies.D{WithdrawalsStorage: wStorage})
+
+	balance, balanceErr := c.GetBalance(withToken(JWT))
+	require.NoError(t, balanceErr)
+	assert.Equal(t, float64(100), balance.Current)
+	assert.Equal(t, float64(50), balance.Withdrawn)
+
+	assert.NoError(t, c.Withdraw(withToken(JWT), &WithdrawRequest{Order: OrderNumber, Sum: 30}))
+	err := c.Withdraw(withToken(JWT), &WithdrawRequest{Order: OrderNumber, Sum: 300})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	withdrawals, withdrawalsErr := c.ListWithdrawals(withToken(JWT))
+	require.NoError(t, withdrawalsErr)
+	require.Len(t, withdrawals.Withdrawals, 1)
+	assert.Equal(t, OrderNumber, withdrawals.Withdrawals[0].OrderNumber)
+}
+
+func TestWithdrawWrongSum(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	//storage must not be called
+	wStorage := mockstorage.NewMockWithdrawalsStorage(ctrl)
+
+	c := startServer(t, dependencies.D{WithdrawalsStorage: wStorage})
+
+	for _, sum := range []float64{0, -100} {
+		err := c.Withdraw(withToken(JWT), &WithdrawRequest{Order: OrderNumber, Sum: sum})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	}
+}
@@ -0,0 +1,73 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"google.golang.org/grpc"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/config"
+)
+
+func NewServer(d dependencies.D) *grpc.Server {
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			recoverer(d.Logger),
+			auditMeta,
+			authenticator(config.Get().JWTSecret),
+		),
+	)
+	server.RegisterService(&ServiceDesc, service{d: d})
+
+	return server
+}
+
+// Run serves gRPC API until shutdownCtx is done, then waits for active calls until forceCtx is done.
+// Returns error if calls were aborted. Does nothing if gRPC address is not configured.
+func Run(shutdownCtx context.Context, forceCtx context.Context, d dependencies.D) error {
+	if config.Get().GRPCAddress == "" {
+		return nil
+	}
+
+	listener, listenErr := net.Listen("tcp", config.Get().GRPCAddress)
+	if listenErr != nil {
+		d.Logger.Fatalln(listenErr)
+	}
+
+	return serve(shutdownCtx, forceCtx, d, listener)
+}
+
+func serve(shutdownCtx context.Context, forceCtx context.Context, d dependencies.D, listener net.Listener) error {
+	server := NewServer(d)
+
+	//graceful shutdown
+	shutdownErr := make(chan error, 1)
+	go func() {
+		<-shutdownCtx.Done()
+
+		d.Logger.Info("Shutting down gRPC server.....")
+		stopped := make(chan struct{})
+		go func() {
+			server.GracefulStop()
+			close(stopped)
+		}()
+
+		select {
+		case <-stopped:
+			shutdownErr <- nil
+		case <-forceCtx.Done():
+			d.Logger.Error("gRPC server shutdown deadline is exceeded, aborting active calls")
+			server.Stop()
+			shutdownErr <- forceCtx.Err()
+		}
+	}()
+
+	fmt.Println("Running gRPC server on " + listener.Addr().String())
+	if serveErr := server.Serve(listener); serveErr != nil {
+		d.Logger.Fatalln(serveErr)
+	}
+
+	return <-shutdownErr
+}
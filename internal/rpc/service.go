@@ -0,0 +1,154 @@
+package rpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/models"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/requests"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/responses"
+)
+
+const ServiceName = "gophermart.Gophermart"
+
+type Empty struct{}
+
+type RegisterRequest = requests.Register
+
+type LoginRequest = requests.Login
+
+type AuthResponse struct {
+	Token string `json:"token"`
+}
+
+// UploadOrderRequest number is validated in format of store bound to user account.
+type UploadOrderRequest struct {
+	Number string `json:"number"`
+}
+
+// UploadOrderResponse is not accepted when order was already uploaded by the same user.
+type UploadOrderResponse struct {
+	Accepted bool `json:"accepted"`
+}
+
+type OrdersResponse struct {
+	Orders []models.Order `json:"orders"`
+}
+
+type BalanceResponse = responses.Balance
+
+type WithdrawRequest = requests.Withdraw
+
+type WithdrawalsResponse struct {
+	Withdrawals []models.Withdrawal `json:"withdrawals"`
+}
+
+type GophermartServer interface {
+	Register(ctx context.Context, in *RegisterRequest) (*AuthResponse, error)
+	Login(ctx context.Context, in *LoginRequest) (*AuthResponse, error)
+	UploadOrder(ctx context.Context, in *UploadOrderRequest) (*UploadOrderResponse, error)
+	ListOrders(ctx context.Context, in *Empty) (*OrdersResponse, error)
+	GetBalance(ctx context.Context, in *Empty) (*BalanceResponse, error)
+	Withdraw(ctx context.Context, in *WithdrawRequest) (*Empty, error)
+	ListWithdrawals(ctx context.Context, in *Empty) (*WithdrawalsResponse, error)
+}
+
+var ServiceDesc = grpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*GophermartServer)(nil),
+	Methods: []grpc.MethodDesc{
+		unaryMethod("Register", GophermartServer.Register),
+		unaryMethod("Login", GophermartServer.Login),
+		unaryMethod("UploadOrder", GophermartServer.UploadOrder),
+		unaryMethod("ListOrders", GophermartServer.ListOrders),
+		unaryMethod("GetBalance", GophermartServer.GetBalance),
+		unaryMethod("Withdraw", GophermartServer.Withdraw),
+		unaryMethod("ListWithdrawals", GophermartServer.ListWithdrawals),
+	},
+}
+
+func fullMethod(name string) string {
+	return "/" + ServiceName + "/" + name
+}
+
+// unaryMethod does the same as handlers generated by protoc-gen-go-grpc.
+func unaryMethod[Req any, Resp any](
+	name string, call func(GophermartServer, context.Context, *Req) (*Resp, error),
+) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: name,
+		Handler: func(
+			srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor,
+		) (any, error) {
+			in := new(Req)
+			if decodeErr := dec(in); decodeErr != nil {
+				return nil, decodeErr
+			}
+			if interceptor == nil {
+				return call(srv.(GophermartServer), ctx, in)
+			}
+
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: fullMethod(name)}
+			return interceptor(
+				ctx, in, info, func(ctx context.Context, req any) (any, error) {
+					return call(srv.(GophermartServer), ctx, req.(*Req))
+				},
+			)
+		},
+	}
+}
+
+// Client calls gophermart gRPC API. Token returned by Register or Login is sent as "authorization" metadata:
+// metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token).
+type Client struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewClient(cc grpc.ClientConnInterface) Client {
+	return Client{cc: cc}
+}
+
+func (c Client) Register(ctx context.Context, in *RegisterRequest, opts ...grpc.CallOption) (*AuthResponse, error) {
+	return invoke[AuthResponse](ctx, c.cc, "Register", in, opts)
+}
+
+func (c Client) Login(ctx context.Context, in *LoginRequest, opts ...grpc.CallOption) (*AuthResponse, error) {
+	return invoke[AuthResponse](ctx, c.cc, "Login", in, opts)
+}
+
+func (c Client) UploadOrder(
+	ctx context.Context, in *UploadOrderRequest, opts ...grpc.CallOption,
+) (*UploadOrderResponse, error) {
+	return invoke[UploadOrderResponse](ctx, c.cc, "UploadOrder", in, opts)
+}
+
+func (c Client) ListOrders(ctx context.Context, opts ...grpc.CallOption) (*OrdersResponse, error) {
+	return invoke[OrdersResponse](ctx, c.cc, "ListOrders", &Empty{}, opts)
+}
+
+func (c Client) GetBalance(ctx context.Context, opts ...grpc.CallOption) (*BalanceResponse, error) {
+	return invoke[BalanceResponse](ctx, c.cc, "GetBalance", &Empty{}, opts)
+}
+
+func (c Client) Withdraw(ctx context.Context, in *WithdrawRequest, opts ...grpc.CallOption) error {
+	_, err := invoke[Empty](ctx, c.cc, "Withdraw", in, opts)
+
+	return err
+}
+
+func (c Client) ListWithdrawals(ctx context.Context, opts ...grpc.CallOption) (*WithdrawalsResponse, error) {
+	return invoke[WithdrawalsResponse](ctx, c.cc, "ListWithdrawals", &Empty{}, opts)
+}
+
+func invoke[Resp any](
+	ctx context.Context, cc grpc.ClientConnInterface, name string, in any, opts []grpc.CallOption,
+) (*Resp, error) {
+	out := new(Resp)
+	opts = append([]grpc.CallOption{grpc.CallContentSubtype(CodecName)}, opts...)
+	if err := cc.Invoke(ctx, fullMethod(name), in, out, opts...); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
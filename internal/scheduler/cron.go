@@ -0,0 +1,139 @@
+package scheduler
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrWrongCron = errors.New("cron expression must have 5 fields: minute hour day-of-month month day-of-week")
+
+// maxCronSteps bounds search of next run time for expressions which never match (e.g. "0 0 31 2 *").
+const maxCronSteps = 100000
+
+type cron struct {
+	minute, hour, dom, month, dow []bool
+	domAny, dowAny                bool
+}
+
+type cronField struct {
+	min, max int
+}
+
+var cronFields = []cronField{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+
+// Cron parses standard 5-field expression. Fields support *, lists, ranges and steps, e.g. "*/15 9-18 * * 1-5".
+// Day of week 7 means Sunday as well as 0.
+func Cron(expr string) (Schedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return nil, ErrWrongCron
+	}
+
+	parsed := make([][]bool, len(fields))
+	for i, field := range fields {
+		values, parseErr := parseCronField(field, cronFields[i])
+		if parseErr != nil {
+			return nil, parseErr
+		}
+		parsed[i] = values
+	}
+
+	c := cron{
+		minute: parsed[0],
+		hour:   parsed[1],
+		dom:    parsed[2],
+		month:  parsed[3],
+		dow:    parsed[4],
+		domAny: fields[2] == "*",
+		dowAny: fields[4] == "*",
+	}
+	c.dow[0] = c.dow[0] || c.dow[7]
+
+	return c, nil
+}
+
+func parseCronField(field string, f cronField) ([]bool, error) {
+	values := make([]bool, f.max+1)
+
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			parsedStep, stepErr := strconv.Atoi(stepPart)
+			if stepErr != nil || parsedStep < 1 {
+				return nil, ErrWrongCron
+			}
+			step = parsedStep
+		}
+
+		from, to := f.min, f.max
+		if rangePart != "*" {
+			fromPart, toPart, isRange := strings.Cut(rangePart, "-")
+			parsedFrom, fromErr := strconv.Atoi(fromPart)
+			if fromErr != nil {
+				return nil, ErrWrongCron
+			}
+			from, to = parsedFrom, parsedFrom
+			if isRange {
+				parsedTo, toErr := strconv.Atoi(toPart)
+				if toErr != nil {
+					return nil, ErrWrongCron
+				}
+				to = parsedTo
+			} else if hasStep {
+				to = f.max
+			}
+		}
+		if from < f.min || to > f.max || from > to {
+			return nil, ErrWrongCron
+		}
+
+		for v := from; v <= to; v += step {
+			values[v] = true
+		}
+	}
+
+	return values, nil
+}
+
+// Next returns zero time if expression never matches.
+func (c cron) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+
+	for i := 0; i < maxCronSteps; i++ {
+		switch {
+		case !c.month[t.Month()]:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case !c.hour[t.Hour()]:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case !c.minute[t.Minute()]:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+// dayMatches follows cron rule: if both day fields are restricted, matching either of them is enough.
+func (c cron) dayMatches(t time.Time) bool {
+	domMatches := c.dom[t.Day()]
+	dowMatches := c.dow[t.Weekday()]
+
+	switch {
+	case c.domAny && c.dowAny:
+		return true
+	case c.domAny:
+		return dowMatches
+	case c.dowAny:
+		return domMatches
+	default:
+		return domMatches || dowMatches
+	}
+}
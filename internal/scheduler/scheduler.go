@@ -0,0 +1,216 @@
+// Package scheduler runs background jobs by interval or cron schedules.
+package scheduler
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	"github.com/bobgromozeka/yp-diploma1/internal/metrics"
+)
+
+type Schedule interface {
+	// Next returns time of the next run after given time. Zero time means job is never run again.
+	Next(after time.Time) time.Time
+}
+
+type every time.Duration
+
+// Every runs job with fixed delay after previous run is finished.
+func Every(interval time.Duration) Schedule {
+	return every(interval)
+}
+
+func (e every) Next(after time.Time) time.Time {
+	return after.Add(time.Duration(e))
+}
+
+type Job struct {
+	Name     string
+	Schedule Schedule
+	// Random delay up to Jitter is added to every run, so replicas or jobs with the same schedule don't run at once.
+	Jitter time.Duration
+	// Zero Timeout means job runs until scheduler is stopped.
+	Timeout time.Duration
+	// RunOnStart runs job right away instead of waiting for the first scheduled time.
+	RunOnStart bool
+	Run        func(ctx context.Context) error
+}
+
+type JobStatus struct {
+	Name           string     `json:"name"`
+	Runs           int64      `json:"runs"`
+	Failures       int64      `json:"failures"`
+	Running        bool       `json:"running"`
+	LastStartedAt  *time.Time `json:"last_started_at,omitempty"`
+	LastFinishedAt *time.Time `json:"last_finished_at,omitempty"`
+	LastDuration   string     `json:"last_duration,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	NextRunAt      *time.Time `json:"next_run_at,omitempty"`
+}
+
+type Scheduler struct {
+	d   dependencies.D
+	now func() time.Time
+
+	mu       sync.Mutex
+	jobs     []Job
+	statuses map[string]*JobStatus
+}
+
+func New(d dependencies.D) *Scheduler {
+	return &Scheduler{
+		d:        d,
+		now:      time.Now,
+		statuses: make(map[string]*JobStatus),
+	}
+}
+
+// Register must be called before Run. Job names must be unique.
+func (s *Scheduler) Register(job Job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.jobs = append(s.jobs, job)
+	s.statuses[job.Name] = &JobStatus{Name: job.Name}
+}
+
+// Run blocks until shutdownCtx is done and every running job returns. Runs of the same job never overlap.
+// Running jobs are not interrupted by shutdown, they run with forceCtx. Scheduler can be run again after it is
+// stopped.
+func (s *Scheduler) Run(shutdownCtx context.Context, forceCtx context.Context) {
+	s.mu.Lock()
+	jobs := append([]Job(nil), s.jobs...)
+	s.mu.Unlock()
+
+	wg := sync.WaitGroup{}
+	for _, job := range jobs {
+		wg.Add(1)
+		go func(job Job) {
+			defer wg.Done()
+			s.loop(shutdownCtx, forceCtx, job)
+		}(job)
+	}
+	wg.Wait()
+
+	s.d.Logger.Info("Stopping scheduler.....")
+}
+
+func (s *Scheduler) loop(shutdownCtx context.Context, forceCtx context.Context, job Job) {
+	runNow := job.RunOnStart
+
+	for {
+		if !runNow {
+			next := job.Schedule.Next(s.now())
+			if next.IsZero() {
+				return
+			}
+			if job.Jitter > 0 {
+				next = next.Add(time.Duration(rand.Int63n(int64(job.Jitter))))
+			}
+			s.update(job.Name, func(st *JobStatus) { st.NextRunAt = &next })
+
+			select {
+			case <-shutdownCtx.Done():
+				return
+			case <-time.After(next.Sub(s.now())):
+			}
+		}
+		runNow = false
+
+		if shutdownCtx.Err() != nil {
+			return
+		}
+		s.runJob(forceCtx, job)
+		if shutdownCtx.Err() != nil {
+			return
+		}
+	}
+}
+
+func (s *Scheduler) runJob(ctx context.Context, job Job) {
+	startedAt := s.now()
+	s.update(
+		job.Name, func(st *JobStatus) {
+			st.Running = true
+			st.LastStartedAt = &startedAt
+			st.NextRunAt = nil
+		},
+	)
+
+	jobCtx := ctx
+	if job.Timeout > 0 {
+		var cancel context.CancelFunc
+		jobCtx, cancel = context.WithTimeout(ctx, job.Timeout)
+		defer cancel()
+	}
+
+	runErr := s.safeRun(jobCtx, job)
+
+	finishedAt := s.now()
+	s.update(
+		job.Name, func(st *JobStatus) {
+			st.Running = false
+			st.Runs++
+			st.LastFinishedAt = &finishedAt
+			st.LastDuration = finishedAt.Sub(startedAt).String()
+			st.LastError = ""
+			if runErr != nil {
+				st.Failures++
+				st.LastError = runErr.Error()
+			}
+		},
+	)
+
+	if runErr != nil && ctx.Err() == nil {
+		s.d.Logger.Errorw("Scheduled job failed", "job", job.Name, "error", runErr)
+	}
+}
+
+// safeRun turns job panic into error, so one broken job does not stop the whole application.
+func (s *Scheduler) safeRun(ctx context.Context, job Job) (runErr error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			runErr = fmt.Errorf("job panicked: %v", recovered)
+		}
+	}()
+
+	return job.Run(ctx)
+}
+
+func (s *Scheduler) update(name string, fn func(st *JobStatus)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fn(s.statuses[name])
+}
+
+// Status returns copy of jobs statuses sorted by name.
+func (s *Scheduler) Status() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]JobStatus, 0, len(s.statuses))
+	for _, st := range s.statuses {
+		statuses = append(statuses, *st)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+
+	return statuses
+}
+
+// Publish exposes jobs statuses on admin metrics endpoint. Name must be unique within process.
+func (s *Scheduler) Publish(name string) {
+	metrics.Publish(
+		name, expvar.Func(
+			func() any {
+				return s.Status()
+			},
+		),
+	)
+}
@@ -0,0 +1,158 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+)
+
+func TestCronNext(t *testing.T) {
+	type testCase struct {
+		Expr  string
+		After time.Time
+		Next  time.Time
+	}
+
+	at := func(month time.Month, day int, hour int, minute int) time.Time {
+		return time.Date(2023, month, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	//2023-05-01 is Monday
+	testCases := []testCase{
+		{Expr: "* * * * *", After: at(5, 1, 10, 0).Add(time.Second * 30), Next: at(5, 1, 10, 1)},
+		{Expr: "*/15 * * * *", After: at(5, 1, 10, 1), Next: at(5, 1, 10, 15)},
+		{Expr: "0 3 * * *", After: at(5, 1, 10, 0), Next: at(5, 2, 3, 0)},
+		{Expr: "30 9-18/3 * * 1-5", After: at(5, 5, 18, 30), Next: at(5, 8, 9, 30)},
+		{Expr: "0 0 1 * *", After: at(5, 1, 0, 0), Next: at(6, 1, 0, 0)},
+		{Expr: "0 0 13 * 5", After: at(5, 1, 0, 0), Next: at(5, 5, 0, 0)},
+		{Expr: "0 0 * * 7", After: at(5, 1, 0, 0), Next: at(5, 7, 0, 0)},
+		{Expr: "0 0 1,15 12 *", After: at(5, 1, 0, 0), Next: at(12, 1, 0, 0)},
+		{Expr: "0 0 31 2 *", After: at(5, 1, 0, 0), Next: time.Time{}},
+	}
+
+	for _, tc := range testCases {
+		schedule, parseErr := Cron(tc.Expr)
+		if assert.NoError(t, parseErr, tc.Expr) {
+			assert.Equal(t, tc.Next, schedule.Next(tc.After), tc.Expr)
+		}
+	}
+}
+
+func TestCronWrongExpressions(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		_, parseErr := Cron(expr)
+		assert.ErrorIs(t, parseErr, ErrWrongCron, expr)
+	}
+}
+
+func TestSchedulerRunsJobs(t *testing.T) {
+	s := New(dependencies.D{Logger: zap.NewExample().Sugar()})
+
+	var runs atomic.Int32
+	s.Register(
+		Job{
+			Name:       "counter",
+			Schedule:   Every(time.Millisecond * 10),
+			RunOnStart: true,
+			Run: func(ctx context.Context) error {
+				runs.Add(1)
+				return nil
+			},
+		},
+	)
+	s.Register(
+		Job{
+			Name:       "panicking",
+			Schedule:   Every(time.Hour),
+			RunOnStart: true,
+			Run: func(ctx context.Context) error {
+				panic("broken job")
+			},
+		},
+	)
+	s.Register(
+		Job{
+			Name:       "slow",
+			Schedule:   Every(time.Hour),
+			Timeout:    time.Millisecond * 10,
+			RunOnStart: true,
+			Run: func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		},
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+	s.Run(ctx, context.Background())
+
+	assert.GreaterOrEqual(t, runs.Load(), int32(3))
+
+	statuses := s.Status()
+	if assert.Len(t, statuses, 3) {
+		assert.Equal(t, "counter", statuses[0].Name)
+		assert.Equal(t, int64(runs.Load()), statuses[0].Runs)
+		assert.Zero(t, statuses[0].Failures)
+
+		assert.Equal(t, "panicking", statuses[1].Name)
+		assert.Equal(t, int64(1), statuses[1].Failures)
+		assert.Equal(t, "job panicked: broken job", statuses[1].LastError)
+		assert.NotNil(t, statuses[1].NextRunAt)
+
+		assert.Equal(t, "slow", statuses[2].Name)
+		assert.Equal(t, context.DeadlineExceeded.Error(), statuses[2].LastError)
+		assert.False(t, statuses[2].Running)
+	}
+}
+
+func TestSchedulerStopsWaitingJobs(t *testing.T) {
+	s := New(dependencies.D{Logger: zap.NewExample().Sugar()})
+	s.Register(
+		Job{
+			Name:     "never",
+			Schedule: Every(time.Hour),
+			Run: func(ctx context.Context) error {
+				return errors.New("must not run")
+			},
+		},
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.Run(ctx, context.Background())
+
+	assert.Zero(t, s.Status()[0].Runs)
+}
+
+func TestSchedulerDrainsRunningJobs(t *testing.T) {
+	shutdownCtx, shutdown := context.WithCancel(context.Background())
+	var finished atomic.Bool
+
+	s := New(dependencies.D{Logger: zap.NewExample().Sugar()})
+	s.Register(
+		Job{
+			Name:       "draining",
+			Schedule:   Every(time.Hour),
+			RunOnStart: true,
+			Run: func(ctx context.Context) error {
+				shutdown()
+				time.Sleep(time.Millisecond * 20)
+				finished.Store(ctx.Err() == nil)
+				return nil
+			},
+		},
+	)
+
+	s.Run(shutdownCtx, context.Background())
+
+	assert.True(t, finished.Load(), "running job must not be aborted by shutdown")
+	assert.Equal(t, int64(1), s.Status()[0].Runs)
+}
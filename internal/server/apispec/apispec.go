@@ -0,0 +1,38 @@
+// Package apispec holds machine-readable OpenAPI specification of HTTP API.
+package apispec
+
+import (
+	"context"
+	_ "embed"
+	"sync"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Spec must describe every route of handlers.MakeMux.
+//
+//go:embed openapi.json
+var Spec []byte
+
+var (
+	loadOnce sync.Once
+	doc      *openapi3.T
+)
+
+// MustLoad parses and validates Spec once. Spec is embedded, so it panics like regexp.MustCompile does.
+func MustLoad() *openapi3.T {
+	loadOnce.Do(
+		func() {
+			loaded, loadErr := openapi3.NewLoader().LoadFromData(Spec)
+			if loadErr != nil {
+				panic(loadErr)
+			}
+			if validateErr := loaded.Validate(context.Background()); validateErr != nil {
+				panic(validateErr)
+			}
+			doc = loaded
+		},
+	)
+
+	return doc
+}
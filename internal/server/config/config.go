@@ -1,10 +1,47 @@
 package config
 
+import (
+	"time"
+)
+
 type Config struct {
-	RunAddress           string
+	RunAddress string
+	// Address of gRPC API. Empty address disables gRPC server.
+	GRPCAddress          string
 	DatabaseURI          string
 	AccrualSystemAddress string
-	JWTSecret            string
+	// Source of accrual results: "http" requests accrual system, "push" serves results posted to callback endpoint.
+	// Push provider stores results in database until accrual poller applies them.
+	AccrualProvider string
+	// Maximum number of concurrent requests to accrual system.
+	AccrualConcurrency int
+	// Secret of HMAC signatures of results pushed by accrual system. Empty secret disables callbacks.
+	AccrualCallbackSecret string
+	JWTSecret             string
+	AdminLogins           string
+	ServiceLogins         string
+	// In-flight work is aborted if it is not finished within ShutdownTimeout after termination signal.
+	ShutdownTimeout time.Duration
+	// Adjustments with absolute amount above threshold must be approved by another admin. Zero disables approvals.
+	AdjustmentApprovalThreshold float64
+	// Holds which are neither captured nor released are released automatically after HoldTTL.
+	HoldTTL time.Duration
+	// Sum of points user can transfer to other users within rolling 24 hours. Zero means no limit.
+	TransferDailyLimit float64
+	// Accrued points are pending and not spendable during hold period. Zero means no hold.
+	AccrualHoldPeriod time.Duration
+	// Accrued points expire after PointsTTL. Zero means points never expire.
+	PointsTTL time.Duration
+	// Points expiring within this window are shown in balance as expiring soon.
+	ExpiringSoonWindow time.Duration
+	// Order number formats of partner stores, see orderformat.Parse. Empty means orderformat.DefaultFormat only.
+	OrderFormats string
+	// Logins of partner store accounts, see stores.ParseLogins. Orders of other users are in default store format.
+	StoreLogins string
+	// Cron expressions of points jobs, see points.Schedule. Empty expression runs job every points.JobInterval.
+	PendingReleaseCron string
+	HoldsReleaseCron   string
+	PointsExpiryCron   string
 }
 
 var configuration Config
@@ -0,0 +1,176 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/exp/slices"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	httphelpers "github.com/bobgromozeka/yp-diploma1/internal/http"
+	"github.com/bobgromozeka/yp-diploma1/internal/jwt"
+	"github.com/bobgromozeka/yp-diploma1/internal/models"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/config"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/helpers"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/requests"
+	"github.com/bobgromozeka/yp-diploma1/internal/storage"
+)
+
+func CreateAdjustment(d dependencies.D) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !httphelpers.CheckContentType(w, r, httphelpers.ContentJSON) {
+			return
+		}
+
+		adminID, adminIDErr := jwt.GetUserID(r.Context())
+		if adminIDErr != nil {
+			d.Logger.Error(adminIDErr)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		userID, parseErr := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if parseErr != nil {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+
+		var adjustmentRequest requests.CreateAdjustment
+
+		decoder := json.NewDecoder(r.Body)
+		if decodeErr := decoder.Decode(&adjustmentRequest); decodeErr != nil {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+		if adjustmentRequest.Amount == 0 {
+			http.Error(w, "Amount should not be zero", http.StatusBadRequest)
+			return
+		}
+		if !slices.Contains(models.AdjustmentReasons, adjustmentRequest.Reason) {
+			http.Error(w, "Unknown reason", http.StatusBadRequest)
+			return
+		}
+
+		threshold := config.Get().AdjustmentApprovalThreshold
+		requireApproval := threshold > 0 && math.Abs(adjustmentRequest.Amount) > threshold
+
+		adjustment, createErr := d.AdjustmentsStorage.CreateAdjustment(
+			r.Context(), models.BalanceAdjustment{
+				UserID:    userID,
+				Amount:    adjustmentRequest.Amount,
+				Reason:    adjustmentRequest.Reason,
+				Comment:   adjustmentRequest.Comment,
+				CreatedBy: adminID,
+			}, requireApproval,
+		)
+		if createErr != nil {
+			writeAdjustmentError(w, d, createErr)
+			return
+		}
+
+		d.Logger.Infow("Balance adjustment is created", "adjustment", adjustment)
+
+		status := http.StatusCreated
+		if adjustment.Status == models.AdjustmentStatusPending {
+			status = http.StatusAccepted
+		}
+
+		w.Header().Set("Content-Type", httphelpers.ContentJSON)
+		w.WriteHeader(status)
+		if encodeErr := json.NewEncoder(w).Encode(adjustment); encodeErr != nil {
+			d.Logger.Error(encodeErr)
+		}
+	}
+}
+
+func GetUserAdjustments(d dependencies.D) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, userErr := getUser(w, r, d)
+		if userErr != nil {
+			return
+		}
+
+		adjustments, adjustmentsErr := d.AdjustmentsStorage.GetUserAdjustments(r.Context(), user.ID)
+		if adjustmentsErr != nil {
+			d.Logger.Error(adjustmentsErr)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		if len(adjustments) < 1 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if serveErr := helpers.ServeJSON(w, adjustments); serveErr != nil {
+			d.Logger.Error(serveErr)
+			return
+		}
+	}
+}
+
+func ApproveAdjustment(d dependencies.D) http.HandlerFunc {
+	return decideAdjustment(d, storage.AdjustmentsStorage.ApproveAdjustment)
+}
+
+func RejectAdjustment(d dependencies.D) http.HandlerFunc {
+	return decideAdjustment(d, storage.AdjustmentsStorage.RejectAdjustment)
+}
+
+func decideAdjustment(
+	d dependencies.D,
+	decide func(s storage.AdjustmentsStorage, ctx context.Context, ID int64, adminID int64) (
+		models.BalanceAdjustment, error,
+	),
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		adminID, adminIDErr := jwt.GetUserID(r.Context())
+		if adminIDErr != nil {
+			d.Logger.Error(adminIDErr)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		ID, parseErr := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if parseErr != nil {
+			http.Error(w, "Adjustment not found", http.StatusNotFound)
+			return
+		}
+
+		adjustment, decideErr := decide(d.AdjustmentsStorage, r.Context(), ID, adminID)
+		if decideErr != nil {
+			writeAdjustmentError(w, d, decideErr)
+			return
+		}
+
+		d.Logger.Infow("Balance adjustment is decided", "adjustment", adjustment)
+
+		if serveErr := helpers.ServeJSON(w, adjustment); serveErr != nil {
+			d.Logger.Error(serveErr)
+			return
+		}
+	}
+}
+
+func writeAdjustmentError(w http.ResponseWriter, d dependencies.D, adjustmentErr error) {
+	switch {
+	case errors.Is(adjustmentErr, storage.ErrUserNotFound):
+		http.Error(w, "User not found", http.StatusNotFound)
+	case errors.Is(adjustmentErr, storage.ErrAdjustmentNotFound):
+		http.Error(w, "Adjustment not found", http.StatusNotFound)
+	case errors.Is(adjustmentErr, storage.ErrAdjustmentDecided):
+		http.Error(w, "Adjustment is already approved or rejected", http.StatusConflict)
+	case errors.Is(adjustmentErr, storage.ErrSelfApproval):
+		http.Error(w, "Adjustment should be approved by another admin", http.StatusForbidden)
+	case errors.Is(adjustmentErr, storage.ErrInsufficientFunds):
+		http.Error(w, "Insufficient funds", http.StatusPaymentRequired)
+	default:
+		d.Logger.Error(adjustmentErr)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,79 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	"github.com/bobgromozeka/yp-diploma1/internal/models"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/helpers"
+)
+
+const (
+	AuditDefaultLimit = 100
+	AuditMaxLimit     = 1000
+)
+
+// FindAuditEvents returns newest audit events first. Filters: user_id, action, request_id, from, to (RFC3339), limit.
+func FindAuditEvents(d dependencies.D) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		filter := models.AuditFilter{
+			Action:    query.Get("action"),
+			RequestID: query.Get("request_id"),
+			Limit:     AuditDefaultLimit,
+		}
+
+		if rawUserID := query.Get("user_id"); rawUserID != "" {
+			userID, parseErr := strconv.ParseInt(rawUserID, 10, 64)
+			if parseErr != nil {
+				http.Error(w, "Wrong user_id format", http.StatusBadRequest)
+				return
+			}
+			filter.UserID = &userID
+		}
+
+		if rawLimit := query.Get("limit"); rawLimit != "" {
+			limit, parseErr := strconv.Atoi(rawLimit)
+			if parseErr != nil || limit < 1 || limit > AuditMaxLimit {
+				http.Error(w, "Limit should be from 1 to "+strconv.Itoa(AuditMaxLimit), http.StatusBadRequest)
+				return
+			}
+			filter.Limit = limit
+		}
+
+		for _, bound := range []struct {
+			name string
+			dest **time.Time
+		}{{"from", &filter.From}, {"to", &filter.To}} {
+			rawTime := query.Get(bound.name)
+			if rawTime == "" {
+				continue
+			}
+			parsedTime, parseErr := time.Parse(time.RFC3339, rawTime)
+			if parseErr != nil {
+				http.Error(w, "Wrong "+bound.name+" format", http.StatusBadRequest)
+				return
+			}
+			*bound.dest = &parsedTime
+		}
+
+		auditEvents, auditErr := d.AuditStorage.FindAuditEvents(r.Context(), filter)
+		if auditErr != nil {
+			d.Logger.Error(auditErr)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		if len(auditEvents) < 1 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if serveErr := helpers.ServeJSON(w, auditEvents); serveErr != nil {
+			d.Logger.Error(serveErr)
+			return
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	httphelpers "github.com/bobgromozeka/yp-diploma1/internal/http"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/helpers"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/requests"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/responses"
+	"github.com/bobgromozeka/yp-diploma1/internal/storage"
+)
+
+func GetOrder(d dependencies.D) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		order, orderErr := d.OrdersStorage.GetOrder(r.Context(), chi.URLParam(r, "number"))
+		if orderErr != nil {
+			if errors.Is(orderErr, storage.ErrOrderNotFound) {
+				http.Error(w, "Order not found", http.StatusNotFound)
+			} else {
+				d.Logger.Error(orderErr)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		if serveErr := helpers.ServeJSON(w, responses.AdminOrder{Order: order, UserID: order.UserID}); serveErr != nil {
+			d.Logger.Error(serveErr)
+			return
+		}
+	}
+}
+
+func RepollOrder(d dependencies.D) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		number := chi.URLParam(r, "number")
+
+		requeueErr := d.OrdersStorage.RequeueOrder(r.Context(), number)
+		if requeueErr != nil {
+			switch {
+			case errors.Is(requeueErr, storage.ErrOrderNotFound):
+				http.Error(w, "Order not found", http.StatusNotFound)
+			case errors.Is(requeueErr, storage.ErrOrderFinal):
+				http.Error(w, "Order is already processed", http.StatusConflict)
+			default:
+				d.Logger.Error(requeueErr)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		d.Logger.Infow("Order is requeued for accrual polling", "order_number", number)
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+// ClawbackOrder cancels accrual of invalidated order while it is still pending.
+func ClawbackOrder(d dependencies.D) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !httphelpers.CheckContentType(w, r, httphelpers.ContentJSON) {
+			return
+		}
+
+		var clawbackRequest requests.Clawback
+
+		decoder := json.NewDecoder(r.Body)
+		if decodeErr := decoder.Decode(&clawbackRequest); decodeErr != nil || clawbackRequest.Reason == "" {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		number := chi.URLParam(r, "number")
+
+		clawbackErr := d.OrdersStorage.ClawbackPendingAccrual(r.Context(), number, clawbackRequest.Reason)
+		if clawbackErr != nil {
+			switch {
+			case errors.Is(clawbackErr, storage.ErrOrderNotFound):
+				http.Error(w, "Order not found", http.StatusNotFound)
+			case errors.Is(clawbackErr, storage.ErrPendingNotFound):
+				http.Error(w, "Order has no pending accrual", http.StatusNotFound)
+			case errors.Is(clawbackErr, storage.ErrPendingReleased):
+				http.Error(w, "Accrual is already released or clawed back", http.StatusConflict)
+			default:
+				d.Logger.Error(clawbackErr)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		d.Logger.Infow("Pending accrual is clawed back", "order_number", number, "reason", clawbackRequest.Reason)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
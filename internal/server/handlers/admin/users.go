@@ -0,0 +1,143 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	"github.com/bobgromozeka/yp-diploma1/internal/models"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/helpers"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/responses"
+	"github.com/bobgromozeka/yp-diploma1/internal/storage"
+)
+
+const UsersSearchLimit = 50
+
+func FindUsers(d dependencies.D) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		users, usersErr := d.UsersStorage.FindUsers(r.Context(), r.URL.Query().Get("login"), UsersSearchLimit)
+		if usersErr != nil {
+			d.Logger.Error(usersErr)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		if len(users) < 1 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if serveErr := helpers.ServeJSON(w, users); serveErr != nil {
+			d.Logger.Error(serveErr)
+			return
+		}
+	}
+}
+
+func GetUser(d dependencies.D) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, userErr := getUser(w, r, d)
+		if userErr != nil {
+			return
+		}
+
+		balance, balanceErr := d.WithdrawalsStorage.GetUserBalance(r.Context(), user.ID)
+		if balanceErr != nil {
+			d.Logger.Error(balanceErr)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		userResponse := responses.AdminUser{
+			User: user,
+			Balance: responses.Balance{
+				Current:   balance.Current,
+				Held:      balance.Held,
+				Pending:   balance.Pending,
+				Withdrawn: balance.Withdrawn,
+			},
+		}
+
+		if serveErr := helpers.ServeJSON(w, userResponse); serveErr != nil {
+			d.Logger.Error(serveErr)
+			return
+		}
+	}
+}
+
+func GetUserOrders(d dependencies.D) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, userErr := getUser(w, r, d)
+		if userErr != nil {
+			return
+		}
+
+		orders, ordersErr := d.OrdersStorage.GetUserOrders(r.Context(), user.ID)
+		if ordersErr != nil {
+			d.Logger.Error(ordersErr)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		if len(orders) < 1 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if serveErr := helpers.ServeJSON(w, orders); serveErr != nil {
+			d.Logger.Error(serveErr)
+			return
+		}
+	}
+}
+
+func GetUserWithdrawals(d dependencies.D) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, userErr := getUser(w, r, d)
+		if userErr != nil {
+			return
+		}
+
+		withdrawals, withdrawalsErr := d.WithdrawalsStorage.GetUserWithdrawals(r.Context(), user.ID)
+		if withdrawalsErr != nil {
+			d.Logger.Error(withdrawalsErr)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		if len(withdrawals) < 1 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if serveErr := helpers.ServeJSON(w, responses.MakeAdminWithdrawals(withdrawals)); serveErr != nil {
+			d.Logger.Error(serveErr)
+			return
+		}
+	}
+}
+
+// getUser writes error response itself, so caller only has to stop handling on error.
+func getUser(w http.ResponseWriter, r *http.Request, d dependencies.D) (models.User, error) {
+	ID, parseErr := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if parseErr != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return models.User{}, storage.ErrUserNotFound
+	}
+
+	user, userErr := d.UsersStorage.GetUser(r.Context(), ID)
+	if userErr != nil {
+		if errors.Is(userErr, storage.ErrUserNotFound) {
+			http.Error(w, "User not found", http.StatusNotFound)
+		} else {
+			d.Logger.Error(userErr)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+		return user, userErr
+	}
+
+	return user, nil
+}
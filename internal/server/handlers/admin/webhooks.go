@@ -0,0 +1,97 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	httphelpers "github.com/bobgromozeka/yp-diploma1/internal/http"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/handlers/webhooks"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/helpers"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/requests"
+	"github.com/bobgromozeka/yp-diploma1/internal/storage"
+)
+
+// CreateWebhook registers endpoint receiving events of all users.
+func CreateWebhook(d dependencies.D) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !httphelpers.CheckContentType(w, r, httphelpers.ContentJSON) {
+			return
+		}
+
+		var createRequest requests.CreateWebhook
+
+		decoder := json.NewDecoder(r.Body)
+		if decodeErr := decoder.Decode(&createRequest); decodeErr != nil {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		endpoint, validationMessage := webhooks.MakeEndpoint(r.Context(), createRequest)
+		if validationMessage != "" {
+			http.Error(w, validationMessage, http.StatusBadRequest)
+			return
+		}
+
+		createdEndpoint, createErr := d.WebhooksStorage.CreateEndpoint(r.Context(), endpoint)
+		if createErr != nil {
+			d.Logger.Error(createErr)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		if encodeErr := json.NewEncoder(w).Encode(createdEndpoint); encodeErr != nil {
+			d.Logger.Error(encodeErr)
+		}
+	}
+}
+
+func GetWebhooks(d dependencies.D) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		endpoints, endpointsErr := d.WebhooksStorage.GetGlobalEndpoints(r.Context())
+		if endpointsErr != nil {
+			d.Logger.Error(endpointsErr)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		if len(endpoints) < 1 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if serveErr := helpers.ServeJSON(w, endpoints); serveErr != nil {
+			d.Logger.Error(serveErr)
+			return
+		}
+	}
+}
+
+func DeleteWebhook(d dependencies.D) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ID, parseErr := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if parseErr != nil {
+			http.Error(w, "Webhook not found", http.StatusNotFound)
+			return
+		}
+
+		deleteErr := d.WebhooksStorage.DeleteGlobalEndpoint(r.Context(), ID)
+		if deleteErr != nil {
+			if errors.Is(deleteErr, storage.ErrWebhookNotFound) {
+				http.Error(w, "Webhook not found", http.StatusNotFound)
+			} else {
+				d.Logger.Error(deleteErr)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -2,9 +2,11 @@ package balance
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
 	"github.com/bobgromozeka/yp-diploma1/internal/jwt"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/config"
 	"github.com/bobgromozeka/yp-diploma1/internal/server/helpers"
 	"github.com/bobgromozeka/yp-diploma1/internal/server/responses"
 )
@@ -20,14 +22,28 @@ func Get(d dependencies.D) http.HandlerFunc {
 
 		var BalanceResponse responses.Balance
 
-		balance, withdrawalsSum, balanceErr := d.WithdrawalsStorage.GetUserBalance(r.Context(), userID)
+		balance, balanceErr := d.WithdrawalsStorage.GetUserBalance(r.Context(), userID)
 		if balanceErr != nil {
 			d.Logger.Error(balanceErr)
 			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 			return
 		}
-		BalanceResponse.Current = balance
-		BalanceResponse.Withdrawn = withdrawalsSum
+		BalanceResponse.Current = balance.Current
+		BalanceResponse.Held = balance.Held
+		BalanceResponse.Pending = balance.Pending
+		BalanceResponse.Withdrawn = balance.Withdrawn
+
+		if config.Get().PointsTTL > 0 {
+			expiringPoints, expiringErr := d.PointsStorage.GetExpiringPoints(
+				r.Context(), userID, time.Now().Add(config.Get().ExpiringSoonWindow),
+			)
+			if expiringErr != nil {
+				d.Logger.Error(expiringErr)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			BalanceResponse.ExpiringSoon = expiringPoints
+		}
 
 		if serveErr := helpers.ServeJSON(w, BalanceResponse); serveErr != nil {
 			d.Logger.Error(serveErr)
@@ -0,0 +1,118 @@
+package balance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	httphelpers "github.com/bobgromozeka/yp-diploma1/internal/http"
+	"github.com/bobgromozeka/yp-diploma1/internal/jwt"
+	"github.com/bobgromozeka/yp-diploma1/internal/models"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/config"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/helpers"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/requests"
+	"github.com/bobgromozeka/yp-diploma1/internal/storage"
+)
+
+// CreateHold reserves points for order. Reserved points are spent by capture or become available again
+// by release or after hold TTL.
+func CreateHold(d dependencies.D) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !httphelpers.CheckContentType(w, r, httphelpers.ContentJSON) {
+			return
+		}
+
+		var holdRequest requests.CreateHold
+
+		decoder := json.NewDecoder(r.Body)
+		if decodeErr := decoder.Decode(&holdRequest); decodeErr != nil || holdRequest.Amount <= 0 {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		if !helpers.CheckOrderFormat(w, r, d, holdRequest.Order) {
+			return
+		}
+
+		userID, userIDErr := jwt.GetUserID(r.Context())
+		if userIDErr != nil {
+			d.Logger.Error(userIDErr)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		hold, holdErr := d.HoldsStorage.CreateHold(
+			r.Context(), userID, holdRequest.Order, holdRequest.Amount, time.Now().Add(config.Get().HoldTTL),
+		)
+		if holdErr != nil {
+			if errors.Is(holdErr, storage.ErrInsufficientFunds) {
+				http.Error(w, "Insufficient funds", http.StatusPaymentRequired)
+			} else {
+				d.Logger.Error(holdErr)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", httphelpers.ContentJSON)
+		w.WriteHeader(http.StatusCreated)
+		if encodeErr := json.NewEncoder(w).Encode(hold); encodeErr != nil {
+			d.Logger.Error(encodeErr)
+		}
+	}
+}
+
+func CaptureHold(d dependencies.D) http.HandlerFunc {
+	return closeHold(d, storage.HoldsStorage.CaptureHold)
+}
+
+func ReleaseHold(d dependencies.D) http.HandlerFunc {
+	return closeHold(d, storage.HoldsStorage.ReleaseHold)
+}
+
+func closeHold(
+	d dependencies.D,
+	closeFn func(s storage.HoldsStorage, ctx context.Context, userID int64, ID int64) (models.Hold, error),
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, userIDErr := jwt.GetUserID(r.Context())
+		if userIDErr != nil {
+			d.Logger.Error(userIDErr)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		ID, parseErr := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if parseErr != nil {
+			http.Error(w, "Hold not found", http.StatusNotFound)
+			return
+		}
+
+		hold, closeErr := closeFn(d.HoldsStorage, r.Context(), userID, ID)
+		if closeErr != nil {
+			switch {
+			case errors.Is(closeErr, storage.ErrHoldNotFound):
+				http.Error(w, "Hold not found", http.StatusNotFound)
+			case errors.Is(closeErr, storage.ErrHoldClosed):
+				http.Error(w, "Hold is already captured, released or expired", http.StatusConflict)
+			case errors.Is(closeErr, storage.ErrInsufficientFunds):
+				http.Error(w, "Insufficient funds", http.StatusPaymentRequired)
+			default:
+				d.Logger.Error(closeErr)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		if serveErr := helpers.ServeJSON(w, hold); serveErr != nil {
+			d.Logger.Error(serveErr)
+			return
+		}
+	}
+}
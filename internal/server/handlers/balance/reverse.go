@@ -0,0 +1,70 @@
+package balance
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	httphelpers "github.com/bobgromozeka/yp-diploma1/internal/http"
+	"github.com/bobgromozeka/yp-diploma1/internal/jwt"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/helpers"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/requests"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/responses"
+	"github.com/bobgromozeka/yp-diploma1/internal/storage"
+)
+
+// ReverseWithdrawal restores points of withdrawal for cancelled order. Repeated calls return the same reversed
+// withdrawal.
+func ReverseWithdrawal(d dependencies.D) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !httphelpers.CheckContentType(w, r, httphelpers.ContentJSON) {
+			return
+		}
+
+		var reverseRequest requests.ReverseWithdrawal
+
+		decoder := json.NewDecoder(r.Body)
+		if decodeErr := decoder.Decode(&reverseRequest); decodeErr != nil || reverseRequest.Reason == "" {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		ID, parseErr := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if parseErr != nil {
+			http.Error(w, "Withdrawal not found", http.StatusNotFound)
+			return
+		}
+
+		actorID, actorIDErr := jwt.GetUserID(r.Context())
+		if actorIDErr != nil {
+			d.Logger.Error(actorIDErr)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		withdrawal, reverseErr := d.WithdrawalsStorage.ReverseWithdrawal(
+			r.Context(), ID, reverseRequest.Reason, actorID,
+		)
+		if reverseErr != nil {
+			if errors.Is(reverseErr, storage.ErrWithdrawalNotFound) {
+				http.Error(w, "Withdrawal not found", http.StatusNotFound)
+			} else {
+				d.Logger.Error(reverseErr)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		d.Logger.Infow("Withdrawal is reversed", "withdrawal", withdrawal)
+
+		adminWithdrawal := responses.AdminWithdrawal{Withdrawal: withdrawal, ID: withdrawal.ID}
+		if serveErr := helpers.ServeJSON(w, adminWithdrawal); serveErr != nil {
+			d.Logger.Error(serveErr)
+			return
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package balance
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	httphelpers "github.com/bobgromozeka/yp-diploma1/internal/http"
+	"github.com/bobgromozeka/yp-diploma1/internal/jwt"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/config"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/requests"
+	"github.com/bobgromozeka/yp-diploma1/internal/storage"
+)
+
+// Transfer moves points from current user to another user by login.
+func Transfer(d dependencies.D) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !httphelpers.CheckContentType(w, r, httphelpers.ContentJSON) {
+			return
+		}
+
+		var transferRequest requests.Transfer
+
+		decoder := json.NewDecoder(r.Body)
+		if decodeErr := decoder.Decode(&transferRequest); decodeErr != nil || transferRequest.To == "" ||
+			transferRequest.Amount <= 0 {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		userID, userIDErr := jwt.GetUserID(r.Context())
+		if userIDErr != nil {
+			d.Logger.Error(userIDErr)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		transfer, transferErr := d.TransfersStorage.Transfer(
+			r.Context(), userID, transferRequest.To, transferRequest.Amount, config.Get().TransferDailyLimit,
+		)
+		if transferErr != nil {
+			switch {
+			case errors.Is(transferErr, storage.ErrRecipientNotFound):
+				http.Error(w, "Recipient not found", http.StatusNotFound)
+			case errors.Is(transferErr, storage.ErrSelfTransfer):
+				http.Error(w, "Points can not be transferred to yourself", http.StatusBadRequest)
+			case errors.Is(transferErr, storage.ErrInsufficientFunds):
+				http.Error(w, "Insufficient funds", http.StatusPaymentRequired)
+			case errors.Is(transferErr, storage.ErrTransferLimit):
+				http.Error(w, "Daily transfer limit exceeded", http.StatusTooManyRequests)
+			default:
+				d.Logger.Error(transferErr)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", httphelpers.ContentJSON)
+		w.WriteHeader(http.StatusCreated)
+		if encodeErr := json.NewEncoder(w).Encode(transfer); encodeErr != nil {
+			d.Logger.Error(encodeErr)
+		}
+	}
+}
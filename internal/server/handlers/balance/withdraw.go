@@ -6,9 +6,9 @@ import (
 	"net/http"
 
 	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
-	"github.com/bobgromozeka/yp-diploma1/internal/functions"
 	httphelpers "github.com/bobgromozeka/yp-diploma1/internal/http"
 	"github.com/bobgromozeka/yp-diploma1/internal/jwt"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/helpers"
 	"github.com/bobgromozeka/yp-diploma1/internal/server/requests"
 	"github.com/bobgromozeka/yp-diploma1/internal/storage"
 )
@@ -22,14 +22,12 @@ func Withdraw(d dependencies.D) http.HandlerFunc {
 		var withdrawRequest requests.Withdraw
 
 		decoder := json.NewDecoder(r.Body)
-		if decodeErr := decoder.Decode(&withdrawRequest); decodeErr != nil {
-			d.Logger.Error(decodeErr)
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		if decodeErr := decoder.Decode(&withdrawRequest); decodeErr != nil || withdrawRequest.Sum <= 0 {
+			http.Error(w, "Bad request", http.StatusBadRequest)
 			return
 		}
 
-		if !functions.CheckLuhn(withdrawRequest.Order) {
-			http.Error(w, "Wrong order format", http.StatusUnprocessableEntity)
+		if !helpers.CheckOrderFormat(w, r, d, withdrawRequest.Order) {
 			return
 		}
 
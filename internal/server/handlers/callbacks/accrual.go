@@ -0,0 +1,61 @@
+package callbacks
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/accrual"
+	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	httphelpers "github.com/bobgromozeka/yp-diploma1/internal/http"
+	"github.com/bobgromozeka/yp-diploma1/internal/models"
+	"github.com/bobgromozeka/yp-diploma1/internal/storage"
+)
+
+// AccrualResult applies order result pushed by accrual system. Repeated results are acknowledged without changes.
+// With push accrual provider result is only stored, it is applied by accrual poller.
+func AccrualResult(d dependencies.D) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !httphelpers.CheckContentType(w, r, httphelpers.ContentJSON) {
+			return
+		}
+
+		var result accrual.Result
+
+		decoder := json.NewDecoder(r.Body)
+		if decodeErr := decoder.Decode(&result); decodeErr != nil || result.Order == "" {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		switch result.Status {
+		case accrual.StatusRegistered, models.OrderStatusProcessing, models.OrderStatusInvalid,
+			models.OrderStatusProcessed:
+		default:
+			http.Error(w, "Unknown status", http.StatusBadRequest)
+			return
+		}
+
+		if d.PushAccrualResult != nil {
+			if pushErr := d.PushAccrualResult(r.Context(), result.Order, result.Status, result.Accrual); pushErr != nil {
+				d.Logger.Error(pushErr)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+
+		if applyErr := accrual.Apply(r.Context(), d, result); applyErr != nil {
+			if errors.Is(applyErr, storage.ErrOrderNotFound) {
+				http.Error(w, "Order not found", http.StatusNotFound)
+			} else {
+				d.Logger.Error(applyErr)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,19 @@
+package docs
+
+import (
+	"net/http"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	httphelpers "github.com/bobgromozeka/yp-diploma1/internal/http"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/apispec"
+)
+
+func OpenAPI(d dependencies.D) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", httphelpers.ContentJSON)
+		w.WriteHeader(http.StatusOK)
+		if _, writeErr := w.Write(apispec.Spec); writeErr != nil {
+			d.Logger.Error(writeErr)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package events
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	"github.com/bobgromozeka/yp-diploma1/internal/events"
+	"github.com/bobgromozeka/yp-diploma1/internal/jwt"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/helpers"
+)
+
+const (
+	LastEventIDHeader = "Last-Event-ID"
+	HeartbeatInterval = time.Second * 15
+)
+
+func Stream(d dependencies.D) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, userIDErr := jwt.GetUserID(r.Context())
+		if userIDErr != nil {
+			d.Logger.Error(userIDErr)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+			return
+		}
+
+		var lastEventID int64
+		if lastEventIDHeader := r.Header.Get(LastEventIDHeader); lastEventIDHeader != "" {
+			parsedID, parseErr := strconv.ParseInt(lastEventIDHeader, 10, 64)
+			if parseErr != nil || parsedID < 0 {
+				http.Error(w, "Wrong "+LastEventIDHeader, http.StatusBadRequest)
+				return
+			}
+			lastEventID = parsedID
+		}
+
+		//subscribe before reading missed events, so nothing is lost in between
+		liveEvents, unsubscribe := d.Events.Subscribe(
+			func(e events.Event) bool {
+				return e.UserID == userID
+			},
+		)
+		defer unsubscribe()
+
+		var missedEvents []events.Event
+		if lastEventID > 0 {
+			var missedErr error
+			missedEvents, missedErr = d.EventsStorage.GetUserEventsAfter(r.Context(), userID, lastEventID)
+			if missedErr != nil {
+				d.Logger.Error(missedErr)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		for _, e := range missedEvents {
+			if writeErr := writeEvent(w, e); writeErr != nil {
+				return
+			}
+			lastEventID = e.ID
+		}
+		flusher.Flush()
+
+		heartbeat := time.NewTicker(HeartbeatInterval)
+		defer heartbeat.Stop()
+
+		//clients reconnect to another instance with Last-Event-ID, so nothing is lost on shutdown
+		stopping := helpers.Stopping(r.Context())
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-stopping:
+				return
+			case <-heartbeat.C:
+				if _, writeErr := fmt.Fprint(w, ": heartbeat\n\n"); writeErr != nil {
+					return
+				}
+			case e, ok := <-liveEvents:
+				//stream fell behind and lost events, client reconnects with Last-Event-ID and reads them from storage
+				if !ok {
+					return
+				}
+				if e.ID <= lastEventID {
+					continue
+				}
+				if writeErr := writeEvent(w, e); writeErr != nil {
+					return
+				}
+				lastEventID = e.ID
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, e events.Event) error {
+	_, writeErr := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, e.Payload)
+	return writeErr
+}
@@ -0,0 +1,710 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	"github.com/bobgromozeka/yp-diploma1/internal/models"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/config"
+	"github.com/bobgromozeka/yp-diploma1/internal/storage"
+	mockstorage "github.com/bobgromozeka/yp-diploma1/internal/storage/mock"
+	"github.com/bobgromozeka/yp-diploma1/internal/testutils"
+)
+
+func TestAdminForbiddenForUsers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	for _, token := range []string{JWT, UserJWT} {
+		uStorage := mockstorage.NewMockUsersStorage(ctrl)
+		expectRole(uStorage, UserID, models.RoleUser)
+
+		req := httptest.NewRequest("GET", "/api/admin/users?login=a", nil)
+		req.Header.Add("Authorization", "Bearer "+token)
+		httpW := httptest.NewRecorder()
+		config.Set(
+			config.Config{
+				JWTSecret: JWTSecret,
+			},
+		)
+
+		d := dependencies.D{
+			UsersStorage: uStorage,
+			Logger:       zap.NewExample().Sugar(),
+		}
+
+		m := MakeMux(d)
+
+		m.ServeHTTP(httpW, req)
+
+		assert.Equal(t, http.StatusForbidden, httpW.Code)
+	}
+}
+
+func TestAdminForbiddenForRevokedAdmin(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	//token still has admin claim, but role was revoked in storage
+	uStorage := mockstorage.NewMockUsersStorage(ctrl)
+	expectRole(uStorage, AdminID, models.RoleUser)
+
+	req := httptest.NewRequest("GET", "/api/admin/users?login=a", nil)
+	req.Header.Add("Authorization", "Bearer "+AdminJWT)
+	httpW := httptest.NewRecorder()
+	config.Set(
+		config.Config{
+			JWTSecret: JWTSecret,
+		},
+	)
+
+	d := dependencies.D{
+		UsersStorage: uStorage,
+		Logger:       zap.NewExample().Sugar(),
+	}
+
+	m := MakeMux(d)
+
+	m.ServeHTTP(httpW, req)
+
+	assert.Equal(t, http.StatusForbidden, httpW.Code)
+}
+
+func TestAdminUnauthorized(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/admin/users?login=a", nil)
+	httpW := httptest.NewRecorder()
+	config.Set(
+		config.Config{
+			JWTSecret: JWTSecret,
+		},
+	)
+
+	d := dependencies.D{
+		Logger: zap.NewExample().Sugar(),
+	}
+
+	m := MakeMux(d)
+
+	m.ServeHTTP(httpW, req)
+
+	assert.Equal(t, http.StatusUnauthorized, httpW.Code)
+}
+
+func TestAdminGetUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	uStorage := mockstorage.NewMockUsersStorage(ctrl)
+	expectRole(uStorage, AdminID, models.RoleAdmin)
+	uStorage.
+		EXPECT().
+		GetUser(testutils.MatchContext(), gomock.Eq(int64(UserID))).
+		Return(models.User{ID: UserID, Login: "login", Role: models.RoleUser}, nil)
+	wStorage := mockstorage.NewMockWithdrawalsStorage(ctrl)
+	wStorage.
+		EXPECT().
+		GetUserBalance(testutils.MatchContext(), gomock.Eq(int64(UserID))).
+		Return(models.Balance{Current: 100, Held: 5, Pending: 10, Withdrawn: 50}, nil)
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/api/admin/users/%d", UserID), nil)
+	req.Header.Add("Authorization", "Bearer "+AdminJWT)
+	httpW := httptest.NewRecorder()
+	config.Set(
+		config.Config{
+			JWTSecret: JWTSecret,
+		},
+	)
+
+	d := dependencies.D{
+		UsersStorage:       uStorage,
+		WithdrawalsStorage: wStorage,
+		Logger:             zap.NewExample().Sugar(),
+	}
+
+	m := MakeMux(d)
+
+	m.ServeHTTP(httpW, req)
+
+	respBody, _ := io.ReadAll(httpW.Body)
+	assert.Equal(t, http.StatusOK, httpW.Code)
+	assert.JSONEq(
+		t,
+		`{"id":1,"login":"login","role":"user","balance":{"current":100,"held":5,"pending":10,"withdrawn":50}}`,
+		string(respBody),
+	)
+}
+
+func TestAdminGetUserNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	uStorage := mockstorage.NewMockUsersStorage(ctrl)
+	expectRole(uStorage, AdminID, models.RoleAdmin)
+	uStorage.
+		EXPECT().
+		GetUser(testutils.MatchContext(), gomock.Eq(int64(100))).
+		Return(models.User{}, storage.ErrUserNotFound)
+
+	req := httptest.NewRequest("GET", "/api/admin/users/100/orders", nil)
+	req.Header.Add("Authorization", "Bearer "+AdminJWT)
+	httpW := httptest.NewRecorder()
+	config.Set(
+		config.Config{
+			JWTSecret: JWTSecret,
+		},
+	)
+
+	d := dependencies.D{
+		UsersStorage: uStorage,
+		Logger:       zap.NewExample().Sugar(),
+	}
+
+	m := MakeMux(d)
+
+	m.ServeHTTP(httpW, req)
+
+	assert.Equal(t, http.StatusNotFound, httpW.Code)
+}
+
+func TestAdminGetOrder(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	uStorage := mockstorage.NewMockUsersStorage(ctrl)
+	expectRole(uStorage, AdminID, models.RoleAdmin)
+
+	orderUploadTime := "2023-08-31T19:35:43Z"
+	orderUploadTimeParsed, _ := time.Parse(time.RFC3339, orderUploadTime)
+	oStorage := mockstorage.NewMockOrdersStorage(ctrl)
+	oStorage.
+		EXPECT().
+		GetOrder(testutils.MatchContext(), gomock.Eq(OrderNumber)).
+		Return(
+			models.Order{
+				ID:         1,
+				UserID:     UserID,
+				Number:     OrderNumber,
+				Status:     models.OrderStatusProcessing,
+				UploadedAt: orderUploadTimeParsed,
+			}, nil,
+		)
+
+	req := httptest.NewRequest("GET", "/api/admin/orders/"+OrderNumber, nil)
+	req.Header.Add("Authorization", "Bearer "+AdminJWT)
+	httpW := httptest.NewRecorder()
+	config.Set(
+		config.Config{
+			JWTSecret: JWTSecret,
+		},
+	)
+
+	d := dependencies.D{
+		UsersStorage:  uStorage,
+		OrdersStorage: oStorage,
+		Logger:        zap.NewExample().Sugar(),
+	}
+
+	m := MakeMux(d)
+
+	m.ServeHTTP(httpW, req)
+
+	respBody, _ := io.ReadAll(httpW.Body)
+	assert.Equal(t, http.StatusOK, httpW.Code)
+	assert.JSONEq(
+		t,
+		fmt.Sprintf(
+			`{"number":"%s","status":"PROCESSING","uploaded_at":"%s","user_id":1}`, OrderNumber, orderUploadTime,
+		),
+		string(respBody),
+	)
+}
+
+func TestAdminRepollOrder(t *testing.T) {
+	type testCase struct {
+		Name   string
+		Err    error
+		Status int
+	}
+
+	testCases := []testCase{
+		{Name: "Requeued", Err: nil, Status: http.StatusAccepted},
+		{Name: "Not found", Err: storage.ErrOrderNotFound, Status: http.StatusNotFound},
+		{Name: "Already processed", Err: storage.ErrOrderFinal, Status: http.StatusConflict},
+	}
+
+	for _, tc := range testCases {
+		t.Run(
+			tc.Name, func(t *testing.T) {
+				ctrl := gomock.NewController(t)
+				defer ctrl.Finish()
+
+				uStorage := mockstorage.NewMockUsersStorage(ctrl)
+				expectRole(uStorage, AdminID, models.RoleAdmin)
+
+				oStorage := mockstorage.NewMockOrdersStorage(ctrl)
+				oStorage.
+					EXPECT().
+					RequeueOrder(testutils.MatchContext(), gomock.Eq(OrderNumber)).
+					Return(tc.Err)
+
+				req := httptest.NewRequest("POST", "/api/admin/orders/"+OrderNumber+"/repoll", nil)
+				req.Header.Add("Authorization", "Bearer "+AdminJWT)
+				httpW := httptest.NewRecorder()
+				config.Set(
+					config.Config{
+						JWTSecret: JWTSecret,
+					},
+				)
+
+				d := dependencies.D{
+					UsersStorage:  uStorage,
+					OrdersStorage: oStorage,
+					Logger:        zap.NewExample().Sugar(),
+				}
+
+				m := MakeMux(d)
+
+				m.ServeHTTP(httpW, req)
+
+				assert.Equal(t, tc.Status, httpW.Code)
+			},
+		)
+	}
+}
+
+func TestAdminCreateAdjustment(t *testing.T) {
+	type testCase struct {
+		Name            string
+		Body            string
+		Threshold       float64
+		RequireApproval bool
+		Status          int
+	}
+
+	testCases := []testCase{
+		{
+			Name:   "Applied",
+			Body:   `{"amount":-50,"reason":"fraud","comment":"duplicate accrual"}`,
+			Status: http.StatusCreated,
+		},
+		{
+			Name:            "Pending approval",
+			Body:            `{"amount":500,"reason":"goodwill"}`,
+			Threshold:       100,
+			RequireApproval: true,
+			Status:          http.StatusAccepted,
+		},
+		{
+			Name:      "Below threshold",
+			Body:      `{"amount":100,"reason":"goodwill"}`,
+			Threshold: 100,
+			Status:    http.StatusCreated,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(
+			tc.Name, func(t *testing.T) {
+				ctrl := gomock.NewController(t)
+				defer ctrl.Finish()
+
+				uStorage := mockstorage.NewMockUsersStorage(ctrl)
+				expectRole(uStorage, AdminID, models.RoleAdmin)
+
+				aStorage := mockstorage.NewMockAdjustmentsStorage(ctrl)
+				aStorage.
+					EXPECT().
+					CreateAdjustment(testutils.MatchContext(), gomock.Any(), gomock.Eq(tc.RequireApproval)).
+					DoAndReturn(
+						func(
+							_ context.Context, a models.BalanceAdjustment, requireApproval bool,
+						) (models.BalanceAdjustment, error) {
+							assert.Equal(t, int64(UserID), a.UserID)
+							assert.Equal(t, int64(AdminID), a.CreatedBy)
+							a.ID = 1
+							a.Status = models.AdjustmentStatusApplied
+							if requireApproval {
+								a.Status = models.AdjustmentStatusPending
+							}
+							return a, nil
+						},
+					)
+
+				req := httptest.NewRequest(
+					"POST", fmt.Sprintf("/api/admin/users/%d/adjustments", UserID), strings.NewReader(tc.Body),
+				)
+				req.Header.Add("Authorization", "Bearer "+AdminJWT)
+				req.Header.Add("Content-Type", "application/json")
+				httpW := httptest.NewRecorder()
+				config.Set(
+					config.Config{
+						JWTSecret:                   JWTSecret,
+						AdjustmentApprovalThreshold: tc.Threshold,
+					},
+				)
+
+				d := dependencies.D{
+					UsersStorage:       uStorage,
+					AdjustmentsStorage: aStorage,
+					Logger:             zap.NewExample().Sugar(),
+				}
+
+				m := MakeMux(d)
+
+				m.ServeHTTP(httpW, req)
+
+				assert.Equal(t, tc.Status, httpW.Code)
+			},
+		)
+	}
+}
+
+func TestAdminCreateAdjustmentBadRequest(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	for _, body := range []string{
+		`{"amount":0,"reason":"goodwill"}`,
+		`{"amount":10,"reason":"unknown"}`,
+		`{"amount":10}`,
+		`not json`,
+	} {
+		uStorage := mockstorage.NewMockUsersStorage(ctrl)
+		expectRole(uStorage, AdminID, models.RoleAdmin)
+
+		req := httptest.NewRequest(
+			"POST", fmt.Sprintf("/api/admin/users/%d/adjustments", UserID), strings.NewReader(body),
+		)
+		req.Header.Add("Authorization", "Bearer "+AdminJWT)
+		req.Header.Add("Content-Type", "application/json")
+		httpW := httptest.NewRecorder()
+		config.Set(
+			config.Config{
+				JWTSecret: JWTSecret,
+			},
+		)
+
+		d := dependencies.D{
+			UsersStorage: uStorage,
+			Logger:       zap.NewExample().Sugar(),
+		}
+
+		m := MakeMux(d)
+
+		m.ServeHTTP(httpW, req)
+
+		assert.Equal(t, http.StatusBadRequest, httpW.Code, body)
+	}
+}
+
+func TestAdminApproveAdjustment(t *testing.T) {
+	type testCase struct {
+		Name   string
+		Err    error
+		Status int
+	}
+
+	testCases := []testCase{
+		{Name: "Approved", Err: nil, Status: http.StatusOK},
+		{Name: "Not found", Err: storage.ErrAdjustmentNotFound, Status: http.StatusNotFound},
+		{Name: "Already decided", Err: storage.ErrAdjustmentDecided, Status: http.StatusConflict},
+		{Name: "Self approval", Err: storage.ErrSelfApproval, Status: http.StatusForbidden},
+		{Name: "Insufficient funds", Err: storage.ErrInsufficientFunds, Status: http.StatusPaymentRequired},
+	}
+
+	for _, tc := range testCases {
+		t.Run(
+			tc.Name, func(t *testing.T) {
+				ctrl := gomock.NewController(t)
+				defer ctrl.Finish()
+
+				uStorage := mockstorage.NewMockUsersStorage(ctrl)
+				expectRole(uStorage, AdminID, models.RoleAdmin)
+
+				aStorage := mockstorage.NewMockAdjustmentsStorage(ctrl)
+				aStorage.
+					EXPECT().
+					ApproveAdjustment(testutils.MatchContext(), gomock.Eq(int64(7)), gomock.Eq(int64(AdminID))).
+					Return(models.BalanceAdjustment{ID: 7, Status: models.AdjustmentStatusApplied}, tc.Err)
+
+				req := httptest.NewRequest("POST", "/api/admin/adjustments/7/approve", nil)
+				req.Header.Add("Authorization", "Bearer "+AdminJWT)
+				httpW := httptest.NewRecorder()
+				config.Set(
+					config.Config{
+						JWTSecret: JWTSecret,
+					},
+				)
+
+				d := dependencies.D{
+					UsersStorage:       uStorage,
+					AdjustmentsStorage: aStorage,
+					Logger:             zap.NewExample().Sugar(),
+				}
+
+				m := MakeMux(d)
+
+				m.ServeHTTP(httpW, req)
+
+				assert.Equal(t, tc.Status, httpW.Code)
+			},
+		)
+	}
+}
+
+func TestAdminFindAuditEvents(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	uStorage := mockstorage.NewMockUsersStorage(ctrl)
+	expectRole(uStorage, AdminID, models.RoleAdmin)
+
+	from := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+	userID := int64(UserID)
+	aStorage := mockstorage.NewMockAuditStorage(ctrl)
+	aStorage.
+		EXPECT().
+		FindAuditEvents(
+			testutils.MatchContext(), gomock.Eq(
+				models.AuditFilter{
+					UserID: &userID,
+					Action: models.AuditBalanceWithdrawn,
+					From:   &from,
+					Limit:  10,
+				},
+			),
+		).
+		Return(
+			[]models.AuditEvent{
+				{ID: 1, Action: models.AuditBalanceWithdrawn, ActorID: &userID, UserID: &userID, Order: OrderNumber},
+			}, nil,
+		)
+
+	req := httptest.NewRequest(
+		"GET", "/api/admin/audit?user_id=1&action=balance.withdrawn&from=2023-05-01T00:00:00Z&limit=10", nil,
+	)
+	req.Header.Add("Authorization", "Bearer "+AdminJWT)
+	httpW := httptest.NewRecorder()
+	config.Set(
+		config.Config{
+			JWTSecret: JWTSecret,
+		},
+	)
+
+	d := dependencies.D{
+		UsersStorage: uStorage,
+		AuditStorage: aStorage,
+		Logger:       zap.NewExample().Sugar(),
+	}
+
+	m := MakeMux(d)
+
+	m.ServeHTTP(httpW, req)
+
+	assert.Equal(t, http.StatusOK, httpW.Code)
+}
+
+func TestAdminFindAuditEventsBadRequest(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	for _, query := range []string{"user_id=abc", "limit=0", "limit=5000", "from=yesterday", "to=2023-05-01"} {
+		uStorage := mockstorage.NewMockUsersStorage(ctrl)
+		expectRole(uStorage, AdminID, models.RoleAdmin)
+
+		req := httptest.NewRequest("GET", "/api/admin/audit?"+query, nil)
+		req.Header.Add("Authorization", "Bearer "+AdminJWT)
+		httpW := httptest.NewRecorder()
+		config.Set(
+			config.Config{
+				JWTSecret: JWTSecret,
+			},
+		)
+
+		d := dependencies.D{
+			UsersStorage: uStorage,
+			Logger:       zap.NewExample().Sugar(),
+		}
+
+		m := MakeMux(d)
+
+		m.ServeHTTP(httpW, req)
+
+		assert.Equal(t, http.StatusBadRequest, httpW.Code, query)
+	}
+}
+
+func TestAdminClawbackOrder(t *testing.T) {
+	type testCase struct {
+		Name   string
+		Err    error
+		Status int
+	}
+
+	testCases := []testCase{
+		{Name: "Clawed back", Err: nil, Status: http.StatusNoContent},
+		{Name: "Order not found", Err: storage.ErrOrderNotFound, Status: http.StatusNotFound},
+		{Name: "No pending accrual", Err: storage.ErrPendingNotFound, Status: http.StatusNotFound},
+		{Name: "Already released", Err: storage.ErrPendingReleased, Status: http.StatusConflict},
+	}
+
+	for _, tc := range testCases {
+		t.Run(
+			tc.Name, func(t *testing.T) {
+				ctrl := gomock.NewController(t)
+				defer ctrl.Finish()
+
+				uStorage := mockstorage.NewMockUsersStorage(ctrl)
+				expectRole(uStorage, AdminID, models.RoleAdmin)
+
+				oStorage := mockstorage.NewMockOrdersStorage(ctrl)
+				oStorage.
+					EXPECT().
+					ClawbackPendingAccrual(testutils.MatchContext(), gomock.Eq(OrderNumber), gomock.Eq("returned")).
+					Return(tc.Err)
+
+				req := httptest.NewRequest(
+					"POST", "/api/admin/orders/"+OrderNumber+"/clawback", strings.NewReader(`{"reason":"returned"}`),
+				)
+				req.Header.Add("Authorization", "Bearer "+AdminJWT)
+				req.Header.Add("Content-Type", "application/json")
+				httpW := httptest.NewRecorder()
+				config.Set(
+					config.Config{
+						JWTSecret: JWTSecret,
+					},
+				)
+
+				d := dependencies.D{
+					UsersStorage:  uStorage,
+					OrdersStorage: oStorage,
+					Logger:        zap.NewExample().Sugar(),
+				}
+
+				m := MakeMux(d)
+
+				m.ServeHTTP(httpW, req)
+
+				assert.Equal(t, tc.Status, httpW.Code)
+			},
+		)
+	}
+}
+
+func TestAdminFindAuditEventsBadRequestForbiddenForUsers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	uStorage := mockstorage.NewMockUsersStorage(ctrl)
+	expectRole(uStorage, UserID, models.RoleUser)
+
+	req := httptest.NewRequest("GET", "/api/admin/audit?limit=0", nil)
+	req.Header.Add("Authorization", "Bearer "+JWT)
+	httpW := httptest.NewRecorder()
+	config.Set(
+		config.Config{
+			JWTSecret: JWTSecret,
+		},
+	)
+
+	d := dependencies.D{
+		UsersStorage: uStorage,
+		Logger:       zap.NewExample().Sugar(),
+	}
+
+	m := MakeMux(d)
+
+	m.ServeHTTP(httpW, req)
+
+	//query is not validated before role is checked
+	assert.Equal(t, http.StatusForbidden, httpW.Code)
+}
+
+func TestAdminClawbackOrderWithoutReason(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	uStorage := mockstorage.NewMockUsersStorage(ctrl)
+	expectRole(uStorage, AdminID, models.RoleAdmin)
+
+	req := httptest.NewRequest("POST", "/api/admin/orders/"+OrderNumber+"/clawback", strings.NewReader(`{}`))
+	req.Header.Add("Authorization", "Bearer "+AdminJWT)
+	req.Header.Add("Content-Type", "application/json")
+	httpW := httptest.NewRecorder()
+	config.Set(
+		config.Config{
+			JWTSecret: JWTSecret,
+		},
+	)
+
+	d := dependencies.D{
+		UsersStorage: uStorage,
+		Logger:       zap.NewExample().Sugar(),
+	}
+
+	m := MakeMux(d)
+
+	m.ServeHTTP(httpW, req)
+
+	assert.Equal(t, http.StatusBadRequest, httpW.Code)
+}
+
+func TestAdminMetrics(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	uStorage := mockstorage.NewMockUsersStorage(ctrl)
+	expectRole(uStorage, AdminID, models.RoleAdmin)
+
+	req := httptest.NewRequest("GET", "/api/admin/metrics", nil)
+	req.Header.Add("Authorization", "Bearer "+AdminJWT)
+	httpW := httptest.NewRecorder()
+	config.Set(
+		config.Config{
+			JWTSecret: JWTSecret,
+		},
+	)
+
+	d := dependencies.D{
+		UsersStorage: uStorage,
+		Logger:       zap.NewExample().Sugar(),
+	}
+
+	m := MakeMux(d)
+
+	m.ServeHTTP(httpW, req)
+
+	respBody, _ := io.ReadAll(httpW.Body)
+	assert.Equal(t, http.StatusOK, httpW.Code)
+	//command line contains secrets passed as flags
+	assert.NotContains(t, string(respBody), "cmdline")
+}
+
+func TestMetricsAreNotPublic(t *testing.T) {
+	for _, path := range []string{"/debug/vars", "/api/admin/metrics"} {
+		req := httptest.NewRequest("GET", path, nil)
+		httpW := httptest.NewRecorder()
+		config.Set(
+			config.Config{
+				JWTSecret: JWTSecret,
+			},
+		)
+
+		m := MakeMux(dependencies.D{Logger: zap.NewExample().Sugar()})
+
+		m.ServeHTTP(httpW, req)
+
+		assert.NotEqual(t, http.StatusOK, httpW.Code, path)
+	}
+}
@@ -1,20 +1,27 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
 
 	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	"github.com/bobgromozeka/yp-diploma1/internal/models"
 	"github.com/bobgromozeka/yp-diploma1/internal/server/config"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/handlers/balance"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/responses"
 	"github.com/bobgromozeka/yp-diploma1/internal/storage"
 	mockstorage "github.com/bobgromozeka/yp-diploma1/internal/storage/mock"
 	"github.com/bobgromozeka/yp-diploma1/internal/testutils"
@@ -49,7 +56,99 @@ func TestBalanceWithdrawWrongOrderNumber(t *testing.T) {
 
 	respBody, _ := io.ReadAll(httpW.Body)
 	assert.Equal(t, http.StatusUnprocessableEntity, httpW.Code)
-	assert.Equal(t, "Wrong order format\n", string(respBody))
+	assert.Equal(t, "Wrong order format: order number should pass Luhn checksum\n", string(respBody))
+}
+
+func TestBalanceWithdrawBadRequest(t *testing.T) {
+	type testCase struct {
+		Name         string
+		Body         string
+		ResponseBody string
+	}
+
+	testCases := []testCase{
+		{
+			Name:         "Malformed JSON",
+			Body:         `{"order":`,
+			ResponseBody: "Wrong request body format\n",
+		},
+		{
+			Name:         "No sum",
+			Body:         fmt.Sprintf(`{"order":"%s"}`, OrderNumber),
+			ResponseBody: "Wrong request body field sum: property \"sum\" is missing\n",
+		},
+		{
+			Name:         "Sum is string",
+			Body:         fmt.Sprintf(`{"order":"%s","sum":"111"}`, OrderNumber),
+			ResponseBody: "Wrong request body field sum: value must be a number\n",
+		},
+		{
+			Name:         "Negative sum",
+			Body:         fmt.Sprintf(`{"order":"%s","sum":-1}`, OrderNumber),
+			ResponseBody: "Wrong request body field sum: number must be more than 0\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(
+			tc.Name, func(t *testing.T) {
+				req := httptest.NewRequest("POST", "/api/user/balance/withdraw", strings.NewReader(tc.Body))
+				req.Header.Add("Content-Type", "application/json")
+				req.Header.Add("Authorization", "Bearer "+JWT)
+				httpW := httptest.NewRecorder()
+				config.Set(
+					config.Config{
+						JWTSecret: JWTSecret,
+					},
+				)
+
+				d := dependencies.D{
+					Logger: zap.NewExample().Sugar(),
+				}
+
+				m := MakeMux(d)
+
+				m.ServeHTTP(httpW, req)
+
+				respBody, _ := io.ReadAll(httpW.Body)
+				assert.Equal(t, http.StatusBadRequest, httpW.Code)
+				assert.Equal(t, tc.ResponseBody, string(respBody))
+
+				//handler rejects malformed body on its own as well
+				req = httptest.NewRequest("POST", "/api/user/balance/withdraw", strings.NewReader(tc.Body))
+				req.Header.Add("Content-Type", "application/json")
+				httpW = httptest.NewRecorder()
+
+				balance.Withdraw(d).ServeHTTP(httpW, req)
+
+				assert.Equal(t, http.StatusBadRequest, httpW.Code)
+			},
+		)
+	}
+}
+
+func TestBalanceWithdrawBadRequestUnauthorized(t *testing.T) {
+	req := httptest.NewRequest(
+		"POST", "/api/user/balance/withdraw", strings.NewReader(fmt.Sprintf(`{"order":"%s","sum":-1}`, OrderNumber)),
+	)
+	req.Header.Add("Content-Type", "application/json")
+	httpW := httptest.NewRecorder()
+	config.Set(
+		config.Config{
+			JWTSecret: JWTSecret,
+		},
+	)
+
+	d := dependencies.D{
+		Logger: zap.NewExample().Sugar(),
+	}
+
+	m := MakeMux(d)
+
+	m.ServeHTTP(httpW, req)
+
+	//request body is not validated before authentication
+	assert.Equal(t, http.StatusUnauthorized, httpW.Code)
 }
 
 func TestBalanceWithdrawInsufficientFunds(t *testing.T) {
@@ -172,7 +271,7 @@ func TestBalanceGetInternalServerError(t *testing.T) {
 	wStorage.
 		EXPECT().
 		GetUserBalance(testutils.MatchContext(), gomock.Eq(int64(UserID))).
-		Return(float64(0), float64(0), errors.New("internal server error"))
+		Return(models.Balance{}, errors.New("internal server error"))
 
 	req := httptest.NewRequest("GET", "/api/user/balance", nil)
 	req.Header.Add("Content-Type", "text/plain")
@@ -206,12 +305,14 @@ func TestBalanceGetSuccess(t *testing.T) {
 	defer ctrl.Finish()
 
 	balance := float64(1000)
+	held := float64(30)
+	pending := float64(20)
 	withdrawalsSum := float64(5000)
 	wStorage := mockstorage.NewMockWithdrawalsStorage(ctrl)
 	wStorage.
 		EXPECT().
 		GetUserBalance(testutils.MatchContext(), gomock.Eq(int64(UserID))).
-		Return(balance, withdrawalsSum, nil)
+		Return(models.Balance{Current: balance, Held: held, Pending: pending, Withdrawn: withdrawalsSum}, nil)
 
 	req := httptest.NewRequest("GET", "/api/user/balance", nil)
 	req.Header.Add("Content-Type", "text/plain")
@@ -237,5 +338,450 @@ func TestBalanceGetSuccess(t *testing.T) {
 
 	respBody, _ := io.ReadAll(httpW.Body)
 	assert.Equal(t, http.StatusOK, httpW.Code)
-	assert.Equal(t, fmt.Sprintf(`{"current":%.0f,"withdrawn":%.0f}`, balance, withdrawalsSum)+"\n", string(respBody))
+	assert.Equal(
+		t,
+		fmt.Sprintf(
+			`{"current":%.0f,"held":%.0f,"pending":%.0f,"withdrawn":%.0f}`, balance, held, pending, withdrawalsSum,
+		)+"\n",
+		string(respBody),
+	)
+}
+
+func TestBalanceGetExpiringSoon(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	wStorage := mockstorage.NewMockWithdrawalsStorage(ctrl)
+	wStorage.
+		EXPECT().
+		GetUserBalance(testutils.MatchContext(), gomock.Eq(int64(UserID))).
+		Return(models.Balance{Current: 100}, nil)
+	expiresAt := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	pStorage := mockstorage.NewMockPointsStorage(ctrl)
+	pStorage.
+		EXPECT().
+		GetExpiringPoints(testutils.MatchContext(), gomock.Eq(int64(UserID)), gomock.Any()).
+		DoAndReturn(
+			func(_ context.Context, _ int64, until time.Time) ([]models.ExpiringPoints, error) {
+				assert.WithinDuration(t, time.Now().Add(time.Hour*24*7), until, time.Minute)
+				return []models.ExpiringPoints{{Amount: 40, ExpiresAt: expiresAt}}, nil
+			},
+		)
+
+	req := httptest.NewRequest("GET", "/api/user/balance", nil)
+	req.Header.Add("Authorization", "Bearer "+JWT)
+	httpW := httptest.NewRecorder()
+	config.Set(
+		config.Config{
+			JWTSecret:          JWTSecret,
+			PointsTTL:          time.Hour * 24 * 365,
+			ExpiringSoonWindow: time.Hour * 24 * 7,
+		},
+	)
+
+	d := dependencies.D{
+		WithdrawalsStorage: wStorage,
+		PointsStorage:      pStorage,
+		Logger:             zap.NewExample().Sugar(),
+	}
+
+	m := MakeMux(d)
+
+	m.ServeHTTP(httpW, req)
+
+	respBody, _ := io.ReadAll(httpW.Body)
+	assert.Equal(t, http.StatusOK, httpW.Code)
+	assert.JSONEq(
+		t,
+		`{"current":100,"held":0,"pending":0,"withdrawn":0,`+
+			`"expiring_soon":[{"amount":40,"expires_at":"2023-06-01T00:00:00Z"}]}`,
+		string(respBody),
+	)
+}
+
+func TestBalanceReverseWithdrawal(t *testing.T) {
+	type testCase struct {
+		Name   string
+		Err    error
+		Status int
+	}
+
+	testCases := []testCase{
+		{Name: "Reversed", Err: nil, Status: http.StatusOK},
+		{Name: "Not found", Err: storage.ErrWithdrawalNotFound, Status: http.StatusNotFound},
+		{Name: "Internal error", Err: errors.New("internal server error"), Status: http.StatusInternalServerError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(
+			tc.Name, func(t *testing.T) {
+				ctrl := gomock.NewController(t)
+				defer ctrl.Finish()
+
+				uStorage := mockstorage.NewMockUsersStorage(ctrl)
+				expectRole(uStorage, AdminID, models.RoleAdmin)
+
+				reversedAt := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+				wStorage := mockstorage.NewMockWithdrawalsStorage(ctrl)
+				wStorage.
+					EXPECT().
+					ReverseWithdrawal(
+						testutils.MatchContext(), gomock.Eq(int64(WithdrawalID)), gomock.Eq("order cancelled"),
+						gomock.Eq(int64(AdminID)),
+					).
+					Return(
+						models.Withdrawal{
+							ID: WithdrawalID, OrderNumber: OrderNumber, Sum: 100, ReversedAt: &reversedAt,
+							ReversalReason: "order cancelled",
+						}, tc.Err,
+					)
+
+				req := httptest.NewRequest(
+					"POST", "/api/user/balance/withdrawals/"+strconv.Itoa(WithdrawalID)+"/reverse",
+					strings.NewReader(`{"reason":"order cancelled"}`),
+				)
+				req.Header.Add("Content-Type", "application/json")
+				req.Header.Add("Authorization", "Bearer "+AdminJWT)
+				httpW := httptest.NewRecorder()
+				config.Set(
+					config.Config{
+						JWTSecret: JWTSecret,
+					},
+				)
+
+				d := dependencies.D{
+					UsersStorage:       uStorage,
+					WithdrawalsStorage: wStorage,
+					Logger:             zap.NewExample().Sugar(),
+				}
+
+				m := MakeMux(d)
+
+				m.ServeHTTP(httpW, req)
+
+				assert.Equal(t, tc.Status, httpW.Code)
+				if tc.Status == http.StatusOK {
+					var reversed responses.AdminWithdrawal
+					assert.NoError(t, json.NewDecoder(httpW.Body).Decode(&reversed))
+					assert.Equal(t, int64(WithdrawalID), reversed.ID)
+				}
+			},
+		)
+	}
+}
+
+func TestBalanceReverseWithdrawalForbiddenForUsers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	uStorage := mockstorage.NewMockUsersStorage(ctrl)
+	expectRole(uStorage, UserID, models.RoleUser)
+
+	req := httptest.NewRequest(
+		"POST", "/api/user/balance/withdrawals/"+strconv.Itoa(WithdrawalID)+"/reverse",
+		strings.NewReader(`{"reason":"order cancelled"}`),
+	)
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Authorization", "Bearer "+UserJWT)
+	httpW := httptest.NewRecorder()
+	config.Set(
+		config.Config{
+			JWTSecret: JWTSecret,
+		},
+	)
+
+	d := dependencies.D{
+		UsersStorage: uStorage,
+		Logger:       zap.NewExample().Sugar(),
+	}
+
+	m := MakeMux(d)
+
+	m.ServeHTTP(httpW, req)
+
+	assert.Equal(t, http.StatusForbidden, httpW.Code)
+}
+
+func TestBalanceReverseWithdrawalWithoutReason(t *testing.T) {
+	req := httptest.NewRequest(
+		"POST", "/api/user/balance/withdrawals/"+strconv.Itoa(WithdrawalID)+"/reverse", strings.NewReader(`{}`),
+	)
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Authorization", "Bearer "+AdminJWT)
+	httpW := httptest.NewRecorder()
+	config.Set(
+		config.Config{
+			JWTSecret: JWTSecret,
+		},
+	)
+
+	d := dependencies.D{
+		Logger: zap.NewExample().Sugar(),
+	}
+
+	m := MakeMux(d)
+
+	m.ServeHTTP(httpW, req)
+
+	assert.Equal(t, http.StatusBadRequest, httpW.Code)
+}
+
+func TestBalanceCreateHold(t *testing.T) {
+	type testCase struct {
+		Name   string
+		Err    error
+		Status int
+	}
+
+	testCases := []testCase{
+		{Name: "Held", Err: nil, Status: http.StatusCreated},
+		{Name: "Insufficient funds", Err: storage.ErrInsufficientFunds, Status: http.StatusPaymentRequired},
+	}
+
+	for _, tc := range testCases {
+		t.Run(
+			tc.Name, func(t *testing.T) {
+				ctrl := gomock.NewController(t)
+				defer ctrl.Finish()
+
+				hStorage := mockstorage.NewMockHoldsStorage(ctrl)
+				hStorage.
+					EXPECT().
+					CreateHold(
+						testutils.MatchContext(), gomock.Eq(int64(UserID)), gomock.Eq(OrderNumber), gomock.Eq(float64(50)),
+						gomock.Any(),
+					).
+					DoAndReturn(
+						func(_ context.Context, userID int64, order string, amount float64, expiresAt time.Time) (
+							models.Hold, error,
+						) {
+							assert.WithinDuration(t, time.Now().Add(time.Minute*10), expiresAt, time.Minute)
+							return models.Hold{ID: 1, UserID: userID, OrderNumber: order, Amount: amount}, tc.Err
+						},
+					)
+
+				req := httptest.NewRequest(
+					"POST", "/api/user/balance/holds", strings.NewReader(fmt.Sprintf(`{"order":"%s","amount":50}`, OrderNumber)),
+				)
+				req.Header.Add("Content-Type", "application/json")
+				req.Header.Add("Authorization", "Bearer "+JWT)
+				httpW := httptest.NewRecorder()
+				config.Set(
+					config.Config{
+						JWTSecret: JWTSecret,
+						HoldTTL:   time.Minute * 10,
+					},
+				)
+
+				d := dependencies.D{
+					HoldsStorage: hStorage,
+					Logger:       zap.NewExample().Sugar(),
+				}
+
+				m := MakeMux(d)
+
+				m.ServeHTTP(httpW, req)
+
+				assert.Equal(t, tc.Status, httpW.Code)
+			},
+		)
+	}
+}
+
+func TestBalanceCreateHoldBadRequest(t *testing.T) {
+	type testCase struct {
+		Body   string
+		Status int
+	}
+
+	for _, tc := range []testCase{
+		{Body: fmt.Sprintf(`{"order":"%s","amount":0}`, OrderNumber), Status: http.StatusBadRequest},
+		{Body: `not json`, Status: http.StatusBadRequest},
+		{Body: fmt.Sprintf(`{"order":"%s","amount":10}`, WrongOrderNumber), Status: http.StatusUnprocessableEntity},
+	} {
+		req := httptest.NewRequest("POST", "/api/user/balance/holds", strings.NewReader(tc.Body))
+		req.Header.Add("Content-Type", "application/json")
+		req.Header.Add("Authorization", "Bearer "+JWT)
+		httpW := httptest.NewRecorder()
+		config.Set(
+			config.Config{
+				JWTSecret: JWTSecret,
+			},
+		)
+
+		d := dependencies.D{
+			Logger: zap.NewExample().Sugar(),
+		}
+
+		m := MakeMux(d)
+
+		m.ServeHTTP(httpW, req)
+
+		assert.Equal(t, tc.Status, httpW.Code, tc.Body)
+	}
+}
+
+func TestBalanceCaptureHold(t *testing.T) {
+	type testCase struct {
+		Name   string
+		Err    error
+		Status int
+	}
+
+	testCases := []testCase{
+		{Name: "Captured", Err: nil, Status: http.StatusOK},
+		{Name: "Not found", Err: storage.ErrHoldNotFound, Status: http.StatusNotFound},
+		{Name: "Closed", Err: storage.ErrHoldClosed, Status: http.StatusConflict},
+		{Name: "Insufficient funds", Err: storage.ErrInsufficientFunds, Status: http.StatusPaymentRequired},
+	}
+
+	for _, tc := range testCases {
+		t.Run(
+			tc.Name, func(t *testing.T) {
+				ctrl := gomock.NewController(t)
+				defer ctrl.Finish()
+
+				hStorage := mockstorage.NewMockHoldsStorage(ctrl)
+				hStorage.
+					EXPECT().
+					CaptureHold(testutils.MatchContext(), gomock.Eq(int64(UserID)), gomock.Eq(int64(3))).
+					Return(models.Hold{ID: 3, Status: models.HoldStatusCaptured}, tc.Err)
+
+				req := httptest.NewRequest("POST", "/api/user/balance/holds/3/capture", nil)
+				req.Header.Add("Authorization", "Bearer "+JWT)
+				httpW := httptest.NewRecorder()
+				config.Set(
+					config.Config{
+						JWTSecret: JWTSecret,
+					},
+				)
+
+				d := dependencies.D{
+					HoldsStorage: hStorage,
+					Logger:       zap.NewExample().Sugar(),
+				}
+
+				m := MakeMux(d)
+
+				m.ServeHTTP(httpW, req)
+
+				assert.Equal(t, tc.Status, httpW.Code)
+			},
+		)
+	}
+}
+
+func TestBalanceReleaseHold(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	hStorage := mockstorage.NewMockHoldsStorage(ctrl)
+	hStorage.
+		EXPECT().
+		ReleaseHold(testutils.MatchContext(), gomock.Eq(int64(UserID)), gomock.Eq(int64(3))).
+		Return(models.Hold{ID: 3, OrderNumber: OrderNumber, Amount: 50, Status: models.HoldStatusReleased}, nil)
+
+	req := httptest.NewRequest("POST", "/api/user/balance/holds/3/release", nil)
+	req.Header.Add("Authorization", "Bearer "+JWT)
+	httpW := httptest.NewRecorder()
+	config.Set(
+		config.Config{
+			JWTSecret: JWTSecret,
+		},
+	)
+
+	d := dependencies.D{
+		HoldsStorage: hStorage,
+		Logger:       zap.NewExample().Sugar(),
+	}
+
+	m := MakeMux(d)
+
+	m.ServeHTTP(httpW, req)
+
+	respBody, _ := io.ReadAll(httpW.Body)
+	assert.Equal(t, http.StatusOK, httpW.Code)
+	assert.Contains(t, string(respBody), `"status":"RELEASED"`)
+}
+
+func TestBalanceTransfer(t *testing.T) {
+	type testCase struct {
+		Name   string
+		Err    error
+		Status int
+	}
+
+	testCases := []testCase{
+		{Name: "Transferred", Err: nil, Status: http.StatusCreated},
+		{Name: "Unknown recipient", Err: storage.ErrRecipientNotFound, Status: http.StatusNotFound},
+		{Name: "Self transfer", Err: storage.ErrSelfTransfer, Status: http.StatusBadRequest},
+		{Name: "Insufficient funds", Err: storage.ErrInsufficientFunds, Status: http.StatusPaymentRequired},
+		{Name: "Daily limit", Err: storage.ErrTransferLimit, Status: http.StatusTooManyRequests},
+	}
+
+	for _, tc := range testCases {
+		t.Run(
+			tc.Name, func(t *testing.T) {
+				ctrl := gomock.NewController(t)
+				defer ctrl.Finish()
+
+				tStorage := mockstorage.NewMockTransfersStorage(ctrl)
+				tStorage.
+					EXPECT().
+					Transfer(
+						testutils.MatchContext(), gomock.Eq(int64(UserID)), gomock.Eq("relative"), gomock.Eq(float64(30)),
+						gomock.Eq(float64(100)),
+					).
+					Return(models.Transfer{ID: 1, To: "relative", Amount: 30}, tc.Err)
+
+				req := httptest.NewRequest(
+					"POST", "/api/user/balance/transfer", strings.NewReader(`{"to":"relative","amount":30}`),
+				)
+				req.Header.Add("Content-Type", "application/json")
+				req.Header.Add("Authorization", "Bearer "+JWT)
+				httpW := httptest.NewRecorder()
+				config.Set(
+					config.Config{
+						JWTSecret:          JWTSecret,
+						TransferDailyLimit: 100,
+					},
+				)
+
+				d := dependencies.D{
+					TransfersStorage: tStorage,
+					Logger:           zap.NewExample().Sugar(),
+				}
+
+				m := MakeMux(d)
+
+				m.ServeHTTP(httpW, req)
+
+				assert.Equal(t, tc.Status, httpW.Code)
+			},
+		)
+	}
+}
+
+func TestBalanceTransferBadRequest(t *testing.T) {
+	for _, body := range []string{`{"to":"relative","amount":0}`, `{"amount":10}`, `not json`} {
+		req := httptest.NewRequest("POST", "/api/user/balance/transfer", strings.NewReader(body))
+		req.Header.Add("Content-Type", "application/json")
+		req.Header.Add("Authorization", "Bearer "+JWT)
+		httpW := httptest.NewRecorder()
+		config.Set(
+			config.Config{
+				JWTSecret: JWTSecret,
+			},
+		)
+
+		d := dependencies.D{
+			Logger: zap.NewExample().Sugar(),
+		}
+
+		m := MakeMux(d)
+
+		m.ServeHTTP(httpW, req)
+
+		assert.Equal(t, http.StatusBadRequest, httpW.Code, body)
+	}
 }
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	"github.com/bobgromozeka/yp-diploma1/internal/models"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/config"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/middlewares"
+	"github.com/bobgromozeka/yp-diploma1/internal/storage"
+	mockstorage "github.com/bobgromozeka/yp-diploma1/internal/storage/mock"
+	"github.com/bobgromozeka/yp-diploma1/internal/testutils"
+	"github.com/bobgromozeka/yp-diploma1/internal/webhooks"
+)
+
+const CallbackSecret = "callback-secret"
+
+func signedCallbackRequest(body string, secret string, signedAt time.Time) *http.Request {
+	req := httptest.NewRequest("POST", "/api/internal/accrual/orders", strings.NewReader(body))
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add(middlewares.SignatureTimestampHeader, strconv.FormatInt(signedAt.Unix(), 10))
+	req.Header.Add(middlewares.SignatureHeader, webhooks.Sign(secret, signedAt.Unix(), []byte(body)))
+
+	return req
+}
+
+func TestAccrualCallback(t *testing.T) {
+	type testCase struct {
+		Name   string
+		Err    error
+		Status int
+	}
+
+	testCases := []testCase{
+		{Name: "Applied", Err: nil, Status: http.StatusNoContent},
+		{Name: "Unknown order", Err: storage.ErrOrderNotFound, Status: http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(
+			tc.Name, func(t *testing.T) {
+				ctrl := gomock.NewController(t)
+				defer ctrl.Finish()
+
+				accrual := float64(42)
+				oStorage := mockstorage.NewMockOrdersStorage(ctrl)
+				oStorage.
+					EXPECT().
+					UpdateOrderStatus(
+						testutils.MatchContext(), gomock.Eq(OrderNumber), gomock.Eq(models.OrderStatusProcessed),
+						gomock.Eq(&accrual), gomock.Eq(models.AccrualTerms{}),
+					).
+					Return(tc.Err)
+
+				req := signedCallbackRequest(
+					`{"order":"`+OrderNumber+`","status":"PROCESSED","accrual":42}`, CallbackSecret, time.Now(),
+				)
+				httpW := httptest.NewRecorder()
+				config.Set(
+					config.Config{
+						JWTSecret:             JWTSecret,
+						AccrualCallbackSecret: CallbackSecret,
+					},
+				)
+
+				d := dependencies.D{
+					OrdersStorage: oStorage,
+					Logger:        zap.NewExample().Sugar(),
+				}
+
+				m := MakeMux(d)
+
+				m.ServeHTTP(httpW, req)
+
+				assert.Equal(t, tc.Status, httpW.Code)
+			},
+		)
+	}
+}
+
+func TestAccrualCallbackPushed(t *testing.T) {
+	var pushed []string
+
+	req := signedCallbackRequest(
+		`{"order":"`+OrderNumber+`","status":"PROCESSED","accrual":42}`, CallbackSecret, time.Now(),
+	)
+	httpW := httptest.NewRecorder()
+	config.Set(
+		config.Config{
+			JWTSecret:             JWTSecret,
+			AccrualCallbackSecret: CallbackSecret,
+		},
+	)
+
+	//storage is not touched, pushed result is applied by accrual poller
+	d := dependencies.D{
+		PushAccrualResult: func(_ context.Context, order string, status string, accrual *float64) error {
+			pushed = append(pushed, order, status, strconv.FormatFloat(*accrual, 'f', -1, 64))
+			return nil
+		},
+		Logger: zap.NewExample().Sugar(),
+	}
+
+	m := MakeMux(d)
+
+	m.ServeHTTP(httpW, req)
+
+	assert.Equal(t, http.StatusAccepted, httpW.Code)
+	assert.Equal(t, []string{OrderNumber, models.OrderStatusProcessed, "42"}, pushed)
+}
+
+func TestAccrualCallbackRejected(t *testing.T) {
+	type testCase struct {
+		Name         string
+		ServerSecret string
+		Req          *http.Request
+		Status       int
+	}
+
+	body := `{"order":"` + OrderNumber + `","status":"PROCESSED","accrual":42}`
+	noSignature := httptest.NewRequest("POST", "/api/internal/accrual/orders", strings.NewReader(body))
+	noSignature.Header.Add("Content-Type", "application/json")
+
+	testCases := []testCase{
+		{
+			Name: "Callbacks disabled", ServerSecret: "", Req: signedCallbackRequest(body, "", time.Now()),
+			Status: http.StatusUnauthorized,
+		},
+		{Name: "No signature", ServerSecret: CallbackSecret, Req: noSignature, Status: http.StatusUnauthorized},
+		{
+			Name: "Wrong secret", ServerSecret: CallbackSecret, Req: signedCallbackRequest(body, "wrong", time.Now()),
+			Status: http.StatusUnauthorized,
+		},
+		{
+			Name: "Replayed", ServerSecret: CallbackSecret,
+			Req:    signedCallbackRequest(body, CallbackSecret, time.Now().Add(-time.Hour)),
+			Status: http.StatusUnauthorized,
+		},
+		{
+			Name: "Too large body", ServerSecret: CallbackSecret,
+			Req: signedCallbackRequest(
+				body[:len(body)-1]+strings.Repeat(" ", middlewares.MaxSignedBodySize)+"}", CallbackSecret, time.Now(),
+			),
+			Status: http.StatusRequestEntityTooLarge,
+		},
+		{
+			Name: "Unknown status", ServerSecret: CallbackSecret,
+			Req: signedCallbackRequest(
+				`{"order":"`+OrderNumber+`","status":"UNKNOWN"}`, CallbackSecret, time.Now(),
+			),
+			Status: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(
+			tc.Name, func(t *testing.T) {
+				httpW := httptest.NewRecorder()
+				config.Set(
+					config.Config{
+						JWTSecret:             JWTSecret,
+						AccrualCallbackSecret: tc.ServerSecret,
+					},
+				)
+
+				d := dependencies.D{
+					Logger: zap.NewExample().Sugar(),
+				}
+
+				m := MakeMux(d)
+
+				m.ServeHTTP(httpW, tc.Req)
+
+				assert.Equal(t, tc.Status, httpW.Code)
+			},
+		)
+	}
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/apispec"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/config"
+)
+
+func TestOpenAPIServed(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/openapi.json", nil)
+	httpW := httptest.NewRecorder()
+	config.Set(config.Config{JWTSecret: JWTSecret})
+
+	m := MakeMux(dependencies.D{Logger: zap.NewExample().Sugar()})
+
+	m.ServeHTTP(httpW, req)
+
+	assert.Equal(t, http.StatusOK, httpW.Code)
+	assert.Equal(t, "application/json", httpW.Header().Get("Content-Type"))
+	var doc map[string]any
+	assert.NoError(t, json.NewDecoder(httpW.Body).Decode(&doc))
+	assert.Equal(t, "3.0.3", doc["openapi"])
+}
+
+func TestOpenAPIDescribesEveryRoute(t *testing.T) {
+	config.Set(config.Config{JWTSecret: JWTSecret})
+	doc := apispec.MustLoad()
+
+	routes := map[string]bool{}
+	walkErr := chi.Walk(
+		MakeMux(dependencies.D{}),
+		func(method string, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+			if len(route) > 1 {
+				route = strings.TrimSuffix(route, "/")
+			}
+			routes[method+" "+route] = true
+
+			pathItem := doc.Paths.Find(route)
+			if assert.NotNil(t, pathItem, "%s is not described", route) {
+				assert.NotNil(t, pathItem.GetOperation(method), "%s %s is not described", method, route)
+			}
+			return nil
+		},
+	)
+	require.NoError(t, walkErr)
+
+	//heartbeat is served by middleware, not by route
+	routes["GET /api/health"] = true
+	for path, pathItem := range doc.Paths {
+		for method := range pathItem.Operations() {
+			assert.True(t, routes[method+" "+path], "%s %s is not routed", method, path)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	"github.com/bobgromozeka/yp-diploma1/internal/events"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/config"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/helpers"
+	mockstorage "github.com/bobgromozeka/yp-diploma1/internal/storage/mock"
+	"github.com/bobgromozeka/yp-diploma1/internal/testutils"
+)
+
+func TestEventsStreamWrongLastEventID(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/user/events", nil)
+	req.Header.Add("Authorization", "Bearer "+JWT)
+	req.Header.Add("Last-Event-ID", "abc")
+	httpW := httptest.NewRecorder()
+	config.Set(
+		config.Config{
+			JWTSecret: JWTSecret,
+		},
+	)
+
+	d := dependencies.D{
+		Events: events.NewBroker(),
+		Logger: zap.NewExample().Sugar(),
+	}
+
+	m := MakeMux(d)
+
+	m.ServeHTTP(httpW, req)
+
+	assert.Equal(t, http.StatusBadRequest, httpW.Code)
+}
+
+func TestEventsStreamResume(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	broker := events.NewBroker()
+	missedEvent := events.Event{
+		ID:      6,
+		UserID:  UserID,
+		Type:    events.TypeBalanceWithdrawn,
+		Payload: json.RawMessage(`{"order":"` + OrderNumber + `","sum":100}`),
+	}
+	liveEvent := events.Event{
+		ID:      7,
+		UserID:  UserID,
+		Type:    events.TypeBalanceCredited,
+		Payload: json.RawMessage(`{"order":"` + OrderNumber + `","amount":500}`),
+	}
+	foreignEvent := events.Event{
+		ID:      8,
+		UserID:  UserID + 1,
+		Type:    events.TypeBalanceCredited,
+		Payload: json.RawMessage(`{"order":"1","amount":1}`),
+	}
+
+	eStorage := mockstorage.NewMockEventsStorage(ctrl)
+	eStorage.
+		EXPECT().
+		GetUserEventsAfter(testutils.MatchContext(), gomock.Eq(int64(UserID)), gomock.Eq(int64(5))).
+		DoAndReturn(
+			func(_ context.Context, _ int64, _ int64) ([]events.Event, error) {
+				//missed event is also delivered live, it must not be sent twice
+				broker.Publish(missedEvent)
+				broker.Publish(foreignEvent)
+				broker.Publish(liveEvent)
+				return []events.Event{missedEvent}, nil
+			},
+		)
+
+	config.Set(
+		config.Config{
+			JWTSecret: JWTSecret,
+		},
+	)
+
+	d := dependencies.D{
+		EventsStorage: eStorage,
+		Events:        broker,
+		Logger:        zap.NewExample().Sugar(),
+	}
+
+	server := httptest.NewServer(MakeMux(d))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/api/user/events", nil)
+	req.Header.Add("Authorization", "Bearer "+JWT)
+	req.Header.Add("Last-Event-ID", "5")
+
+	resp, respErr := http.DefaultClient.Do(req)
+	require.NoError(t, respErr)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	var lines []string
+	scanner := bufio.NewScanner(resp.Body)
+	for len(lines) < 8 && scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	assert.Equal(
+		t,
+		`id: 6
+event: balance.withdrawn
+data: {"order":"4561261212345467","sum":100}
+
+id: 7
+event: balance.credited
+data: {"order":"4561261212345467","amount":500}
+`,
+		strings.Join(lines, "\n"),
+	)
+}
+
+func TestEventsStreamEndsOnShutdown(t *testing.T) {
+	config.Set(
+		config.Config{
+			JWTSecret: JWTSecret,
+		},
+	)
+
+	d := dependencies.D{
+		Events: events.NewBroker(),
+		Logger: zap.NewExample().Sugar(),
+	}
+
+	stopping := make(chan struct{})
+	close(stopping)
+	req := httptest.NewRequest("GET", "/api/user/events", nil).
+		WithContext(helpers.WithStopping(context.Background(), stopping))
+	req.Header.Add("Authorization", "Bearer "+JWT)
+	httpW := httptest.NewRecorder()
+
+	served := make(chan struct{})
+	go func() {
+		MakeMux(d).ServeHTTP(httpW, req)
+		close(served)
+	}()
+
+	select {
+	case <-served:
+	case <-time.After(time.Second * 5):
+		t.Fatal("stream must end when server is stopping")
+	}
+	assert.Equal(t, http.StatusOK, httpW.Code)
+}
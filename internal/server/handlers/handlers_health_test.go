@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	"github.com/bobgromozeka/yp-diploma1/internal/circuit"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/config"
+)
+
+func TestReady(t *testing.T) {
+	type testCase struct {
+		Name     string
+		Failures int
+		Status   int
+		Body     string
+	}
+
+	testCases := []testCase{
+		{Name: "Ready", Failures: 0, Status: http.StatusOK, Body: `{"database":"ok","accrual":"closed"}`},
+		{
+			Name: "Accrual circuit is open", Failures: 1, Status: http.StatusOK,
+			Body: `{"database":"ok","accrual":"open"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(
+			tc.Name, func(t *testing.T) {
+				breaker := circuit.New(1, time.Minute)
+				for i := 0; i < tc.Failures; i++ {
+					breaker.Allow()
+					breaker.Record(false)
+				}
+
+				req := httptest.NewRequest("GET", "/api/ready", nil)
+				httpW := httptest.NewRecorder()
+				config.Set(
+					config.Config{
+						JWTSecret: JWTSecret,
+					},
+				)
+
+				d := dependencies.D{
+					AccrualBreaker: breaker,
+					Logger:         zap.NewExample().Sugar(),
+				}
+
+				m := MakeMux(d)
+
+				m.ServeHTTP(httpW, req)
+
+				respBody, _ := io.ReadAll(httpW.Body)
+				assert.Equal(t, tc.Status, httpW.Code)
+				assert.JSONEq(t, tc.Body, string(respBody))
+			},
+		)
+	}
+}
+
+func TestReadyShowsRole(t *testing.T) {
+	for _, isLeader := range []bool{true, false} {
+		leader := &atomic.Bool{}
+		leader.Store(isLeader)
+
+		req := httptest.NewRequest("GET", "/api/ready", nil)
+		httpW := httptest.NewRecorder()
+		config.Set(
+			config.Config{
+				JWTSecret: JWTSecret,
+			},
+		)
+
+		d := dependencies.D{
+			Leader: leader,
+			Logger: zap.NewExample().Sugar(),
+		}
+
+		m := MakeMux(d)
+
+		m.ServeHTTP(httpW, req)
+
+		expectedRole := "follower"
+		if isLeader {
+			expectedRole = "leader"
+		}
+		respBody, _ := io.ReadAll(httpW.Body)
+		assert.Equal(t, http.StatusOK, httpW.Code)
+		assert.JSONEq(t, `{"database":"ok","accrual":"closed","role":"`+expectedRole+`"}`, string(respBody))
+	}
+}
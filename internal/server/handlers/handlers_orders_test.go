@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -15,7 +17,9 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	"github.com/bobgromozeka/yp-diploma1/internal/events"
 	"github.com/bobgromozeka/yp-diploma1/internal/models"
+	"github.com/bobgromozeka/yp-diploma1/internal/orderformat"
 	"github.com/bobgromozeka/yp-diploma1/internal/server/config"
 	"github.com/bobgromozeka/yp-diploma1/internal/storage"
 	mockstorage "github.com/bobgromozeka/yp-diploma1/internal/storage/mock"
@@ -48,7 +52,106 @@ func TestCreateWrongOrderNumber(t *testing.T) {
 
 	respBody, _ := io.ReadAll(httpW.Body)
 	assert.Equal(t, http.StatusUnprocessableEntity, httpW.Code)
-	assert.Equal(t, "Wrong order format\n", string(respBody))
+	assert.Equal(t, "Wrong order format: order number should pass Luhn checksum\n", string(respBody))
+}
+
+func TestCreateOrderStoreFormat(t *testing.T) {
+	formats, parseErr := orderformat.Parse("books=regex:^BK[0-9]+$")
+	assert.NoError(t, parseErr)
+
+	tests := []struct {
+		name         string
+		userStore    string
+		url          string
+		number       string
+		expectedBody string
+	}{
+		{
+			"Number in wrong format of bound store", "books", "/api/user/orders", OrderNumber,
+			"Wrong order format: order number should match ^BK[0-9]+$\n",
+		},
+		{
+			"Store param does not change format", "", "/api/user/orders?store=books", "BK123",
+			"Wrong order format: order number should pass Luhn checksum\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				ctrl := gomock.NewController(t)
+				defer ctrl.Finish()
+
+				uStorage := mockstorage.NewMockUsersStorage(ctrl)
+				uStorage.
+					EXPECT().
+					GetUser(testutils.MatchContext(), gomock.Eq(int64(UserID))).
+					Return(models.User{ID: UserID, Login: "login", Role: models.RoleUser, Store: tt.userStore}, nil)
+
+				req := httptest.NewRequest("POST", tt.url, strings.NewReader(tt.number))
+				req.Header.Add("Content-Type", "text/plain")
+				req.Header.Add("Authorization", "Bearer "+JWT)
+				httpW := httptest.NewRecorder()
+				config.Set(
+					config.Config{
+						JWTSecret: JWTSecret,
+					},
+				)
+
+				m := MakeMux(
+					dependencies.D{UsersStorage: uStorage, OrderFormats: formats, Logger: zap.NewExample().Sugar()},
+				)
+
+				m.ServeHTTP(httpW, req)
+
+				respBody, _ := io.ReadAll(httpW.Body)
+				assert.Equal(t, http.StatusUnprocessableEntity, httpW.Code)
+				assert.Equal(t, tt.expectedBody, string(respBody))
+			},
+		)
+	}
+}
+
+func TestCreateOrderInStore(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	uStorage := mockstorage.NewMockUsersStorage(ctrl)
+	uStorage.
+		EXPECT().
+		GetUser(testutils.MatchContext(), gomock.Eq(int64(UserID))).
+		Return(models.User{ID: UserID, Login: "login", Role: models.RoleUser, Store: "books"}, nil)
+
+	oStorage := mockstorage.NewMockOrdersStorage(ctrl)
+	oStorage.
+		EXPECT().
+		CreateOrder(testutils.MatchContext(), gomock.Eq("BK123"), gomock.Eq(int64(UserID))).
+		Return(nil)
+
+	formats, parseErr := orderformat.Parse("books=regex:^BK[0-9]+$")
+	assert.NoError(t, parseErr)
+
+	req := httptest.NewRequest("POST", "/api/user/orders", strings.NewReader("BK123"))
+	req.Header.Add("Content-Type", "text/plain")
+	req.Header.Add("Authorization", "Bearer "+JWT)
+	httpW := httptest.NewRecorder()
+	config.Set(
+		config.Config{
+			JWTSecret: JWTSecret,
+		},
+	)
+
+	m := MakeMux(
+		dependencies.D{
+			UsersStorage:  uStorage,
+			OrdersStorage: oStorage,
+			OrderFormats:  formats,
+			Logger:        zap.NewExample().Sugar(),
+		},
+	)
+
+	m.ServeHTTP(httpW, req)
+
+	assert.Equal(t, http.StatusAccepted, httpW.Code)
 }
 
 func TestCreateOrderAlreadyCreated(t *testing.T) {
@@ -203,6 +306,42 @@ func TestCreateOrderSuccess(t *testing.T) {
 	assert.Equal(t, "Order accepted", string(respBody))
 }
 
+func TestCreateOrderRequestsPriorityCheck(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	oStorage := mockstorage.NewMockOrdersStorage(ctrl)
+	oStorage.
+		EXPECT().
+		CreateOrder(testutils.MatchContext(), gomock.Eq(OrderNumber), gomock.Eq(int64(UserID))).
+		Return(nil)
+
+	req := httptest.NewRequest("POST", "/api/user/orders", strings.NewReader(OrderNumber))
+	req.Header.Add("Content-Type", "text/plain")
+	req.Header.Add("Authorization", "Bearer "+JWT)
+	httpW := httptest.NewRecorder()
+	config.Set(
+		config.Config{
+			JWTSecret: JWTSecret,
+		},
+	)
+
+	d := dependencies.D{
+		OrdersStorage:  oStorage,
+		PriorityChecks: make(chan string, 1),
+		Logger:         zap.NewExample().Sugar(),
+	}
+
+	m := MakeMux(d)
+
+	m.ServeHTTP(httpW, req)
+
+	assert.Equal(t, http.StatusAccepted, httpW.Code)
+	if assert.Len(t, d.PriorityChecks, 1) {
+		assert.Equal(t, OrderNumber, <-d.PriorityChecks)
+	}
+}
+
 func TestOrdersGetAllInternalServerError(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -335,3 +474,339 @@ func TestOrdersGetAllSuccess(t *testing.T) {
 		string(respBody),
 	)
 }
+
+func TestOrdersGetNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	oStorage := mockstorage.NewMockOrdersStorage(ctrl)
+	oStorage.
+		EXPECT().
+		GetOrder(testutils.MatchContext(), gomock.Eq(OrderNumber)).
+		Return(models.Order{}, storage.ErrOrderNotFound)
+
+	req := httptest.NewRequest("GET", "/api/user/orders/"+OrderNumber, nil)
+	req.Header.Add("Authorization", "Bearer "+JWT)
+	httpW := httptest.NewRecorder()
+	config.Set(
+		config.Config{
+			JWTSecret: JWTSecret,
+		},
+	)
+
+	d := dependencies.D{
+		OrdersStorage: oStorage,
+		Logger:        zap.NewExample().Sugar(),
+	}
+
+	m := MakeMux(d)
+
+	m.ServeHTTP(httpW, req)
+
+	respBody, _ := io.ReadAll(httpW.Body)
+	assert.Equal(t, http.StatusNotFound, httpW.Code)
+	assert.Equal(t, "Order not found\n", string(respBody))
+}
+
+func TestOrdersGetForeign(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	oStorage := mockstorage.NewMockOrdersStorage(ctrl)
+	oStorage.
+		EXPECT().
+		GetOrder(testutils.MatchContext(), gomock.Eq(OrderNumber)).
+		Return(models.Order{ID: 1, UserID: UserID + 1, Number: OrderNumber, Status: models.OrderStatusNew}, nil)
+
+	req := httptest.NewRequest("GET", "/api/user/orders/"+OrderNumber, nil)
+	req.Header.Add("Authorization", "Bearer "+JWT)
+	httpW := httptest.NewRecorder()
+	config.Set(
+		config.Config{
+			JWTSecret: JWTSecret,
+		},
+	)
+
+	d := dependencies.D{
+		OrdersStorage: oStorage,
+		Logger:        zap.NewExample().Sugar(),
+	}
+
+	m := MakeMux(d)
+
+	m.ServeHTTP(httpW, req)
+
+	respBody, _ := io.ReadAll(httpW.Body)
+	assert.Equal(t, http.StatusForbidden, httpW.Code)
+	assert.Equal(t, "Order created by another user\n", string(respBody))
+}
+
+func TestOrdersGetSuccess(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	orderUploadTime := "2023-08-31T19:35:43Z"
+	orderUploadTimeParsed, _ := time.Parse(time.RFC3339, orderUploadTime)
+	oStorage := mockstorage.NewMockOrdersStorage(ctrl)
+	oStorage.
+		EXPECT().
+		GetOrder(testutils.MatchContext(), gomock.Eq(OrderNumber)).
+		Return(
+			models.Order{
+				ID:         1,
+				UserID:     UserID,
+				Number:     OrderNumber,
+				Status:     models.OrderStatusProcessing,
+				UploadedAt: orderUploadTimeParsed,
+			}, nil,
+		)
+
+	req := httptest.NewRequest("GET", "/api/user/orders/"+OrderNumber, nil)
+	req.Header.Add("Authorization", "Bearer "+JWT)
+	httpW := httptest.NewRecorder()
+	config.Set(
+		config.Config{
+			JWTSecret: JWTSecret,
+		},
+	)
+
+	d := dependencies.D{
+		OrdersStorage: oStorage,
+		Logger:        zap.NewExample().Sugar(),
+	}
+
+	m := MakeMux(d)
+
+	m.ServeHTTP(httpW, req)
+
+	respBody, _ := io.ReadAll(httpW.Body)
+	assert.Equal(t, http.StatusOK, httpW.Code)
+	assert.Equal(
+		t,
+		fmt.Sprintf(`{"number":"%s","status":"PROCESSING","uploaded_at":"%s"}`, OrderNumber, orderUploadTime)+"\n",
+		string(respBody),
+	)
+}
+
+func TestOrdersGetWaitStatusChanged(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	orderUploadTime := "2023-08-31T19:35:43Z"
+	orderUploadTimeParsed, _ := time.Parse(time.RFC3339, orderUploadTime)
+	accrual := float64(500)
+	order := models.Order{
+		ID:         1,
+		UserID:     UserID,
+		Number:     OrderNumber,
+		Status:     models.OrderStatusProcessing,
+		UploadedAt: orderUploadTimeParsed,
+	}
+	processedOrder := order
+	processedOrder.Status = models.OrderStatusProcessed
+	processedOrder.Accrual = &accrual
+
+	broker := events.NewBroker()
+	oStorage := mockstorage.NewMockOrdersStorage(ctrl)
+	gomock.InOrder(
+		oStorage.
+			EXPECT().
+			GetOrder(testutils.MatchContext(), gomock.Eq(OrderNumber)).
+			DoAndReturn(
+				func(_ context.Context, _ string) (models.Order, error) {
+					//status is changed by accrual updater right after first read
+					go broker.Publish(
+						events.Event{
+							ID:      1,
+							UserID:  UserID,
+							Type:    events.TypeOrderStatusChanged,
+							Payload: json.RawMessage(fmt.Sprintf(`{"number":"%s","status":"PROCESSED"}`, OrderNumber)),
+						},
+					)
+					return order, nil
+				},
+			),
+		oStorage.
+			EXPECT().
+			GetOrder(testutils.MatchContext(), gomock.Eq(OrderNumber)).
+			Return(processedOrder, nil),
+	)
+
+	req := httptest.NewRequest("GET", "/api/user/orders/"+OrderNumber+"?wait=5s", nil)
+	req.Header.Add("Authorization", "Bearer "+JWT)
+	httpW := httptest.NewRecorder()
+	config.Set(
+		config.Config{
+			JWTSecret: JWTSecret,
+		},
+	)
+
+	d := dependencies.D{
+		OrdersStorage: oStorage,
+		Events:        broker,
+		Logger:        zap.NewExample().Sugar(),
+	}
+
+	m := MakeMux(d)
+
+	m.ServeHTTP(httpW, req)
+
+	respBody, _ := io.ReadAll(httpW.Body)
+	assert.Equal(t, http.StatusOK, httpW.Code)
+	assert.Equal(
+		t,
+		fmt.Sprintf(
+			`{"number":"%s","status":"PROCESSED","accrual":500,"uploaded_at":"%s"}`, OrderNumber, orderUploadTime,
+		)+"\n",
+		string(respBody),
+	)
+}
+
+func TestOrdersGetWrongWait(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/user/orders/"+OrderNumber+"?wait=soon", nil)
+	req.Header.Add("Authorization", "Bearer "+JWT)
+	httpW := httptest.NewRecorder()
+	config.Set(
+		config.Config{
+			JWTSecret: JWTSecret,
+		},
+	)
+
+	d := dependencies.D{
+		Logger: zap.NewExample().Sugar(),
+	}
+
+	m := MakeMux(d)
+
+	m.ServeHTTP(httpW, req)
+
+	assert.Equal(t, http.StatusBadRequest, httpW.Code)
+}
+
+func TestCreateBatchWrongBody(t *testing.T) {
+	type testCase struct {
+		Name         string
+		ContentType  string
+		Body         string
+		ResponseBody string
+	}
+
+	testCases := []testCase{
+		{
+			Name:         "Wrong content type",
+			ContentType:  "application/xml",
+			Body:         "<orders/>",
+			ResponseBody: "Wrong request body: header Content-Type has unexpected value \"application/xml\"\n",
+		},
+		{
+			Name:         "Wrong JSON",
+			ContentType:  "application/json",
+			Body:         `{"order":"1"}`,
+			ResponseBody: "Wrong request body: value must be an array\n",
+		},
+		{
+			Name:         "Empty text",
+			ContentType:  "text/plain",
+			Body:         "\n\n",
+			ResponseBody: "No order numbers\n",
+		},
+		{
+			Name:         "Too many orders",
+			ContentType:  "text/plain",
+			Body:         strings.Repeat(OrderNumber+"\n", 1001),
+			ResponseBody: "Too many order numbers\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(
+			tc.Name, func(t *testing.T) {
+				req := httptest.NewRequest("POST", "/api/user/orders/batch", strings.NewReader(tc.Body))
+				req.Header.Add("Content-Type", tc.ContentType)
+				req.Header.Add("Authorization", "Bearer "+JWT)
+				httpW := httptest.NewRecorder()
+				config.Set(
+					config.Config{
+						JWTSecret: JWTSecret,
+					},
+				)
+
+				d := dependencies.D{
+					Logger: zap.NewExample().Sugar(),
+				}
+
+				m := MakeMux(d)
+
+				m.ServeHTTP(httpW, req)
+
+				respBody, _ := io.ReadAll(httpW.Body)
+				assert.Equal(t, http.StatusBadRequest, httpW.Code)
+				assert.Equal(t, tc.ResponseBody, string(respBody))
+			},
+		)
+	}
+}
+
+func TestCreateBatchSuccess(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	oStorage := mockstorage.NewMockOrdersStorage(ctrl)
+	oStorage.
+		EXPECT().
+		CreateOrders(
+			testutils.MatchContext(), gomock.Eq([]string{OrderNumber, "79927398713", "12345678903"}),
+			gomock.Eq(int64(UserID)),
+		).
+		Times(2).
+		Return(
+			map[string]string{
+				OrderNumber:   models.OrderUploadAccepted,
+				"79927398713": models.OrderUploadAlreadyCreated,
+				"12345678903": models.OrderUploadForeign,
+			}, nil,
+		)
+
+	bodies := map[string]string{
+		"text/plain": OrderNumber + "\r\n79927398713\n" + WrongOrderNumber + "\n12345678903\n" + OrderNumber + "\n",
+		"application/json": fmt.Sprintf(
+			`["%s","79927398713","%s","12345678903","%s"]`, OrderNumber, WrongOrderNumber, OrderNumber,
+		),
+	}
+
+	for contentType, body := range bodies {
+		t.Run(
+			contentType, func(t *testing.T) {
+				req := httptest.NewRequest("POST", "/api/user/orders/batch", strings.NewReader(body))
+				req.Header.Add("Content-Type", contentType)
+				req.Header.Add("Authorization", "Bearer "+JWT)
+				httpW := httptest.NewRecorder()
+				config.Set(
+					config.Config{
+						JWTSecret: JWTSecret,
+					},
+				)
+
+				d := dependencies.D{
+					OrdersStorage: oStorage,
+					Logger:        zap.NewExample().Sugar(),
+				}
+
+				m := MakeMux(d)
+
+				m.ServeHTTP(httpW, req)
+
+				respBody, _ := io.ReadAll(httpW.Body)
+				assert.Equal(t, http.StatusAccepted, httpW.Code)
+				assert.JSONEq(
+					t,
+					`[{"number":"4561261212345467","status":"accepted"},
+					{"number":"79927398713","status":"already_created"},
+					{"number":"12345","status":"invalid","reason":"order number should pass Luhn checksum"},
+					{"number":"12345678903","status":"foreign"}]`,
+					string(respBody),
+				)
+			},
+		)
+	}
+}
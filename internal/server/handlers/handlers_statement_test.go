@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	"github.com/bobgromozeka/yp-diploma1/internal/models"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/config"
+	mockstorage "github.com/bobgromozeka/yp-diploma1/internal/storage/mock"
+	"github.com/bobgromozeka/yp-diploma1/internal/testutils"
+)
+
+func statementEntries() []models.StatementEntry {
+	accrualTime, _ := time.Parse(time.RFC3339, "2023-08-31T19:35:43Z")
+	withdrawalTime, _ := time.Parse(time.RFC3339, "2023-09-01T10:00:00Z")
+
+	return []models.StatementEntry{
+		{Type: models.StatementEntryAccrual, Order: OrderNumber, Amount: 500, Balance: 500, At: accrualTime},
+		{Type: models.StatementEntryWithdrawal, Order: "79927398713", Amount: -120.5, Balance: 379.5, At: withdrawalTime},
+		{
+			Type:    models.StatementEntryAdjustment,
+			Amount:  20.5,
+			Balance: 400,
+			At:      withdrawalTime,
+			Reason:  models.AdjustmentReasonGoodwill,
+		},
+	}
+}
+
+func TestStatementWrongParams(t *testing.T) {
+	for _, query := range []string{"?format=xml", "?from=yesterday", "?to=2023-13-01"} {
+		t.Run(
+			query, func(t *testing.T) {
+				req := httptest.NewRequest("GET", "/api/user/statement"+query, nil)
+				req.Header.Add("Authorization", "Bearer "+JWT)
+				httpW := httptest.NewRecorder()
+				config.Set(
+					config.Config{
+						JWTSecret: JWTSecret,
+					},
+				)
+
+				d := dependencies.D{
+					Logger: zap.NewExample().Sugar(),
+				}
+
+				m := MakeMux(d)
+
+				m.ServeHTTP(httpW, req)
+
+				assert.Equal(t, http.StatusBadRequest, httpW.Code)
+			},
+		)
+	}
+}
+
+func TestStatementCSV(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	from, _ := time.ParseInLocation("2006-01-02", "2023-08-01", time.Local)
+	sStorage := mockstorage.NewMockStatementsStorage(ctrl)
+	sStorage.
+		EXPECT().
+		StreamUserStatement(testutils.MatchContext(), gomock.Eq(int64(UserID)), gomock.Eq(&from), gomock.Nil(), gomock.Any()).
+		DoAndReturn(
+			func(_ context.Context, _ int64, _ *time.Time, _ *time.Time, fn func(e models.StatementEntry) error) error {
+				for _, e := range statementEntries() {
+					if fnErr := fn(e); fnErr != nil {
+						return fnErr
+					}
+				}
+				return nil
+			},
+		)
+
+	req := httptest.NewRequest("GET", "/api/user/statement?format=csv&from=2023-08-01", nil)
+	req.Header.Add("Authorization", "Bearer "+JWT)
+	httpW := httptest.NewRecorder()
+	config.Set(
+		config.Config{
+			JWTSecret: JWTSecret,
+		},
+	)
+
+	d := dependencies.D{
+		StatementsStorage: sStorage,
+		Logger:            zap.NewExample().Sugar(),
+	}
+
+	m := MakeMux(d)
+
+	m.ServeHTTP(httpW, req)
+
+	respBody, _ := io.ReadAll(httpW.Body)
+	assert.Equal(t, http.StatusOK, httpW.Code)
+	assert.Equal(t, "text/csv", httpW.Header().Get("Content-Type"))
+	assert.Equal(
+		t,
+		"type,order,amount,balance,at,reason,counterparty\n"+
+			"accrual,4561261212345467,500,500,2023-08-31T19:35:43Z,,\n"+
+			"withdrawal,79927398713,-120.5,379.5,2023-09-01T10:00:00Z,,\n"+
+			"adjustment,,20.5,400,2023-09-01T10:00:00Z,goodwill,\n",
+		string(respBody),
+	)
+}
+
+func TestStatementJSON(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	sStorage := mockstorage.NewMockStatementsStorage(ctrl)
+	sStorage.
+		EXPECT().
+		StreamUserStatement(testutils.MatchContext(), gomock.Eq(int64(UserID)), gomock.Nil(), gomock.Nil(), gomock.Any()).
+		DoAndReturn(
+			func(_ context.Context, _ int64, _ *time.Time, _ *time.Time, fn func(e models.StatementEntry) error) error {
+				for _, e := range statementEntries() {
+					if fnErr := fn(e); fnErr != nil {
+						return fnErr
+					}
+				}
+				return nil
+			},
+		)
+
+	req := httptest.NewRequest("GET", "/api/user/statement", nil)
+	req.Header.Add("Authorization", "Bearer "+JWT)
+	httpW := httptest.NewRecorder()
+	config.Set(
+		config.Config{
+			JWTSecret: JWTSecret,
+		},
+	)
+
+	d := dependencies.D{
+		StatementsStorage: sStorage,
+		Logger:            zap.NewExample().Sugar(),
+	}
+
+	m := MakeMux(d)
+
+	m.ServeHTTP(httpW, req)
+
+	respBody, _ := io.ReadAll(httpW.Body)
+	assert.Equal(t, http.StatusOK, httpW.Code)
+	assert.JSONEq(
+		t,
+		`[{"type":"accrual","order":"4561261212345467","amount":500,"balance":500,"at":"2023-08-31T19:35:43Z"},
+		{"type":"withdrawal","order":"79927398713","amount":-120.5,"balance":379.5,"at":"2023-09-01T10:00:00Z"},
+		{"type":"adjustment","amount":20.5,"balance":400,"at":"2023-09-01T10:00:00Z","reason":"goodwill"}]`,
+		string(respBody),
+	)
+}
+
+func TestStatementInternalServerError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	sStorage := mockstorage.NewMockStatementsStorage(ctrl)
+	sStorage.
+		EXPECT().
+		StreamUserStatement(testutils.MatchContext(), gomock.Eq(int64(UserID)), gomock.Nil(), gomock.Nil(), gomock.Any()).
+		Return(errors.New("internal server error"))
+
+	req := httptest.NewRequest("GET", "/api/user/statement", nil)
+	req.Header.Add("Authorization", "Bearer "+JWT)
+	httpW := httptest.NewRecorder()
+	config.Set(
+		config.Config{
+			JWTSecret: JWTSecret,
+		},
+	)
+
+	d := dependencies.D{
+		StatementsStorage: sStorage,
+		Logger:            zap.NewExample().Sugar(),
+	}
+
+	m := MakeMux(d)
+
+	m.ServeHTTP(httpW, req)
+
+	assert.Equal(t, http.StatusInternalServerError, httpW.Code)
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -14,6 +15,8 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	"github.com/bobgromozeka/yp-diploma1/internal/audit"
+	"github.com/bobgromozeka/yp-diploma1/internal/models"
 	"github.com/bobgromozeka/yp-diploma1/internal/server/config"
 	"github.com/bobgromozeka/yp-diploma1/internal/storage"
 	mockstorage "github.com/bobgromozeka/yp-diploma1/internal/storage/mock"
@@ -33,31 +36,31 @@ func TestLoginBadRequestWrongJSON(t *testing.T) {
 			Name:         "Wrong JSON",
 			Body:         "{bad body}",
 			Status:       http.StatusBadRequest,
-			ResponseBody: "Bad request\n",
+			ResponseBody: "Wrong request body format\n",
 		},
 		{
 			Name:         "Empty login",
 			Body:         `{"login":"","password":"password"}`,
 			Status:       http.StatusBadRequest,
-			ResponseBody: "Bad request\n",
+			ResponseBody: "Wrong request body field login: minimum string length is 1\n",
 		},
 		{
 			Name:         "Empty password",
 			Body:         `{"login":"login","password":""}`,
 			Status:       http.StatusBadRequest,
-			ResponseBody: "Bad request\n",
+			ResponseBody: "Wrong request body field password: minimum string length is 1\n",
 		},
 		{
 			Name:         "No login",
 			Body:         `{"password":"password"}`,
 			Status:       http.StatusBadRequest,
-			ResponseBody: "Bad request\n",
+			ResponseBody: "Wrong request body field login: property \"login\" is missing\n",
 		},
 		{
 			Name:         "No password",
 			Body:         `{"login":"login"}`,
 			Status:       http.StatusBadRequest,
-			ResponseBody: "Bad request\n",
+			ResponseBody: "Wrong request body field password: property \"password\" is missing\n",
 		},
 	}
 
@@ -98,7 +101,7 @@ func TestLoginInternalServerError(t *testing.T) {
 	uStorage.
 		EXPECT().
 		AuthUser(testutils.MatchContext(), gomock.Eq("login"), gomock.Eq("password")).
-		Return(int64(0), errors.New("internal server error"))
+		Return(models.User{}, errors.New("internal server error"))
 
 	body := strings.NewReader(`{"login":"login","password":"password"}`)
 	req := httptest.NewRequest("POST", "/api/user/login", body)
@@ -132,7 +135,14 @@ func TestLoginUserNotFound(t *testing.T) {
 	uStorage.
 		EXPECT().
 		AuthUser(testutils.MatchContext(), gomock.Eq("login"), gomock.Eq("password")).
-		Return(int64(0), storage.ErrUserNotFound)
+		Return(models.User{}, storage.ErrUserNotFound)
+	aStorage := mockstorage.NewMockAuditStorage(ctrl)
+	aStorage.
+		EXPECT().
+		RecordAuditEvent(
+			testutils.MatchContext(), gomock.Eq(models.AuditEvent{Action: models.AuditUserLoginFailed, Login: "login"}),
+		).
+		Return(nil)
 
 	body := strings.NewReader(`{"login":"login","password":"password"}`)
 	req := httptest.NewRequest("POST", "/api/user/login", body)
@@ -145,6 +155,7 @@ func TestLoginUserNotFound(t *testing.T) {
 		UsersStorage:       uStorage,
 		OrdersStorage:      nil,
 		WithdrawalsStorage: nil,
+		AuditStorage:       aStorage,
 		DB:                 nil,
 		Logger:             logger,
 	}
@@ -166,11 +177,27 @@ func TestLoginSuccess(t *testing.T) {
 	uStorage.
 		EXPECT().
 		AuthUser(testutils.MatchContext(), gomock.Eq("login"), gomock.Eq("password")).
-		Return(int64(UserID), nil)
+		Return(models.User{ID: UserID, Login: "login", Role: models.RoleUser}, nil)
+	aStorage := mockstorage.NewMockAuditStorage(ctrl)
+	aStorage.
+		EXPECT().
+		RecordAuditEvent(testutils.MatchContext(), gomock.Any()).
+		DoAndReturn(
+			func(ctx context.Context, e models.AuditEvent) error {
+				meta := audit.MetaFromContext(ctx)
+				assert.Equal(t, models.AuditUserLoggedIn, e.Action)
+				assert.Equal(t, int64(UserID), *e.UserID)
+				assert.Equal(t, "192.0.2.1", meta.IP)
+				assert.Equal(t, "test-agent", meta.UserAgent)
+				assert.NotEmpty(t, meta.RequestID)
+				return nil
+			},
+		)
 
 	body := strings.NewReader(`{"login":"login","password":"password"}`)
 	req := httptest.NewRequest("POST", "/api/user/login", body)
 	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("User-Agent", "test-agent")
 	httpW := httptest.NewRecorder()
 	logger := zap.NewExample().Sugar()
 	config.Set(
@@ -183,6 +210,7 @@ func TestLoginSuccess(t *testing.T) {
 		UsersStorage:       uStorage,
 		OrdersStorage:      nil,
 		WithdrawalsStorage: nil,
+		AuditStorage:       aStorage,
 		DB:                 nil,
 		Logger:             logger,
 	}
@@ -192,7 +220,7 @@ func TestLoginSuccess(t *testing.T) {
 	m.ServeHTTP(httpW, req)
 	fmt.Println("Bearer " + httpW.Header().Get("Authorization"))
 	assert.Equal(t, http.StatusOK, httpW.Code)
-	assert.Equal(t, "Bearer "+JWT, httpW.Header().Get("Authorization"))
+	assert.Equal(t, "Bearer "+UserJWT, httpW.Header().Get("Authorization"))
 }
 
 func TestRegisterNewUserAlreadyExists(t *testing.T) {
@@ -251,7 +279,7 @@ func TestRegisterNewUserBadRequestBody(t *testing.T) {
 	responseBody, _ := io.ReadAll(httpW.Body)
 
 	assert.Equal(t, http.StatusBadRequest, httpW.Code)
-	assert.Equal(t, "Bad request\n", string(responseBody))
+	assert.Equal(t, "Wrong request body format\n", string(responseBody))
 }
 
 func TestRegisterUserInternalServerError(t *testing.T) {
@@ -299,7 +327,7 @@ func TestRegisterUserInternalServerErrorOnLogin(t *testing.T) {
 	uStorage.
 		EXPECT().
 		AuthUser(testutils.MatchContext(), gomock.Eq("login"), gomock.Eq("password")).
-		Return(int64(0), errors.New("internal server error"))
+		Return(models.User{}, errors.New("internal server error"))
 
 	body := strings.NewReader(`{"login":"login","password":"password"}`)
 	req := httptest.NewRequest("POST", "/api/user/register", body)
@@ -337,7 +365,7 @@ func TestUserRegisterSuccess(t *testing.T) {
 	uStorage.
 		EXPECT().
 		AuthUser(testutils.MatchContext(), gomock.Eq("login"), gomock.Eq("password")).
-		Return(int64(UserID), nil)
+		Return(models.User{ID: UserID, Login: "login", Role: models.RoleUser}, nil)
 
 	body := strings.NewReader(`{"login":"login","password":"password"}`)
 	req := httptest.NewRequest("POST", "/api/user/register", body)
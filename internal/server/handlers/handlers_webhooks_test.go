@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	"github.com/bobgromozeka/yp-diploma1/internal/models"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/config"
+	"github.com/bobgromozeka/yp-diploma1/internal/storage"
+	mockstorage "github.com/bobgromozeka/yp-diploma1/internal/storage/mock"
+	"github.com/bobgromozeka/yp-diploma1/internal/testutils"
+)
+
+func TestWebhooksCreateBadRequest(t *testing.T) {
+	type testCase struct {
+		Name         string
+		Body         string
+		ResponseBody string
+	}
+
+	testCases := []testCase{
+		{
+			Name:         "Wrong JSON",
+			Body:         "{bad body}",
+			ResponseBody: "Wrong request body format\n",
+		},
+		{
+			Name:         "Wrong url",
+			Body:         `{"url":"ftp://example.com"}`,
+			ResponseBody: "Wrong url format\n",
+		},
+		{
+			Name:         "Unknown event type",
+			Body:         `{"url":"https://example.com/hook","event_types":["order.deleted"]}`,
+			ResponseBody: "Unknown event type order.deleted\n",
+		},
+		{
+			Name:         "Short secret",
+			Body:         `{"url":"https://example.com/hook","secret":"short"}`,
+			ResponseBody: "Secret is too short\n",
+		},
+		{
+			Name:         "Loopback url",
+			Body:         `{"url":"http://127.0.0.1:5432/"}`,
+			ResponseBody: "Url points to not allowed address\n",
+		},
+		{
+			Name:         "Link-local url",
+			Body:         `{"url":"http://169.254.169.254/latest/meta-data"}`,
+			ResponseBody: "Url points to not allowed address\n",
+		},
+		{
+			Name:         "Private url",
+			Body:         `{"url":"https://10.0.0.5/hook"}`,
+			ResponseBody: "Url points to not allowed address\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(
+			tc.Name, func(t *testing.T) {
+				req := httptest.NewRequest("POST", "/api/user/webhooks", strings.NewReader(tc.Body))
+				req.Header.Add("Content-Type", "application/json")
+				req.Header.Add("Authorization", "Bearer "+JWT)
+				httpW := httptest.NewRecorder()
+				config.Set(
+					config.Config{
+						JWTSecret: JWTSecret,
+					},
+				)
+
+				d := dependencies.D{
+					Logger: zap.NewExample().Sugar(),
+				}
+
+				m := MakeMux(d)
+
+				m.ServeHTTP(httpW, req)
+
+				respBody, _ := io.ReadAll(httpW.Body)
+				assert.Equal(t, http.StatusBadRequest, httpW.Code)
+				assert.Equal(t, tc.ResponseBody, string(respBody))
+			},
+		)
+	}
+}
+
+func TestWebhooksCreateSuccess(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	createdAt := "2023-09-01T12:00:00Z"
+	createdAtParsed, _ := time.Parse(time.RFC3339, createdAt)
+	wStorage := mockstorage.NewMockWebhooksStorage(ctrl)
+	wStorage.
+		EXPECT().
+		CreateEndpoint(testutils.MatchContext(), gomock.Any()).
+		DoAndReturn(
+			func(_ context.Context, endpoint models.WebhookEndpoint) (models.WebhookEndpoint, error) {
+				assert.Equal(t, int64(UserID), *endpoint.UserID)
+				endpoint.ID = 3
+				endpoint.Active = true
+				endpoint.CreatedAt = createdAtParsed
+				return endpoint, nil
+			},
+		)
+
+	body := strings.NewReader(
+		`{"url":"https://203.0.113.10/hook","secret":"0123456789abcdef","event_types":["balance.credited"]}`,
+	)
+	req := httptest.NewRequest("POST", "/api/user/webhooks", body)
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Authorization", "Bearer "+JWT)
+	httpW := httptest.NewRecorder()
+	config.Set(
+		config.Config{
+			JWTSecret: JWTSecret,
+		},
+	)
+
+	d := dependencies.D{
+		WebhooksStorage: wStorage,
+		Logger:          zap.NewExample().Sugar(),
+	}
+
+	m := MakeMux(d)
+
+	m.ServeHTTP(httpW, req)
+
+	respBody, _ := io.ReadAll(httpW.Body)
+	assert.Equal(t, http.StatusCreated, httpW.Code)
+	assert.JSONEq(
+		t,
+		`{"id":3,"url":"https://203.0.113.10/hook","secret":"0123456789abcdef","event_types":["balance.credited"],
+		"active":true,"created_at":"`+createdAt+`"}`,
+		string(respBody),
+	)
+}
+
+func TestWebhooksDeleteNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	wStorage := mockstorage.NewMockWebhooksStorage(ctrl)
+	wStorage.
+		EXPECT().
+		DeleteEndpoint(testutils.MatchContext(), gomock.Eq(int64(UserID)), gomock.Eq(int64(3))).
+		Return(storage.ErrWebhookNotFound)
+
+	req := httptest.NewRequest("DELETE", "/api/user/webhooks/3", nil)
+	req.Header.Add("Authorization", "Bearer "+JWT)
+	httpW := httptest.NewRecorder()
+	config.Set(
+		config.Config{
+			JWTSecret: JWTSecret,
+		},
+	)
+
+	d := dependencies.D{
+		WebhooksStorage: wStorage,
+		Logger:          zap.NewExample().Sugar(),
+	}
+
+	m := MakeMux(d)
+
+	m.ServeHTTP(httpW, req)
+
+	assert.Equal(t, http.StatusNotFound, httpW.Code)
+}
@@ -0,0 +1,65 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	"github.com/bobgromozeka/yp-diploma1/internal/circuit"
+	httphelpers "github.com/bobgromozeka/yp-diploma1/internal/http"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/responses"
+)
+
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+	RoleLeader        = "leader"
+	RoleFollower      = "follower"
+	PingTimeout       = time.Second * 2
+)
+
+// Ready reports whether instance can serve traffic: database is reachable. Accrual system circuit state is
+// reported only, login, balance and withdrawals work without accrual system.
+func Ready(d dependencies.D) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		readiness := responses.Readiness{
+			Database: StatusOK,
+			Accrual:  circuit.StateClosed,
+		}
+		ready := true
+
+		if d.DB != nil {
+			ctx, cancel := context.WithTimeout(r.Context(), PingTimeout)
+			defer cancel()
+			if pingErr := d.DB.PingContext(ctx); pingErr != nil {
+				d.Logger.Error(pingErr)
+				readiness.Database = StatusUnavailable
+				ready = false
+			}
+		}
+
+		if d.AccrualBreaker != nil {
+			readiness.Accrual = d.AccrualBreaker.State()
+		}
+
+		if d.Leader != nil {
+			readiness.Role = RoleFollower
+			if d.Leader.Load() {
+				readiness.Role = RoleLeader
+			}
+		}
+
+		status := http.StatusOK
+		if !ready {
+			status = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", httphelpers.ContentJSON)
+		w.WriteHeader(status)
+		if encodeErr := json.NewEncoder(w).Encode(readiness); encodeErr != nil {
+			d.Logger.Error(encodeErr)
+		}
+	}
+}
@@ -1,51 +1,80 @@
 package handlers
 
 import (
+	"net/http"
+
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/jwtauth/v5"
 
 	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
 	"github.com/bobgromozeka/yp-diploma1/internal/jwt"
+	"github.com/bobgromozeka/yp-diploma1/internal/metrics"
+	"github.com/bobgromozeka/yp-diploma1/internal/models"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/apispec"
 	"github.com/bobgromozeka/yp-diploma1/internal/server/config"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/handlers/admin"
 	"github.com/bobgromozeka/yp-diploma1/internal/server/handlers/balance"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/handlers/callbacks"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/handlers/docs"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/handlers/events"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/handlers/health"
 	"github.com/bobgromozeka/yp-diploma1/internal/server/handlers/orders"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/handlers/statement"
 	"github.com/bobgromozeka/yp-diploma1/internal/server/handlers/users"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/handlers/webhooks"
 	"github.com/bobgromozeka/yp-diploma1/internal/server/handlers/withdrawals"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/middlewares"
 )
 
 func MakeMux(d dependencies.D) *chi.Mux {
 	r := chi.NewMux()
+	//validation goes after authentication in every group, so unauthenticated requests get 401 instead of 400
+	validate := middlewares.ValidateRequest(apispec.MustLoad())
 
 	r.Use(
 		middleware.StripSlashes,
+		middleware.RequestID,
 		middleware.Logger,
 		middleware.Recoverer,
+		middlewares.AuditMeta,
 	)
 
 	r.Route(
 		"/api", func(r chi.Router) {
 			r.Use(middleware.Heartbeat("/health"))
 
+			r.With(validate).Get("/ready", health.Ready(d))
+			r.With(validate).Get("/openapi.json", docs.OpenAPI(d))
+
 			r.Route(
 				"/user", func(r chi.Router) {
-					r.Post(
-						"/register", users.Register(d),
-					)
+					r.Group(
+						func(r chi.Router) {
+							r.Use(validate)
 
-					r.Post(
-						"/login", users.Login(d),
+							r.Post(
+								"/register", users.Register(d),
+							)
+
+							r.Post(
+								"/login", users.Login(d),
+							)
+						},
 					)
 
 					r.Group(
 						func(r chi.Router) {
 							r.Use(jwtauth.Verifier(jwt.GetTokenAuth(config.Get().JWTSecret)))
 							r.Use(jwtauth.Authenticator)
+							r.Use(validate)
 
 							r.Route(
 								"/orders", func(r chi.Router) {
 									r.Get("/", orders.GetAll(d))
 									r.Post("/", orders.Create(d))
+									r.Post("/batch", orders.CreateBatch(d))
+									r.Get("/{number}", orders.Get(d))
 								},
 							)
 
@@ -57,10 +86,84 @@ func MakeMux(d dependencies.D) *chi.Mux {
 									r.Post(
 										"/withdraw", balance.Withdraw(d),
 									)
+									r.Post("/transfer", balance.Transfer(d))
+									r.Post("/holds", balance.CreateHold(d))
+									r.Post("/holds/{id}/capture", balance.CaptureHold(d))
+									r.Post("/holds/{id}/release", balance.ReleaseHold(d))
+									r.With(middlewares.RequireRole(d, models.RoleAdmin, models.RoleService)).Post(
+										"/withdrawals/{id}/reverse", balance.ReverseWithdrawal(d),
+									)
 								},
 							)
 
 							r.Get("/withdrawals", withdrawals.GetAll(d))
+
+							r.Get("/statement", statement.Get(d))
+
+							r.Get("/events", events.Stream(d))
+
+							r.Route(
+								"/webhooks", func(r chi.Router) {
+									r.Get("/", webhooks.GetAll(d))
+									r.Post("/", webhooks.Create(d))
+									r.Delete("/{id}", webhooks.Delete(d))
+									r.Get("/{id}/deliveries", webhooks.GetDeliveries(d))
+								},
+							)
+						},
+					)
+				},
+			)
+
+			r.Route(
+				"/internal", func(r chi.Router) {
+					r.With(middlewares.RequireSignature(config.Get().AccrualCallbackSecret), validate).Post(
+						"/accrual/orders", callbacks.AccrualResult(d),
+					)
+				},
+			)
+
+			r.Route(
+				"/admin", func(r chi.Router) {
+					r.Use(jwtauth.Verifier(jwt.GetTokenAuth(config.Get().JWTSecret)))
+					r.Use(jwtauth.Authenticator)
+					r.Use(middlewares.RequireRole(d, models.RoleAdmin))
+					r.Use(validate)
+
+					r.Route(
+						"/users", func(r chi.Router) {
+							r.Get("/", admin.FindUsers(d))
+							r.Get("/{id}", admin.GetUser(d))
+							r.Get("/{id}/orders", admin.GetUserOrders(d))
+							r.Get("/{id}/withdrawals", admin.GetUserWithdrawals(d))
+							r.Get("/{id}/adjustments", admin.GetUserAdjustments(d))
+							r.Post("/{id}/adjustments", admin.CreateAdjustment(d))
+						},
+					)
+
+					r.Route(
+						"/orders", func(r chi.Router) {
+							r.Get("/{number}", admin.GetOrder(d))
+							r.Post("/{number}/repoll", admin.RepollOrder(d))
+							r.Post("/{number}/clawback", admin.ClawbackOrder(d))
+						},
+					)
+
+					r.Route(
+						"/adjustments", func(r chi.Router) {
+							r.Post("/{id}/approve", admin.ApproveAdjustment(d))
+							r.Post("/{id}/reject", admin.RejectAdjustment(d))
+						},
+					)
+
+					r.Get("/audit", admin.FindAuditEvents(d))
+					r.Method(http.MethodGet, "/metrics", metrics.Handler())
+
+					r.Route(
+						"/webhooks", func(r chi.Router) {
+							r.Get("/", admin.GetWebhooks(d))
+							r.Post("/", admin.CreateWebhook(d))
+							r.Delete("/{id}", admin.DeleteWebhook(d))
 						},
 					)
 				},
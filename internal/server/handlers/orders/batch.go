@@ -0,0 +1,133 @@
+package orders
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/accrual"
+	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	httphelpers "github.com/bobgromozeka/yp-diploma1/internal/http"
+	"github.com/bobgromozeka/yp-diploma1/internal/jwt"
+	"github.com/bobgromozeka/yp-diploma1/internal/models"
+	"github.com/bobgromozeka/yp-diploma1/internal/orderformat"
+	"github.com/bobgromozeka/yp-diploma1/internal/stores"
+)
+
+const MaxBatchSize = 1000
+
+func CreateBatch(d dependencies.D) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		numbers, parseMessage := parseBatch(r)
+		if parseMessage != "" {
+			http.Error(w, parseMessage, http.StatusBadRequest)
+			return
+		}
+
+		userID, userIDErr := jwt.GetUserID(r.Context())
+		if userIDErr != nil {
+			d.Logger.Error(userIDErr)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		store, storeErr := stores.Of(r.Context(), d, userID)
+		if storeErr != nil {
+			d.Logger.Error(storeErr)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		results := make([]models.OrderUploadResult, 0, len(numbers))
+		validNumbers := make([]string, 0, len(numbers))
+		seen := make(map[string]bool, len(numbers))
+		for _, number := range numbers {
+			if seen[number] {
+				continue
+			}
+			seen[number] = true
+
+			result := models.OrderUploadResult{Number: number, Status: models.OrderUploadInvalid}
+			formatErr := d.OrderFormats.Validate(store, number)
+			switch {
+			case errors.Is(formatErr, orderformat.ErrUnknownStore):
+				d.Logger.Errorw("Store of user is not configured", "user_id", userID, "error", formatErr)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			case formatErr != nil:
+				result.Reason = formatErr.Error()
+			default:
+				validNumbers = append(validNumbers, number)
+			}
+			results = append(results, result)
+		}
+
+		statuses := map[string]string{}
+		if len(validNumbers) > 0 {
+			var createErr error
+			statuses, createErr = d.OrdersStorage.CreateOrders(r.Context(), validNumbers, userID)
+			if createErr != nil {
+				d.Logger.Errorw("Create orders batch", "error", createErr)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		responseStatus := http.StatusOK
+		for i, result := range results {
+			if status, found := statuses[result.Number]; found {
+				results[i].Status = status
+			}
+			if results[i].Status == models.OrderUploadAccepted {
+				responseStatus = http.StatusAccepted
+				accrual.RequestPriorityCheck(d, result.Number)
+			}
+		}
+
+		w.Header().Set("Content-Type", httphelpers.ContentJSON)
+		w.WriteHeader(responseStatus)
+		if encodeErr := json.NewEncoder(w).Encode(results); encodeErr != nil {
+			d.Logger.Error(encodeErr)
+		}
+	}
+}
+
+// parseBatch accepts JSON array of strings or newline separated text. Returns message if body is wrong.
+func parseBatch(r *http.Request) ([]string, string) {
+	var numbers []string
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case httphelpers.ContentJSON:
+		if decodeErr := json.NewDecoder(r.Body).Decode(&numbers); decodeErr != nil {
+			return nil, "Body should be JSON array of order numbers"
+		}
+		for i := range numbers {
+			numbers[i] = strings.TrimSpace(numbers[i])
+		}
+	case httphelpers.ContentText:
+		body, readErr := io.ReadAll(r.Body)
+		if readErr != nil {
+			return nil, "Could not read body"
+		}
+		for _, line := range strings.Split(string(body), "\n") {
+			if number := strings.TrimSpace(line); number != "" {
+				numbers = append(numbers, number)
+			}
+		}
+	default:
+		return nil, "Content type should be " + httphelpers.ContentJSON + " or " + httphelpers.ContentText
+	}
+
+	if len(numbers) < 1 {
+		return nil, "No order numbers"
+	}
+	if len(numbers) > MaxBatchSize {
+		return nil, "Too many order numbers"
+	}
+
+	return numbers, ""
+}
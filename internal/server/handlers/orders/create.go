@@ -4,10 +4,11 @@ import (
 	"io"
 	"net/http"
 
+	"github.com/bobgromozeka/yp-diploma1/internal/accrual"
 	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
-	"github.com/bobgromozeka/yp-diploma1/internal/functions"
 	httphelpers "github.com/bobgromozeka/yp-diploma1/internal/http"
 	"github.com/bobgromozeka/yp-diploma1/internal/jwt"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/helpers"
 	"github.com/bobgromozeka/yp-diploma1/internal/storage"
 )
 
@@ -24,8 +25,7 @@ func Create(d dependencies.D) http.HandlerFunc {
 			return
 		}
 
-		if !functions.CheckLuhn(string(orderNumber)) {
-			http.Error(w, "Wrong order format", http.StatusUnprocessableEntity)
+		if !helpers.CheckOrderFormat(w, r, d, string(orderNumber)) {
 			return
 		}
 
@@ -53,6 +53,8 @@ func Create(d dependencies.D) http.HandlerFunc {
 			}
 		}
 
+		accrual.RequestPriorityCheck(d, string(orderNumber))
+
 		w.WriteHeader(http.StatusAccepted)
 		w.Write([]byte("Order accepted"))
 	}
@@ -0,0 +1,111 @@
+package orders
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	"github.com/bobgromozeka/yp-diploma1/internal/events"
+	"github.com/bobgromozeka/yp-diploma1/internal/jwt"
+	"github.com/bobgromozeka/yp-diploma1/internal/models"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/helpers"
+	"github.com/bobgromozeka/yp-diploma1/internal/storage"
+)
+
+const MaxWait = time.Second * 60
+
+func Get(d dependencies.D) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, userIDErr := jwt.GetUserID(r.Context())
+		if userIDErr != nil {
+			d.Logger.Error(userIDErr)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		number := chi.URLParam(r, "number")
+
+		var wait time.Duration
+		if waitParam := r.URL.Query().Get("wait"); waitParam != "" {
+			parsedWait, parseErr := time.ParseDuration(waitParam)
+			if parseErr != nil || parsedWait < 0 {
+				http.Error(w, "Wrong wait format", http.StatusBadRequest)
+				return
+			}
+			wait = parsedWait
+			if wait > MaxWait {
+				wait = MaxWait
+			}
+		}
+
+		//subscribe before reading order to not miss status change happened in between. Channel closed on
+		//overflow is handled as status change: order is read again.
+		var statusChanges <-chan events.Event
+		if wait > 0 {
+			ch, unsubscribe := d.Events.Subscribe(
+				func(e events.Event) bool {
+					if e.Type != events.TypeOrderStatusChanged || e.UserID != userID {
+						return false
+					}
+					var changedOrder events.OrderStatusChanged
+					return e.Decode(&changedOrder) == nil && changedOrder.Number == number
+				},
+			)
+			defer unsubscribe()
+			statusChanges = ch
+		}
+
+		order, orderErr := getUserOrder(w, r, d, userID, number)
+		if orderErr != nil {
+			return
+		}
+
+		if wait > 0 && !order.IsFinal() {
+			timer := time.NewTimer(wait)
+			defer timer.Stop()
+
+			select {
+			case <-statusChanges:
+				order, orderErr = getUserOrder(w, r, d, userID, number)
+				if orderErr != nil {
+					return
+				}
+			case <-timer.C:
+			case <-helpers.Stopping(r.Context()):
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		if serveErr := helpers.ServeJSON(w, order); serveErr != nil {
+			d.Logger.Error(serveErr)
+			return
+		}
+	}
+}
+
+// getUserOrder writes error response itself, so caller only has to stop handling on error.
+func getUserOrder(
+	w http.ResponseWriter, r *http.Request, d dependencies.D, userID int64, number string,
+) (models.Order, error) {
+	order, orderErr := d.OrdersStorage.GetOrder(r.Context(), number)
+	if orderErr != nil {
+		if errors.Is(orderErr, storage.ErrOrderNotFound) {
+			http.Error(w, "Order not found", http.StatusNotFound)
+		} else {
+			d.Logger.Error(orderErr)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+		return order, orderErr
+	}
+
+	if order.UserID != userID {
+		http.Error(w, "Order created by another user", http.StatusForbidden)
+		return order, storage.ErrOrderForeign
+	}
+
+	return order, nil
+}
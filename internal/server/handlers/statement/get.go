@@ -0,0 +1,179 @@
+package statement
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	httphelpers "github.com/bobgromozeka/yp-diploma1/internal/http"
+	"github.com/bobgromozeka/yp-diploma1/internal/jwt"
+	"github.com/bobgromozeka/yp-diploma1/internal/models"
+)
+
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+	DateLayout = "2006-01-02"
+)
+
+type entryWriter interface {
+	Begin() error
+	Write(e models.StatementEntry) error
+	End() error
+}
+
+func Get(d dependencies.D) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, userIDErr := jwt.GetUserID(r.Context())
+		if userIDErr != nil {
+			d.Logger.Error(userIDErr)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		from, fromErr := parseTime(r.URL.Query().Get("from"))
+		if fromErr != nil {
+			http.Error(w, "Wrong from format", http.StatusBadRequest)
+			return
+		}
+		to, toErr := parseTime(r.URL.Query().Get("to"))
+		if toErr != nil {
+			http.Error(w, "Wrong to format", http.StatusBadRequest)
+			return
+		}
+
+		var writer entryWriter
+		format := r.URL.Query().Get("format")
+		switch format {
+		case "", FormatJSON:
+			format = FormatJSON
+			writer = &jsonWriter{w: w}
+		case FormatCSV:
+			writer = &csvWriter{w: csv.NewWriter(w)}
+		default:
+			http.Error(w, "Format should be json or csv", http.StatusBadRequest)
+			return
+		}
+
+		//response is started lazily, so errors before the first entry still get proper status code
+		started := false
+		begin := func() error {
+			if started {
+				return nil
+			}
+			started = true
+
+			contentType := httphelpers.ContentJSON
+			if format == FormatCSV {
+				contentType = "text/csv"
+			}
+			w.Header().Set("Content-Type", contentType)
+			w.Header().Set("Content-Disposition", "attachment; filename=statement."+format)
+			w.WriteHeader(http.StatusOK)
+
+			return writer.Begin()
+		}
+
+		streamErr := d.StatementsStorage.StreamUserStatement(
+			r.Context(), userID, from, to, func(e models.StatementEntry) error {
+				if beginErr := begin(); beginErr != nil {
+					return beginErr
+				}
+				return writer.Write(e)
+			},
+		)
+		if streamErr != nil {
+			d.Logger.Errorw("Statement streaming failed", "error", streamErr, "user_id", userID)
+			if !started {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		if beginErr := begin(); beginErr != nil {
+			d.Logger.Error(beginErr)
+			return
+		}
+		if endErr := writer.End(); endErr != nil {
+			d.Logger.Error(endErr)
+		}
+	}
+}
+
+func parseTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, parseErr := time.Parse(time.RFC3339, value)
+	if parseErr != nil {
+		parsed, parseErr = time.ParseInLocation(DateLayout, value, time.Local)
+		if parseErr != nil {
+			return nil, parseErr
+		}
+	}
+
+	return &parsed, nil
+}
+
+type jsonWriter struct {
+	w       http.ResponseWriter
+	entries int
+}
+
+func (jw *jsonWriter) Begin() error {
+	_, writeErr := jw.w.Write([]byte("["))
+	return writeErr
+}
+
+func (jw *jsonWriter) Write(e models.StatementEntry) error {
+	if jw.entries > 0 {
+		if _, writeErr := jw.w.Write([]byte(",")); writeErr != nil {
+			return writeErr
+		}
+	}
+	jw.entries++
+
+	encoded, encodeErr := json.Marshal(e)
+	if encodeErr != nil {
+		return encodeErr
+	}
+	_, writeErr := jw.w.Write(encoded)
+
+	return writeErr
+}
+
+func (jw *jsonWriter) End() error {
+	_, writeErr := jw.w.Write([]byte("]\n"))
+	return writeErr
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (cw *csvWriter) Begin() error {
+	return cw.w.Write([]string{"type", "order", "amount", "balance", "at", "reason", "counterparty"})
+}
+
+func (cw *csvWriter) Write(e models.StatementEntry) error {
+	return cw.w.Write(
+		[]string{
+			e.Type,
+			e.Order,
+			strconv.FormatFloat(e.Amount, 'f', -1, 64),
+			strconv.FormatFloat(e.Balance, 'f', -1, 64),
+			e.At.Format(time.RFC3339),
+			e.Reason,
+			e.Counterparty,
+		},
+	)
+}
+
+func (cw *csvWriter) End() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
@@ -8,6 +8,8 @@ import (
 	"github.com/bobgromozeka/yp-diploma1/internal/constants"
 	httphelpers "github.com/bobgromozeka/yp-diploma1/internal/http"
 	"github.com/bobgromozeka/yp-diploma1/internal/jwt"
+	"github.com/bobgromozeka/yp-diploma1/internal/logins"
+	"github.com/bobgromozeka/yp-diploma1/internal/models"
 	"github.com/bobgromozeka/yp-diploma1/internal/server/config"
 	"github.com/bobgromozeka/yp-diploma1/internal/server/requests"
 	"github.com/bobgromozeka/yp-diploma1/internal/storage"
@@ -28,23 +30,33 @@ func Login(d dependencies.D) http.HandlerFunc {
 			return
 		}
 
-		ID, authErr := d.UsersStorage.AuthUser(r.Context(), reqPayload.Login, reqPayload.Password)
+		user, authErr := d.UsersStorage.AuthUser(r.Context(), reqPayload.Login, reqPayload.Password)
 		if authErr != nil && authErr != storage.ErrUserNotFound {
 			d.Logger.Error(authErr)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
 			return
 		} else if authErr == storage.ErrUserNotFound {
+			logins.Record(r.Context(), d, models.AuditEvent{Action: models.AuditUserLoginFailed, Login: reqPayload.Login})
 			http.Error(w, "Wrong login or password", http.StatusUnauthorized)
 			return
 		}
 
-		token, jwtErr := jwt.MakeJWT(config.Get().JWTSecret, jwt.MakeJWTPayload(ID))
+		token, jwtErr := jwt.MakeJWT(config.Get().JWTSecret, jwt.MakeJWTPayload(user.ID, user.Role))
 		if jwtErr != nil {
 			d.Logger.Error(jwtErr)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
 			return
 		}
 
+		logins.Record(
+			r.Context(), d, models.AuditEvent{
+				Action:  models.AuditUserLoggedIn,
+				ActorID: &user.ID,
+				UserID:  &user.ID,
+				Login:   user.Login,
+			},
+		)
+
 		w.Header().Set(constants.AuthorizationHeader, "Bearer "+token)
 		w.WriteHeader(http.StatusOK)
 	}
@@ -38,14 +38,14 @@ func Register(d dependencies.D) http.HandlerFunc {
 			return
 		}
 
-		ID, authErr := d.UsersStorage.AuthUser(r.Context(), reqPayload.Login, reqPayload.Password)
+		user, authErr := d.UsersStorage.AuthUser(r.Context(), reqPayload.Login, reqPayload.Password)
 		if authErr != nil {
 			d.Logger.Error(authErr)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
 			return
 		}
 
-		token, jwtErr := jwt.MakeJWT(config.Get().JWTSecret, jwt.MakeJWTPayload(ID))
+		token, jwtErr := jwt.MakeJWT(config.Get().JWTSecret, jwt.MakeJWTPayload(user.ID, user.Role))
 		if jwtErr != nil {
 			d.Logger.Error(jwtErr)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
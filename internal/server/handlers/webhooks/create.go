@@ -0,0 +1,109 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/exp/slices"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	"github.com/bobgromozeka/yp-diploma1/internal/events"
+	httphelpers "github.com/bobgromozeka/yp-diploma1/internal/http"
+	"github.com/bobgromozeka/yp-diploma1/internal/jwt"
+	"github.com/bobgromozeka/yp-diploma1/internal/models"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/requests"
+	"github.com/bobgromozeka/yp-diploma1/internal/webhooks"
+)
+
+const (
+	MinSecretLength     = 16
+	generatedSecretSize = 32
+)
+
+func Create(d dependencies.D) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !httphelpers.CheckContentType(w, r, httphelpers.ContentJSON) {
+			return
+		}
+
+		userID, userIDErr := jwt.GetUserID(r.Context())
+		if userIDErr != nil {
+			d.Logger.Error(userIDErr)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		var createRequest requests.CreateWebhook
+
+		decoder := json.NewDecoder(r.Body)
+		if decodeErr := decoder.Decode(&createRequest); decodeErr != nil {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		endpoint, validationMessage := MakeEndpoint(r.Context(), createRequest)
+		if validationMessage != "" {
+			http.Error(w, validationMessage, http.StatusBadRequest)
+			return
+		}
+		endpoint.UserID = &userID
+
+		createdEndpoint, createErr := d.WebhooksStorage.CreateEndpoint(r.Context(), endpoint)
+		if createErr != nil {
+			d.Logger.Error(createErr)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		if encodeErr := json.NewEncoder(w).Encode(createdEndpoint); encodeErr != nil {
+			d.Logger.Error(encodeErr)
+		}
+	}
+}
+
+// MakeEndpoint validates request and returns endpoint or message describing what is wrong with request.
+// Endpoints on internal addresses are rejected, dispatcher would otherwise post to them on user's behalf.
+func MakeEndpoint(ctx context.Context, createRequest requests.CreateWebhook) (models.WebhookEndpoint, string) {
+	var endpoint models.WebhookEndpoint
+
+	endpointURL, parseErr := url.Parse(createRequest.URL)
+	if parseErr != nil || (endpointURL.Scheme != "http" && endpointURL.Scheme != "https") || endpointURL.Host == "" {
+		return endpoint, "Wrong url format"
+	}
+
+	for _, eventType := range createRequest.EventTypes {
+		if !slices.Contains(events.Types, eventType) {
+			return endpoint, "Unknown event type " + eventType
+		}
+	}
+
+	secret := createRequest.Secret
+	if secret == "" {
+		secretBytes := make([]byte, generatedSecretSize)
+		if _, randErr := rand.Read(secretBytes); randErr != nil {
+			return endpoint, "Could not generate secret"
+		}
+		secret = hex.EncodeToString(secretBytes)
+	} else if len(secret) < MinSecretLength {
+		return endpoint, "Secret is too short"
+	}
+
+	if hostErr := webhooks.CheckHost(ctx, endpointURL.Hostname()); errors.Is(hostErr, webhooks.ErrForbiddenAddress) {
+		return endpoint, "Url points to not allowed address"
+	} else if hostErr != nil {
+		return endpoint, "Could not resolve url host"
+	}
+
+	endpoint.URL = endpointURL.String()
+	endpoint.Secret = secret
+	endpoint.EventTypes = createRequest.EventTypes
+
+	return endpoint, ""
+}
@@ -0,0 +1,43 @@
+package webhooks
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	"github.com/bobgromozeka/yp-diploma1/internal/jwt"
+	"github.com/bobgromozeka/yp-diploma1/internal/storage"
+)
+
+func Delete(d dependencies.D) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, userIDErr := jwt.GetUserID(r.Context())
+		if userIDErr != nil {
+			d.Logger.Error(userIDErr)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		ID, parseErr := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if parseErr != nil {
+			http.Error(w, "Webhook not found", http.StatusNotFound)
+			return
+		}
+
+		deleteErr := d.WebhooksStorage.DeleteEndpoint(r.Context(), userID, ID)
+		if deleteErr != nil {
+			if errors.Is(deleteErr, storage.ErrWebhookNotFound) {
+				http.Error(w, "Webhook not found", http.StatusNotFound)
+			} else {
+				d.Logger.Error(deleteErr)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,54 @@
+package webhooks
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	"github.com/bobgromozeka/yp-diploma1/internal/jwt"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/helpers"
+	"github.com/bobgromozeka/yp-diploma1/internal/storage"
+)
+
+const DeliveriesLimit = 100
+
+func GetDeliveries(d dependencies.D) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, userIDErr := jwt.GetUserID(r.Context())
+		if userIDErr != nil {
+			d.Logger.Error(userIDErr)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		ID, parseErr := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if parseErr != nil {
+			http.Error(w, "Webhook not found", http.StatusNotFound)
+			return
+		}
+
+		attempts, attemptsErr := d.WebhooksStorage.GetEndpointAttempts(r.Context(), userID, ID, DeliveriesLimit)
+		if attemptsErr != nil {
+			if errors.Is(attemptsErr, storage.ErrWebhookNotFound) {
+				http.Error(w, "Webhook not found", http.StatusNotFound)
+			} else {
+				d.Logger.Error(attemptsErr)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		if len(attempts) < 1 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if serveErr := helpers.ServeJSON(w, attempts); serveErr != nil {
+			d.Logger.Error(serveErr)
+			return
+		}
+	}
+}
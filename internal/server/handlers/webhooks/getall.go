@@ -0,0 +1,37 @@
+package webhooks
+
+import (
+	"net/http"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	"github.com/bobgromozeka/yp-diploma1/internal/jwt"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/helpers"
+)
+
+func GetAll(d dependencies.D) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, userIDErr := jwt.GetUserID(r.Context())
+		if userIDErr != nil {
+			d.Logger.Error(userIDErr)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		endpoints, endpointsErr := d.WebhooksStorage.GetUserEndpoints(r.Context(), userID)
+		if endpointsErr != nil {
+			d.Logger.Error(endpointsErr)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		if len(endpoints) < 1 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if serveErr := helpers.ServeJSON(w, endpoints); serveErr != nil {
+			d.Logger.Error(serveErr)
+			return
+		}
+	}
+}
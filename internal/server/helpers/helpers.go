@@ -1,10 +1,32 @@
 package helpers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	"github.com/bobgromozeka/yp-diploma1/internal/jwt"
+	"github.com/bobgromozeka/yp-diploma1/internal/orderformat"
+	"github.com/bobgromozeka/yp-diploma1/internal/stores"
 )
 
+type stoppingKey struct{}
+
+// WithStopping stores channel which is closed when server starts shutting down.
+func WithStopping(ctx context.Context, stopping <-chan struct{}) context.Context {
+	return context.WithValue(ctx, stoppingKey{}, stopping)
+}
+
+// Stopping returns channel which is closed when server starts shutting down. Shutdown waits for active requests,
+// so long-lived responses (event streams, long polls) must end on it. Outside of server channel is never closed.
+func Stopping(ctx context.Context) <-chan struct{} {
+	stopping, _ := ctx.Value(stoppingKey{}).(<-chan struct{})
+
+	return stopping
+}
+
 func ServeJSON(w http.ResponseWriter, payload any) error {
 	je := json.NewEncoder(w)
 
@@ -18,3 +40,36 @@ func ServeJSON(w http.ResponseWriter, payload any) error {
 
 	return nil
 }
+
+// CheckOrderFormat validates number in format of store bound to authenticated user. Number in wrong format is
+// unprocessable entity.
+func CheckOrderFormat(w http.ResponseWriter, r *http.Request, d dependencies.D, number string) bool {
+	userID, userIDErr := jwt.GetUserID(r.Context())
+	if userIDErr != nil {
+		d.Logger.Error(userIDErr)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return false
+	}
+
+	store, storeErr := stores.Of(r.Context(), d, userID)
+	if storeErr != nil {
+		d.Logger.Error(storeErr)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return false
+	}
+
+	formatErr := d.OrderFormats.Validate(store, number)
+	if formatErr == nil {
+		return true
+	}
+
+	if errors.Is(formatErr, orderformat.ErrUnknownStore) {
+		//stores of accounts are checked on start, so it is a configuration mismatch between instances
+		d.Logger.Errorw("Store of user is not configured", "user_id", userID, "error", formatErr)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	} else {
+		http.Error(w, "Wrong order format: "+formatErr.Error(), http.StatusUnprocessableEntity)
+	}
+
+	return false
+}
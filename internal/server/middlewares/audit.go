@@ -0,0 +1,37 @@
+package middlewares
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/audit"
+)
+
+// AuditMeta must be used after middleware.RequestID.
+func AuditMeta(next http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			ip, _, splitErr := net.SplitHostPort(r.RemoteAddr)
+			if splitErr != nil {
+				ip = r.RemoteAddr
+			}
+
+			requestID := middleware.GetReqID(r.Context())
+			if requestID != "" {
+				w.Header().Set(middleware.RequestIDHeader, requestID)
+			}
+
+			ctx := audit.WithMeta(
+				r.Context(), audit.Meta{
+					IP:        ip,
+					UserAgent: r.UserAgent(),
+					RequestID: requestID,
+				},
+			)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		},
+	)
+}
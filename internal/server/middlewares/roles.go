@@ -0,0 +1,45 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+
+	"golang.org/x/exp/slices"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	"github.com/bobgromozeka/yp-diploma1/internal/jwt"
+	"github.com/bobgromozeka/yp-diploma1/internal/storage"
+)
+
+// RequireRole must be used after jwtauth.Authenticator. Role is loaded from storage instead of token claims,
+// so revoked roles stop working immediately for already issued tokens.
+func RequireRole(d dependencies.D, roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				userID, userIDErr := jwt.GetUserID(r.Context())
+				if userIDErr != nil {
+					http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+					return
+				}
+
+				user, userErr := d.UsersStorage.GetUser(r.Context(), userID)
+				if errors.Is(userErr, storage.ErrUserNotFound) {
+					http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+					return
+				} else if userErr != nil {
+					d.Logger.Error(userErr)
+					http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+					return
+				}
+
+				if !slices.Contains(roles, user.Role) {
+					http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+					return
+				}
+
+				next.ServeHTTP(w, r)
+			},
+		)
+	}
+}
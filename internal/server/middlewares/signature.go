@@ -0,0 +1,65 @@
+package middlewares
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/webhooks"
+)
+
+const (
+	SignatureTimestampHeader = "X-Accrual-Timestamp"
+	SignatureHeader          = "X-Accrual-Signature"
+	// Requests signed earlier are rejected, so captured requests can't be replayed later.
+	SignatureTolerance = time.Minute * 5
+	// Signature is checked after body is read, so unauthenticated requests can't make server buffer more.
+	MaxSignedBodySize = 64 << 10
+)
+
+// RequireSignature checks HMAC-SHA256 signature of "timestamp.body", the same scheme as outgoing webhooks use.
+// Every request is rejected when secret is empty.
+func RequireSignature(secret string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				timestamp, parseErr := strconv.ParseInt(r.Header.Get(SignatureTimestampHeader), 10, 64)
+				if secret == "" || parseErr != nil {
+					http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+					return
+				}
+
+				signedAt := time.Unix(timestamp, 0)
+				if time.Since(signedAt) > SignatureTolerance || time.Until(signedAt) > SignatureTolerance {
+					http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+					return
+				}
+
+				body, readErr := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxSignedBodySize))
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(readErr, &maxBytesErr) {
+					http.Error(
+						w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge,
+					)
+					return
+				} else if readErr != nil {
+					http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+					return
+				}
+
+				expected := webhooks.Sign(secret, timestamp, body)
+				if !hmac.Equal([]byte(expected), []byte(r.Header.Get(SignatureHeader))) {
+					http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+					return
+				}
+
+				r.Body = io.NopCloser(bytes.NewReader(body))
+				next.ServeHTTP(w, r)
+			},
+		)
+	}
+}
@@ -0,0 +1,94 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+)
+
+// ValidateRequest rejects requests which don't match OpenAPI specification with 400. Routes missing in
+// specification are left to router. Security requirements are not checked here, so it must go after route
+// authentication middlewares.
+func ValidateRequest(doc *openapi3.T) func(http.Handler) http.Handler {
+	router, routerErr := gorillamux.NewRouter(doc)
+	if routerErr != nil {
+		panic(routerErr)
+	}
+
+	options := &openapi3filter.Options{
+		AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+		//request is passed to handlers as is, signed callbacks must keep the same body
+		SkipSettingDefaults: true,
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				route, pathParams, findErr := router.FindRoute(routeRequest(r))
+				if findErr != nil {
+					next.ServeHTTP(w, r)
+					return
+				}
+
+				validateErr := openapi3filter.ValidateRequest(
+					r.Context(), &openapi3filter.RequestValidationInput{
+						Request:    r,
+						PathParams: pathParams,
+						Route:      route,
+						Options:    options,
+					},
+				)
+				if validateErr != nil {
+					http.Error(w, validationMessage(validateErr), http.StatusBadRequest)
+					return
+				}
+
+				next.ServeHTTP(w, r)
+			},
+		)
+	}
+}
+
+// routeRequest strips trailing slash the same way as middleware.StripSlashes does for chi routes.
+func routeRequest(r *http.Request) *http.Request {
+	if len(r.URL.Path) < 2 || !strings.HasSuffix(r.URL.Path, "/") {
+		return r
+	}
+
+	stripped := r.Clone(r.Context())
+	stripped.URL.Path = strings.TrimSuffix(r.URL.Path, "/")
+	stripped.URL.RawPath = ""
+
+	return stripped
+}
+
+func validationMessage(err error) string {
+	var requestErr *openapi3filter.RequestError
+	if !errors.As(err, &requestErr) {
+		return "Bad request"
+	}
+
+	subject := "request body"
+	if requestErr.Parameter != nil {
+		subject = requestErr.Parameter.Name
+	}
+
+	var parseErr *openapi3filter.ParseError
+	if errors.As(err, &parseErr) {
+		return "Wrong " + subject + " format"
+	}
+
+	var schemaErr *openapi3.SchemaError
+	if errors.As(err, &schemaErr) {
+		if pointer := schemaErr.JSONPointer(); len(pointer) > 0 {
+			subject += " field " + strings.Join(pointer, ".")
+		}
+		return "Wrong " + subject + ": " + schemaErr.Reason
+	}
+
+	return "Wrong " + subject + ": " + requestErr.Reason
+}
@@ -11,3 +11,33 @@ type Withdraw struct {
 	Order string  `json:"order"`
 	Sum   float64 `json:"sum"`
 }
+
+type CreateWebhook struct {
+	URL        string   `json:"url"`
+	Secret     string   `json:"secret"`
+	EventTypes []string `json:"event_types"`
+}
+
+type CreateAdjustment struct {
+	Amount  float64 `json:"amount"`
+	Reason  string  `json:"reason"`
+	Comment string  `json:"comment"`
+}
+
+type Clawback struct {
+	Reason string `json:"reason"`
+}
+
+type ReverseWithdrawal struct {
+	Reason string `json:"reason"`
+}
+
+type Transfer struct {
+	To     string  `json:"to"`
+	Amount float64 `json:"amount"`
+}
+
+type CreateHold struct {
+	Order  string  `json:"order"`
+	Amount float64 `json:"amount"`
+}
@@ -1,5 +1,9 @@
 package responses
 
+import (
+	"github.com/bobgromozeka/yp-diploma1/internal/models"
+)
+
 type Register struct {
 	Token string `json:"token"`
 }
@@ -7,6 +11,41 @@ type Register struct {
 type Login Register
 
 type Balance struct {
-	Current   float64 `json:"current"`
-	Withdrawn float64 `json:"withdrawn"`
+	Current      float64                 `json:"current"`
+	Held         float64                 `json:"held"`
+	Pending      float64                 `json:"pending"`
+	Withdrawn    float64                 `json:"withdrawn"`
+	ExpiringSoon []models.ExpiringPoints `json:"expiring_soon,omitempty"`
+}
+
+type AdminUser struct {
+	models.User
+	Balance Balance `json:"balance"`
+}
+
+type AdminOrder struct {
+	models.Order
+	UserID int64 `json:"user_id"`
+}
+
+// AdminWithdrawal exposes withdrawal ID, reversals address withdrawal by it.
+type AdminWithdrawal struct {
+	models.Withdrawal
+	ID int64 `json:"id"`
+}
+
+func MakeAdminWithdrawals(withdrawals []models.Withdrawal) []AdminWithdrawal {
+	adminWithdrawals := make([]AdminWithdrawal, 0, len(withdrawals))
+	for _, w := range withdrawals {
+		adminWithdrawals = append(adminWithdrawals, AdminWithdrawal{Withdrawal: w, ID: w.ID})
+	}
+
+	return adminWithdrawals
+}
+
+type Readiness struct {
+	Database string `json:"database"`
+	Accrual  string `json:"accrual"`
+	// Role is leader or follower of background jobs election.
+	Role string `json:"role,omitempty"`
 }
@@ -4,33 +4,41 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
-	"time"
 
 	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
 	"github.com/bobgromozeka/yp-diploma1/internal/server/config"
 	"github.com/bobgromozeka/yp-diploma1/internal/server/handlers"
+	"github.com/bobgromozeka/yp-diploma1/internal/server/helpers"
 )
 
-func Run(shutdownCtx context.Context, d dependencies.D) {
-	server := http.Server{Addr: config.Get().RunAddress, Handler: handlers.MakeMux(d)}
+// Run serves requests until shutdownCtx is done, then waits for active requests until forceCtx is done.
+// Event streams and long polls end on shutdown, see helpers.Stopping.
+// Returns error if connections were closed forcibly.
+func Run(shutdownCtx context.Context, forceCtx context.Context, d dependencies.D) error {
+	server := http.Server{
+		Addr:    config.Get().RunAddress,
+		Handler: handlers.MakeMux(d),
+		//request contexts are not canceled on shutdown, so started requests finish, but streams must end
+		BaseContext: func(net.Listener) context.Context {
+			return helpers.WithStopping(context.Background(), shutdownCtx.Done())
+		},
+	}
 
 	//graceful shutdown
+	shutdownErr := make(chan error, 1)
 	go func() {
 		<-shutdownCtx.Done()
 
-		forceCtx, cancelForceCtx := context.WithTimeout(context.Background(), time.Second*30)
-		go func() {
-			<-forceCtx.Done()
-			d.Logger.Fatal("Shutdown deadline is exceeded. Forcing exit")
-		}()
-
 		d.Logger.Info("Shutting down server.....")
-		err := server.Shutdown(forceCtx)
-		if err != nil {
-			d.Logger.Fatal(err)
+		if err := server.Shutdown(forceCtx); err != nil {
+			d.Logger.Errorw("Server shutdown deadline is exceeded, closing active connections", "error", err)
+			server.Close()
+			shutdownErr <- err
+			return
 		}
-		cancelForceCtx()
+		shutdownErr <- nil
 	}()
 
 	fmt.Println("Running server on " + config.Get().RunAddress)
@@ -39,4 +47,6 @@ func Run(shutdownCtx context.Context, d dependencies.D) {
 			d.Logger.Fatalln(err)
 		}
 	}
+
+	return <-shutdownErr
 }
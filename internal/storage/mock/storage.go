@@ -7,7 +7,9 @@ package mock_storage
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
+	events "github.com/bobgromozeka/yp-diploma1/internal/events"
 	models "github.com/bobgromozeka/yp-diploma1/internal/models"
 	storage "github.com/bobgromozeka/yp-diploma1/internal/storage"
 	gomock "github.com/golang/mock/gomock"
@@ -37,10 +39,10 @@ func (m *MockUsersStorage) EXPECT() *MockUsersStorageMockRecorder {
 }
 
 // AuthUser mocks base method.
-func (m *MockUsersStorage) AuthUser(ctx context.Context, login, password string) (int64, error) {
+func (m *MockUsersStorage) AuthUser(ctx context.Context, login, password string) (models.User, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "AuthUser", ctx, login, password)
-	ret0, _ := ret[0].(int64)
+	ret0, _ := ret[0].(models.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -65,6 +67,94 @@ func (mr *MockUsersStorageMockRecorder) CreateUser(ctx, login, password interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockUsersStorage)(nil).CreateUser), ctx, login, password)
 }
 
+// FindUsers mocks base method.
+func (m *MockUsersStorage) FindUsers(ctx context.Context, loginPrefix string, limit int) ([]models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindUsers", ctx, loginPrefix, limit)
+	ret0, _ := ret[0].([]models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindUsers indicates an expected call of FindUsers.
+func (mr *MockUsersStorageMockRecorder) FindUsers(ctx, loginPrefix, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindUsers", reflect.TypeOf((*MockUsersStorage)(nil).FindUsers), ctx, loginPrefix, limit)
+}
+
+// GetUser mocks base method.
+func (m *MockUsersStorage) GetUser(ctx context.Context, ID int64) (models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUser", ctx, ID)
+	ret0, _ := ret[0].(models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUser indicates an expected call of GetUser.
+func (mr *MockUsersStorageMockRecorder) GetUser(ctx, ID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUser", reflect.TypeOf((*MockUsersStorage)(nil).GetUser), ctx, ID)
+}
+
+// RevokeRole mocks base method.
+func (m *MockUsersStorage) RevokeRole(ctx context.Context, role string, keepLogins []string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeRole", ctx, role, keepLogins)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeRole indicates an expected call of RevokeRole.
+func (mr *MockUsersStorageMockRecorder) RevokeRole(ctx, role, keepLogins interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeRole", reflect.TypeOf((*MockUsersStorage)(nil).RevokeRole), ctx, role, keepLogins)
+}
+
+// SetUserRole mocks base method.
+func (m *MockUsersStorage) SetUserRole(ctx context.Context, login, role string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetUserRole", ctx, login, role)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetUserRole indicates an expected call of SetUserRole.
+func (mr *MockUsersStorageMockRecorder) SetUserRole(ctx, login, role interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetUserRole", reflect.TypeOf((*MockUsersStorage)(nil).SetUserRole), ctx, login, role)
+}
+
+// SetUserStore mocks base method.
+func (m *MockUsersStorage) SetUserStore(ctx context.Context, login, store string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetUserStore", ctx, login, store)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetUserStore indicates an expected call of SetUserStore.
+func (mr *MockUsersStorageMockRecorder) SetUserStore(ctx, login, store interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetUserStore", reflect.TypeOf((*MockUsersStorage)(nil).SetUserStore), ctx, login, store)
+}
+
+// UnbindStores mocks base method.
+func (m *MockUsersStorage) UnbindStores(ctx context.Context, keepLogins []string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnbindStores", ctx, keepLogins)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UnbindStores indicates an expected call of UnbindStores.
+func (mr *MockUsersStorageMockRecorder) UnbindStores(ctx, keepLogins interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnbindStores", reflect.TypeOf((*MockUsersStorage)(nil).UnbindStores), ctx, keepLogins)
+}
+
 // MockOrdersStorage is a mock of OrdersStorage interface.
 type MockOrdersStorage struct {
 	ctrl     *gomock.Controller
@@ -88,6 +178,50 @@ func (m *MockOrdersStorage) EXPECT() *MockOrdersStorageMockRecorder {
 	return m.recorder
 }
 
+// ClaimPriorityChecks mocks base method.
+func (m *MockOrdersStorage) ClaimPriorityChecks(ctx context.Context, numbers []string) ([]models.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimPriorityChecks", ctx, numbers)
+	ret0, _ := ret[0].([]models.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimPriorityChecks indicates an expected call of ClaimPriorityChecks.
+func (mr *MockOrdersStorageMockRecorder) ClaimPriorityChecks(ctx, numbers interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimPriorityChecks", reflect.TypeOf((*MockOrdersStorage)(nil).ClaimPriorityChecks), ctx, numbers)
+}
+
+// ClaimStalePriorityChecks mocks base method.
+func (m *MockOrdersStorage) ClaimStalePriorityChecks(ctx context.Context, requestedBefore time.Time, limit int) ([]models.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimStalePriorityChecks", ctx, requestedBefore, limit)
+	ret0, _ := ret[0].([]models.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimStalePriorityChecks indicates an expected call of ClaimStalePriorityChecks.
+func (mr *MockOrdersStorageMockRecorder) ClaimStalePriorityChecks(ctx, requestedBefore, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimStalePriorityChecks", reflect.TypeOf((*MockOrdersStorage)(nil).ClaimStalePriorityChecks), ctx, requestedBefore, limit)
+}
+
+// ClawbackPendingAccrual mocks base method.
+func (m *MockOrdersStorage) ClawbackPendingAccrual(ctx context.Context, number, reason string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClawbackPendingAccrual", ctx, number, reason)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ClawbackPendingAccrual indicates an expected call of ClawbackPendingAccrual.
+func (mr *MockOrdersStorageMockRecorder) ClawbackPendingAccrual(ctx, number, reason interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClawbackPendingAccrual", reflect.TypeOf((*MockOrdersStorage)(nil).ClawbackPendingAccrual), ctx, number, reason)
+}
+
 // CreateOrder mocks base method.
 func (m *MockOrdersStorage) CreateOrder(ctx context.Context, number string, userID int64) error {
 	m.ctrl.T.Helper()
@@ -102,6 +236,21 @@ func (mr *MockOrdersStorageMockRecorder) CreateOrder(ctx, number, userID interfa
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOrder", reflect.TypeOf((*MockOrdersStorage)(nil).CreateOrder), ctx, number, userID)
 }
 
+// CreateOrders mocks base method.
+func (m *MockOrdersStorage) CreateOrders(ctx context.Context, numbers []string, userID int64) (map[string]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOrders", ctx, numbers, userID)
+	ret0, _ := ret[0].(map[string]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateOrders indicates an expected call of CreateOrders.
+func (mr *MockOrdersStorageMockRecorder) CreateOrders(ctx, numbers, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOrders", reflect.TypeOf((*MockOrdersStorage)(nil).CreateOrders), ctx, numbers, userID)
+}
+
 // GetLatestUnprocessedOrders mocks base method.
 func (m *MockOrdersStorage) GetLatestUnprocessedOrders(ctx context.Context, count int) ([]models.Order, error) {
 	m.ctrl.T.Helper()
@@ -117,6 +266,21 @@ func (mr *MockOrdersStorageMockRecorder) GetLatestUnprocessedOrders(ctx, count i
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLatestUnprocessedOrders", reflect.TypeOf((*MockOrdersStorage)(nil).GetLatestUnprocessedOrders), ctx, count)
 }
 
+// GetOrder mocks base method.
+func (m *MockOrdersStorage) GetOrder(ctx context.Context, number string) (models.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrder", ctx, number)
+	ret0, _ := ret[0].(models.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrder indicates an expected call of GetOrder.
+func (mr *MockOrdersStorageMockRecorder) GetOrder(ctx, number interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrder", reflect.TypeOf((*MockOrdersStorage)(nil).GetOrder), ctx, number)
+}
+
 // GetUserOrders mocks base method.
 func (m *MockOrdersStorage) GetUserOrders(ctx context.Context, userID int64) ([]models.Order, error) {
 	m.ctrl.T.Helper()
@@ -132,18 +296,32 @@ func (mr *MockOrdersStorageMockRecorder) GetUserOrders(ctx, userID interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserOrders", reflect.TypeOf((*MockOrdersStorage)(nil).GetUserOrders), ctx, userID)
 }
 
+// RequeueOrder mocks base method.
+func (m *MockOrdersStorage) RequeueOrder(ctx context.Context, number string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RequeueOrder", ctx, number)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RequeueOrder indicates an expected call of RequeueOrder.
+func (mr *MockOrdersStorageMockRecorder) RequeueOrder(ctx, number interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequeueOrder", reflect.TypeOf((*MockOrdersStorage)(nil).RequeueOrder), ctx, number)
+}
+
 // UpdateOrderStatus mocks base method.
-func (m *MockOrdersStorage) UpdateOrderStatus(ctx context.Context, number, status string, accrual *float64) error {
+func (m *MockOrdersStorage) UpdateOrderStatus(ctx context.Context, number, status string, accrual *float64, terms models.AccrualTerms) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateOrderStatus", ctx, number, status, accrual)
+	ret := m.ctrl.Call(m, "UpdateOrderStatus", ctx, number, status, accrual, terms)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // UpdateOrderStatus indicates an expected call of UpdateOrderStatus.
-func (mr *MockOrdersStorageMockRecorder) UpdateOrderStatus(ctx, number, status, accrual interface{}) *gomock.Call {
+func (mr *MockOrdersStorageMockRecorder) UpdateOrderStatus(ctx, number, status, accrual, terms interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateOrderStatus", reflect.TypeOf((*MockOrdersStorage)(nil).UpdateOrderStatus), ctx, number, status, accrual)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateOrderStatus", reflect.TypeOf((*MockOrdersStorage)(nil).UpdateOrderStatus), ctx, number, status, accrual, terms)
 }
 
 // MockWithdrawalsStorage is a mock of WithdrawalsStorage interface.
@@ -170,13 +348,12 @@ func (m *MockWithdrawalsStorage) EXPECT() *MockWithdrawalsStorageMockRecorder {
 }
 
 // GetUserBalance mocks base method.
-func (m *MockWithdrawalsStorage) GetUserBalance(ctx context.Context, userID int64) (float64, float64, error) {
+func (m *MockWithdrawalsStorage) GetUserBalance(ctx context.Context, userID int64) (models.Balance, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetUserBalance", ctx, userID)
-	ret0, _ := ret[0].(float64)
-	ret1, _ := ret[1].(float64)
-	ret2, _ := ret[2].(error)
-	return ret0, ret1, ret2
+	ret0, _ := ret[0].(models.Balance)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // GetUserBalance indicates an expected call of GetUserBalance.
@@ -200,6 +377,21 @@ func (mr *MockWithdrawalsStorageMockRecorder) GetUserWithdrawals(ctx, userID int
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserWithdrawals", reflect.TypeOf((*MockWithdrawalsStorage)(nil).GetUserWithdrawals), ctx, userID)
 }
 
+// ReverseWithdrawal mocks base method.
+func (m *MockWithdrawalsStorage) ReverseWithdrawal(ctx context.Context, ID int64, reason string, actorID int64) (models.Withdrawal, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReverseWithdrawal", ctx, ID, reason, actorID)
+	ret0, _ := ret[0].(models.Withdrawal)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReverseWithdrawal indicates an expected call of ReverseWithdrawal.
+func (mr *MockWithdrawalsStorageMockRecorder) ReverseWithdrawal(ctx, ID, reason, actorID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReverseWithdrawal", reflect.TypeOf((*MockWithdrawalsStorage)(nil).ReverseWithdrawal), ctx, ID, reason, actorID)
+}
+
 // Withdraw mocks base method.
 func (m *MockWithdrawalsStorage) Withdraw(ctx context.Context, userID int64, orderNumber string, sum float64) error {
 	m.ctrl.T.Helper()
@@ -214,55 +406,852 @@ func (mr *MockWithdrawalsStorageMockRecorder) Withdraw(ctx, userID, orderNumber,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Withdraw", reflect.TypeOf((*MockWithdrawalsStorage)(nil).Withdraw), ctx, userID, orderNumber, sum)
 }
 
-// MockFactory is a mock of Factory interface.
-type MockFactory struct {
+// MockAdjustmentsStorage is a mock of AdjustmentsStorage interface.
+type MockAdjustmentsStorage struct {
 	ctrl     *gomock.Controller
-	recorder *MockFactoryMockRecorder
+	recorder *MockAdjustmentsStorageMockRecorder
 }
 
-// MockFactoryMockRecorder is the mock recorder for MockFactory.
-type MockFactoryMockRecorder struct {
-	mock *MockFactory
+// MockAdjustmentsStorageMockRecorder is the mock recorder for MockAdjustmentsStorage.
+type MockAdjustmentsStorageMockRecorder struct {
+	mock *MockAdjustmentsStorage
 }
 
-// NewMockFactory creates a new mock instance.
-func NewMockFactory(ctrl *gomock.Controller) *MockFactory {
-	mock := &MockFactory{ctrl: ctrl}
-	mock.recorder = &MockFactoryMockRecorder{mock}
+// NewMockAdjustmentsStorage creates a new mock instance.
+func NewMockAdjustmentsStorage(ctrl *gomock.Controller) *MockAdjustmentsStorage {
+	mock := &MockAdjustmentsStorage{ctrl: ctrl}
+	mock.recorder = &MockAdjustmentsStorageMockRecorder{mock}
 	return mock
 }
 
 // EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockFactory) EXPECT() *MockFactoryMockRecorder {
+func (m *MockAdjustmentsStorage) EXPECT() *MockAdjustmentsStorageMockRecorder {
 	return m.recorder
 }
 
-// CreateOrdersStorage mocks base method.
-func (m *MockFactory) CreateOrdersStorage() storage.OrdersStorage {
+// ApproveAdjustment mocks base method.
+func (m *MockAdjustmentsStorage) ApproveAdjustment(ctx context.Context, ID, adminID int64) (models.BalanceAdjustment, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateOrdersStorage")
-	ret0, _ := ret[0].(storage.OrdersStorage)
-	return ret0
+	ret := m.ctrl.Call(m, "ApproveAdjustment", ctx, ID, adminID)
+	ret0, _ := ret[0].(models.BalanceAdjustment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// CreateOrdersStorage indicates an expected call of CreateOrdersStorage.
-func (mr *MockFactoryMockRecorder) CreateOrdersStorage() *gomock.Call {
+// ApproveAdjustment indicates an expected call of ApproveAdjustment.
+func (mr *MockAdjustmentsStorageMockRecorder) ApproveAdjustment(ctx, ID, adminID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOrdersStorage", reflect.TypeOf((*MockFactory)(nil).CreateOrdersStorage))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApproveAdjustment", reflect.TypeOf((*MockAdjustmentsStorage)(nil).ApproveAdjustment), ctx, ID, adminID)
 }
 
-// CreateUsersStorage mocks base method.
-func (m *MockFactory) CreateUsersStorage() storage.UsersStorage {
+// CreateAdjustment mocks base method.
+func (m *MockAdjustmentsStorage) CreateAdjustment(ctx context.Context, adjustment models.BalanceAdjustment, requireApproval bool) (models.BalanceAdjustment, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateUsersStorage")
-	ret0, _ := ret[0].(storage.UsersStorage)
-	return ret0
+	ret := m.ctrl.Call(m, "CreateAdjustment", ctx, adjustment, requireApproval)
+	ret0, _ := ret[0].(models.BalanceAdjustment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// CreateUsersStorage indicates an expected call of CreateUsersStorage.
-func (mr *MockFactoryMockRecorder) CreateUsersStorage() *gomock.Call {
+// CreateAdjustment indicates an expected call of CreateAdjustment.
+func (mr *MockAdjustmentsStorageMockRecorder) CreateAdjustment(ctx, adjustment, requireApproval interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUsersStorage", reflect.TypeOf((*MockFactory)(nil).CreateUsersStorage))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAdjustment", reflect.TypeOf((*MockAdjustmentsStorage)(nil).CreateAdjustment), ctx, adjustment, requireApproval)
+}
+
+// GetUserAdjustments mocks base method.
+func (m *MockAdjustmentsStorage) GetUserAdjustments(ctx context.Context, userID int64) ([]models.BalanceAdjustment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserAdjustments", ctx, userID)
+	ret0, _ := ret[0].([]models.BalanceAdjustment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserAdjustments indicates an expected call of GetUserAdjustments.
+func (mr *MockAdjustmentsStorageMockRecorder) GetUserAdjustments(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserAdjustments", reflect.TypeOf((*MockAdjustmentsStorage)(nil).GetUserAdjustments), ctx, userID)
+}
+
+// RejectAdjustment mocks base method.
+func (m *MockAdjustmentsStorage) RejectAdjustment(ctx context.Context, ID, adminID int64) (models.BalanceAdjustment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RejectAdjustment", ctx, ID, adminID)
+	ret0, _ := ret[0].(models.BalanceAdjustment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RejectAdjustment indicates an expected call of RejectAdjustment.
+func (mr *MockAdjustmentsStorageMockRecorder) RejectAdjustment(ctx, ID, adminID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RejectAdjustment", reflect.TypeOf((*MockAdjustmentsStorage)(nil).RejectAdjustment), ctx, ID, adminID)
+}
+
+// MockHoldsStorage is a mock of HoldsStorage interface.
+type MockHoldsStorage struct {
+	ctrl     *gomock.Controller
+	recorder *MockHoldsStorageMockRecorder
+}
+
+// MockHoldsStorageMockRecorder is the mock recorder for MockHoldsStorage.
+type MockHoldsStorageMockRecorder struct {
+	mock *MockHoldsStorage
+}
+
+// NewMockHoldsStorage creates a new mock instance.
+func NewMockHoldsStorage(ctrl *gomock.Controller) *MockHoldsStorage {
+	mock := &MockHoldsStorage{ctrl: ctrl}
+	mock.recorder = &MockHoldsStorageMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockHoldsStorage) EXPECT() *MockHoldsStorageMockRecorder {
+	return m.recorder
+}
+
+// CaptureHold mocks base method.
+func (m *MockHoldsStorage) CaptureHold(ctx context.Context, userID, ID int64) (models.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CaptureHold", ctx, userID, ID)
+	ret0, _ := ret[0].(models.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CaptureHold indicates an expected call of CaptureHold.
+func (mr *MockHoldsStorageMockRecorder) CaptureHold(ctx, userID, ID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CaptureHold", reflect.TypeOf((*MockHoldsStorage)(nil).CaptureHold), ctx, userID, ID)
+}
+
+// CreateHold mocks base method.
+func (m *MockHoldsStorage) CreateHold(ctx context.Context, userID int64, orderNumber string, amount float64, expiresAt time.Time) (models.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateHold", ctx, userID, orderNumber, amount, expiresAt)
+	ret0, _ := ret[0].(models.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateHold indicates an expected call of CreateHold.
+func (mr *MockHoldsStorageMockRecorder) CreateHold(ctx, userID, orderNumber, amount, expiresAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateHold", reflect.TypeOf((*MockHoldsStorage)(nil).CreateHold), ctx, userID, orderNumber, amount, expiresAt)
+}
+
+// ReleaseExpiredHolds mocks base method.
+func (m *MockHoldsStorage) ReleaseExpiredHolds(ctx context.Context, now time.Time, limit int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseExpiredHolds", ctx, now, limit)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReleaseExpiredHolds indicates an expected call of ReleaseExpiredHolds.
+func (mr *MockHoldsStorageMockRecorder) ReleaseExpiredHolds(ctx, now, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseExpiredHolds", reflect.TypeOf((*MockHoldsStorage)(nil).ReleaseExpiredHolds), ctx, now, limit)
+}
+
+// ReleaseHold mocks base method.
+func (m *MockHoldsStorage) ReleaseHold(ctx context.Context, userID, ID int64) (models.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseHold", ctx, userID, ID)
+	ret0, _ := ret[0].(models.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReleaseHold indicates an expected call of ReleaseHold.
+func (mr *MockHoldsStorageMockRecorder) ReleaseHold(ctx, userID, ID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseHold", reflect.TypeOf((*MockHoldsStorage)(nil).ReleaseHold), ctx, userID, ID)
+}
+
+// MockTransfersStorage is a mock of TransfersStorage interface.
+type MockTransfersStorage struct {
+	ctrl     *gomock.Controller
+	recorder *MockTransfersStorageMockRecorder
+}
+
+// MockTransfersStorageMockRecorder is the mock recorder for MockTransfersStorage.
+type MockTransfersStorageMockRecorder struct {
+	mock *MockTransfersStorage
+}
+
+// NewMockTransfersStorage creates a new mock instance.
+func NewMockTransfersStorage(ctrl *gomock.Controller) *MockTransfersStorage {
+	mock := &MockTransfersStorage{ctrl: ctrl}
+	mock.recorder = &MockTransfersStorageMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTransfersStorage) EXPECT() *MockTransfersStorageMockRecorder {
+	return m.recorder
+}
+
+// Transfer mocks base method.
+func (m *MockTransfersStorage) Transfer(ctx context.Context, fromUserID int64, toLogin string, amount, dailyLimit float64) (models.Transfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Transfer", ctx, fromUserID, toLogin, amount, dailyLimit)
+	ret0, _ := ret[0].(models.Transfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Transfer indicates an expected call of Transfer.
+func (mr *MockTransfersStorageMockRecorder) Transfer(ctx, fromUserID, toLogin, amount, dailyLimit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transfer", reflect.TypeOf((*MockTransfersStorage)(nil).Transfer), ctx, fromUserID, toLogin, amount, dailyLimit)
+}
+
+// MockPointsStorage is a mock of PointsStorage interface.
+type MockPointsStorage struct {
+	ctrl     *gomock.Controller
+	recorder *MockPointsStorageMockRecorder
+}
+
+// MockPointsStorageMockRecorder is the mock recorder for MockPointsStorage.
+type MockPointsStorageMockRecorder struct {
+	mock *MockPointsStorage
+}
+
+// NewMockPointsStorage creates a new mock instance.
+func NewMockPointsStorage(ctrl *gomock.Controller) *MockPointsStorage {
+	mock := &MockPointsStorage{ctrl: ctrl}
+	mock.recorder = &MockPointsStorageMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPointsStorage) EXPECT() *MockPointsStorageMockRecorder {
+	return m.recorder
+}
+
+// ExpireLots mocks base method.
+func (m *MockPointsStorage) ExpireLots(ctx context.Context, now time.Time, limit int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExpireLots", ctx, now, limit)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExpireLots indicates an expected call of ExpireLots.
+func (mr *MockPointsStorageMockRecorder) ExpireLots(ctx, now, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExpireLots", reflect.TypeOf((*MockPointsStorage)(nil).ExpireLots), ctx, now, limit)
+}
+
+// GetExpiringPoints mocks base method.
+func (m *MockPointsStorage) GetExpiringPoints(ctx context.Context, userID int64, until time.Time) ([]models.ExpiringPoints, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetExpiringPoints", ctx, userID, until)
+	ret0, _ := ret[0].([]models.ExpiringPoints)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetExpiringPoints indicates an expected call of GetExpiringPoints.
+func (mr *MockPointsStorageMockRecorder) GetExpiringPoints(ctx, userID, until interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExpiringPoints", reflect.TypeOf((*MockPointsStorage)(nil).GetExpiringPoints), ctx, userID, until)
+}
+
+// ReleasePendingAccruals mocks base method.
+func (m *MockPointsStorage) ReleasePendingAccruals(ctx context.Context, now time.Time, limit int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleasePendingAccruals", ctx, now, limit)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReleasePendingAccruals indicates an expected call of ReleasePendingAccruals.
+func (mr *MockPointsStorageMockRecorder) ReleasePendingAccruals(ctx, now, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleasePendingAccruals", reflect.TypeOf((*MockPointsStorage)(nil).ReleasePendingAccruals), ctx, now, limit)
+}
+
+// MockAuditStorage is a mock of AuditStorage interface.
+type MockAuditStorage struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuditStorageMockRecorder
+}
+
+// MockAuditStorageMockRecorder is the mock recorder for MockAuditStorage.
+type MockAuditStorageMockRecorder struct {
+	mock *MockAuditStorage
+}
+
+// NewMockAuditStorage creates a new mock instance.
+func NewMockAuditStorage(ctrl *gomock.Controller) *MockAuditStorage {
+	mock := &MockAuditStorage{ctrl: ctrl}
+	mock.recorder = &MockAuditStorageMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAuditStorage) EXPECT() *MockAuditStorageMockRecorder {
+	return m.recorder
+}
+
+// FindAuditEvents mocks base method.
+func (m *MockAuditStorage) FindAuditEvents(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindAuditEvents", ctx, filter)
+	ret0, _ := ret[0].([]models.AuditEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindAuditEvents indicates an expected call of FindAuditEvents.
+func (mr *MockAuditStorageMockRecorder) FindAuditEvents(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAuditEvents", reflect.TypeOf((*MockAuditStorage)(nil).FindAuditEvents), ctx, filter)
+}
+
+// RecordAuditEvent mocks base method.
+func (m *MockAuditStorage) RecordAuditEvent(ctx context.Context, e models.AuditEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordAuditEvent", ctx, e)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordAuditEvent indicates an expected call of RecordAuditEvent.
+func (mr *MockAuditStorageMockRecorder) RecordAuditEvent(ctx, e interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordAuditEvent", reflect.TypeOf((*MockAuditStorage)(nil).RecordAuditEvent), ctx, e)
+}
+
+// MockStatementsStorage is a mock of StatementsStorage interface.
+type MockStatementsStorage struct {
+	ctrl     *gomock.Controller
+	recorder *MockStatementsStorageMockRecorder
+}
+
+// MockStatementsStorageMockRecorder is the mock recorder for MockStatementsStorage.
+type MockStatementsStorageMockRecorder struct {
+	mock *MockStatementsStorage
+}
+
+// NewMockStatementsStorage creates a new mock instance.
+func NewMockStatementsStorage(ctrl *gomock.Controller) *MockStatementsStorage {
+	mock := &MockStatementsStorage{ctrl: ctrl}
+	mock.recorder = &MockStatementsStorageMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStatementsStorage) EXPECT() *MockStatementsStorageMockRecorder {
+	return m.recorder
+}
+
+// StreamUserStatement mocks base method.
+func (m *MockStatementsStorage) StreamUserStatement(ctx context.Context, userID int64, from, to *time.Time, fn func(models.StatementEntry) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StreamUserStatement", ctx, userID, from, to, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StreamUserStatement indicates an expected call of StreamUserStatement.
+func (mr *MockStatementsStorageMockRecorder) StreamUserStatement(ctx, userID, from, to, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamUserStatement", reflect.TypeOf((*MockStatementsStorage)(nil).StreamUserStatement), ctx, userID, from, to, fn)
+}
+
+// MockEventsStorage is a mock of EventsStorage interface.
+type MockEventsStorage struct {
+	ctrl     *gomock.Controller
+	recorder *MockEventsStorageMockRecorder
+}
+
+// MockEventsStorageMockRecorder is the mock recorder for MockEventsStorage.
+type MockEventsStorageMockRecorder struct {
+	mock *MockEventsStorage
+}
+
+// NewMockEventsStorage creates a new mock instance.
+func NewMockEventsStorage(ctrl *gomock.Controller) *MockEventsStorage {
+	mock := &MockEventsStorage{ctrl: ctrl}
+	mock.recorder = &MockEventsStorageMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockEventsStorage) EXPECT() *MockEventsStorageMockRecorder {
+	return m.recorder
+}
+
+// GetUserEventsAfter mocks base method.
+func (m *MockEventsStorage) GetUserEventsAfter(ctx context.Context, userID, afterID int64) ([]events.Event, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserEventsAfter", ctx, userID, afterID)
+	ret0, _ := ret[0].([]events.Event)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserEventsAfter indicates an expected call of GetUserEventsAfter.
+func (mr *MockEventsStorageMockRecorder) GetUserEventsAfter(ctx, userID, afterID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserEventsAfter", reflect.TypeOf((*MockEventsStorage)(nil).GetUserEventsAfter), ctx, userID, afterID)
+}
+
+// Listen mocks base method.
+func (m *MockEventsStorage) Listen(ctx context.Context, handler func(events.Event)) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Listen", ctx, handler)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Listen indicates an expected call of Listen.
+func (mr *MockEventsStorageMockRecorder) Listen(ctx, handler interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Listen", reflect.TypeOf((*MockEventsStorage)(nil).Listen), ctx, handler)
+}
+
+// MockWebhooksStorage is a mock of WebhooksStorage interface.
+type MockWebhooksStorage struct {
+	ctrl     *gomock.Controller
+	recorder *MockWebhooksStorageMockRecorder
+}
+
+// MockWebhooksStorageMockRecorder is the mock recorder for MockWebhooksStorage.
+type MockWebhooksStorageMockRecorder struct {
+	mock *MockWebhooksStorage
+}
+
+// NewMockWebhooksStorage creates a new mock instance.
+func NewMockWebhooksStorage(ctrl *gomock.Controller) *MockWebhooksStorage {
+	mock := &MockWebhooksStorage{ctrl: ctrl}
+	mock.recorder = &MockWebhooksStorageMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWebhooksStorage) EXPECT() *MockWebhooksStorageMockRecorder {
+	return m.recorder
+}
+
+// ClaimDueDeliveries mocks base method.
+func (m *MockWebhooksStorage) ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimDueDeliveries", ctx, now, lease, limit)
+	ret0, _ := ret[0].([]models.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimDueDeliveries indicates an expected call of ClaimDueDeliveries.
+func (mr *MockWebhooksStorageMockRecorder) ClaimDueDeliveries(ctx, now, lease, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimDueDeliveries", reflect.TypeOf((*MockWebhooksStorage)(nil).ClaimDueDeliveries), ctx, now, lease, limit)
+}
+
+// CreateEndpoint mocks base method.
+func (m *MockWebhooksStorage) CreateEndpoint(ctx context.Context, endpoint models.WebhookEndpoint) (models.WebhookEndpoint, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateEndpoint", ctx, endpoint)
+	ret0, _ := ret[0].(models.WebhookEndpoint)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateEndpoint indicates an expected call of CreateEndpoint.
+func (mr *MockWebhooksStorageMockRecorder) CreateEndpoint(ctx, endpoint interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateEndpoint", reflect.TypeOf((*MockWebhooksStorage)(nil).CreateEndpoint), ctx, endpoint)
+}
+
+// DeleteEndpoint mocks base method.
+func (m *MockWebhooksStorage) DeleteEndpoint(ctx context.Context, userID, ID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteEndpoint", ctx, userID, ID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteEndpoint indicates an expected call of DeleteEndpoint.
+func (mr *MockWebhooksStorageMockRecorder) DeleteEndpoint(ctx, userID, ID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteEndpoint", reflect.TypeOf((*MockWebhooksStorage)(nil).DeleteEndpoint), ctx, userID, ID)
+}
+
+// DeleteGlobalEndpoint mocks base method.
+func (m *MockWebhooksStorage) DeleteGlobalEndpoint(ctx context.Context, ID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteGlobalEndpoint", ctx, ID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteGlobalEndpoint indicates an expected call of DeleteGlobalEndpoint.
+func (mr *MockWebhooksStorageMockRecorder) DeleteGlobalEndpoint(ctx, ID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteGlobalEndpoint", reflect.TypeOf((*MockWebhooksStorage)(nil).DeleteGlobalEndpoint), ctx, ID)
+}
+
+// GetEndpointAttempts mocks base method.
+func (m *MockWebhooksStorage) GetEndpointAttempts(ctx context.Context, userID, endpointID int64, limit int) ([]models.WebhookDeliveryAttempt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetEndpointAttempts", ctx, userID, endpointID, limit)
+	ret0, _ := ret[0].([]models.WebhookDeliveryAttempt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetEndpointAttempts indicates an expected call of GetEndpointAttempts.
+func (mr *MockWebhooksStorageMockRecorder) GetEndpointAttempts(ctx, userID, endpointID, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEndpointAttempts", reflect.TypeOf((*MockWebhooksStorage)(nil).GetEndpointAttempts), ctx, userID, endpointID, limit)
+}
+
+// GetGlobalEndpoints mocks base method.
+func (m *MockWebhooksStorage) GetGlobalEndpoints(ctx context.Context) ([]models.WebhookEndpoint, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetGlobalEndpoints", ctx)
+	ret0, _ := ret[0].([]models.WebhookEndpoint)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetGlobalEndpoints indicates an expected call of GetGlobalEndpoints.
+func (mr *MockWebhooksStorageMockRecorder) GetGlobalEndpoints(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetGlobalEndpoints", reflect.TypeOf((*MockWebhooksStorage)(nil).GetGlobalEndpoints), ctx)
+}
+
+// GetUserEndpoints mocks base method.
+func (m *MockWebhooksStorage) GetUserEndpoints(ctx context.Context, userID int64) ([]models.WebhookEndpoint, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserEndpoints", ctx, userID)
+	ret0, _ := ret[0].([]models.WebhookEndpoint)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserEndpoints indicates an expected call of GetUserEndpoints.
+func (mr *MockWebhooksStorageMockRecorder) GetUserEndpoints(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserEndpoints", reflect.TypeOf((*MockWebhooksStorage)(nil).GetUserEndpoints), ctx, userID)
+}
+
+// RecordDeliveryAttempt mocks base method.
+func (m *MockWebhooksStorage) RecordDeliveryAttempt(ctx context.Context, attempt models.WebhookDeliveryAttempt, status string, nextAttemptAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordDeliveryAttempt", ctx, attempt, status, nextAttemptAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordDeliveryAttempt indicates an expected call of RecordDeliveryAttempt.
+func (mr *MockWebhooksStorageMockRecorder) RecordDeliveryAttempt(ctx, attempt, status, nextAttemptAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordDeliveryAttempt", reflect.TypeOf((*MockWebhooksStorage)(nil).RecordDeliveryAttempt), ctx, attempt, status, nextAttemptAt)
+}
+
+// MockAccrualResultsStorage is a mock of AccrualResultsStorage interface.
+type MockAccrualResultsStorage struct {
+	ctrl     *gomock.Controller
+	recorder *MockAccrualResultsStorageMockRecorder
+}
+
+// MockAccrualResultsStorageMockRecorder is the mock recorder for MockAccrualResultsStorage.
+type MockAccrualResultsStorageMockRecorder struct {
+	mock *MockAccrualResultsStorage
+}
+
+// NewMockAccrualResultsStorage creates a new mock instance.
+func NewMockAccrualResultsStorage(ctrl *gomock.Controller) *MockAccrualResultsStorage {
+	mock := &MockAccrualResultsStorage{ctrl: ctrl}
+	mock.recorder = &MockAccrualResultsStorageMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAccrualResultsStorage) EXPECT() *MockAccrualResultsStorageMockRecorder {
+	return m.recorder
+}
+
+// DeleteAccrualResult mocks base method.
+func (m *MockAccrualResultsStorage) DeleteAccrualResult(ctx context.Context, r models.AccrualResult) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAccrualResult", ctx, r)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAccrualResult indicates an expected call of DeleteAccrualResult.
+func (mr *MockAccrualResultsStorageMockRecorder) DeleteAccrualResult(ctx, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAccrualResult", reflect.TypeOf((*MockAccrualResultsStorage)(nil).DeleteAccrualResult), ctx, r)
+}
+
+// GetAccrualResult mocks base method.
+func (m *MockAccrualResultsStorage) GetAccrualResult(ctx context.Context, number string) (models.AccrualResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAccrualResult", ctx, number)
+	ret0, _ := ret[0].(models.AccrualResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccrualResult indicates an expected call of GetAccrualResult.
+func (mr *MockAccrualResultsStorageMockRecorder) GetAccrualResult(ctx, number interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccrualResult", reflect.TypeOf((*MockAccrualResultsStorage)(nil).GetAccrualResult), ctx, number)
+}
+
+// SaveAccrualResult mocks base method.
+func (m *MockAccrualResultsStorage) SaveAccrualResult(ctx context.Context, r models.AccrualResult) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveAccrualResult", ctx, r)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveAccrualResult indicates an expected call of SaveAccrualResult.
+func (mr *MockAccrualResultsStorageMockRecorder) SaveAccrualResult(ctx, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveAccrualResult", reflect.TypeOf((*MockAccrualResultsStorage)(nil).SaveAccrualResult), ctx, r)
+}
+
+// MockLocksStorage is a mock of LocksStorage interface.
+type MockLocksStorage struct {
+	ctrl     *gomock.Controller
+	recorder *MockLocksStorageMockRecorder
+}
+
+// MockLocksStorageMockRecorder is the mock recorder for MockLocksStorage.
+type MockLocksStorageMockRecorder struct {
+	mock *MockLocksStorage
+}
+
+// NewMockLocksStorage creates a new mock instance.
+func NewMockLocksStorage(ctrl *gomock.Controller) *MockLocksStorage {
+	mock := &MockLocksStorage{ctrl: ctrl}
+	mock.recorder = &MockLocksStorageMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLocksStorage) EXPECT() *MockLocksStorageMockRecorder {
+	return m.recorder
+}
+
+// RunWithLock mocks base method.
+func (m *MockLocksStorage) RunWithLock(ctx context.Context, key int64, checkInterval time.Duration, fn func(context.Context)) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RunWithLock", ctx, key, checkInterval, fn)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RunWithLock indicates an expected call of RunWithLock.
+func (mr *MockLocksStorageMockRecorder) RunWithLock(ctx, key, checkInterval, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunWithLock", reflect.TypeOf((*MockLocksStorage)(nil).RunWithLock), ctx, key, checkInterval, fn)
+}
+
+// MockFactory is a mock of Factory interface.
+type MockFactory struct {
+	ctrl     *gomock.Controller
+	recorder *MockFactoryMockRecorder
+}
+
+// MockFactoryMockRecorder is the mock recorder for MockFactory.
+type MockFactoryMockRecorder struct {
+	mock *MockFactory
+}
+
+// NewMockFactory creates a new mock instance.
+func NewMockFactory(ctrl *gomock.Controller) *MockFactory {
+	mock := &MockFactory{ctrl: ctrl}
+	mock.recorder = &MockFactoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockFactory) EXPECT() *MockFactoryMockRecorder {
+	return m.recorder
+}
+
+// CreateAccrualResultsStorage mocks base method.
+func (m *MockFactory) CreateAccrualResultsStorage() storage.AccrualResultsStorage {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAccrualResultsStorage")
+	ret0, _ := ret[0].(storage.AccrualResultsStorage)
+	return ret0
+}
+
+// CreateAccrualResultsStorage indicates an expected call of CreateAccrualResultsStorage.
+func (mr *MockFactoryMockRecorder) CreateAccrualResultsStorage() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAccrualResultsStorage", reflect.TypeOf((*MockFactory)(nil).CreateAccrualResultsStorage))
+}
+
+// CreateAdjustmentsStorage mocks base method.
+func (m *MockFactory) CreateAdjustmentsStorage() storage.AdjustmentsStorage {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAdjustmentsStorage")
+	ret0, _ := ret[0].(storage.AdjustmentsStorage)
+	return ret0
+}
+
+// CreateAdjustmentsStorage indicates an expected call of CreateAdjustmentsStorage.
+func (mr *MockFactoryMockRecorder) CreateAdjustmentsStorage() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAdjustmentsStorage", reflect.TypeOf((*MockFactory)(nil).CreateAdjustmentsStorage))
+}
+
+// CreateAuditStorage mocks base method.
+func (m *MockFactory) CreateAuditStorage() storage.AuditStorage {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAuditStorage")
+	ret0, _ := ret[0].(storage.AuditStorage)
+	return ret0
+}
+
+// CreateAuditStorage indicates an expected call of CreateAuditStorage.
+func (mr *MockFactoryMockRecorder) CreateAuditStorage() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAuditStorage", reflect.TypeOf((*MockFactory)(nil).CreateAuditStorage))
+}
+
+// CreateEventsStorage mocks base method.
+func (m *MockFactory) CreateEventsStorage() storage.EventsStorage {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateEventsStorage")
+	ret0, _ := ret[0].(storage.EventsStorage)
+	return ret0
+}
+
+// CreateEventsStorage indicates an expected call of CreateEventsStorage.
+func (mr *MockFactoryMockRecorder) CreateEventsStorage() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateEventsStorage", reflect.TypeOf((*MockFactory)(nil).CreateEventsStorage))
+}
+
+// CreateHoldsStorage mocks base method.
+func (m *MockFactory) CreateHoldsStorage() storage.HoldsStorage {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateHoldsStorage")
+	ret0, _ := ret[0].(storage.HoldsStorage)
+	return ret0
+}
+
+// CreateHoldsStorage indicates an expected call of CreateHoldsStorage.
+func (mr *MockFactoryMockRecorder) CreateHoldsStorage() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateHoldsStorage", reflect.TypeOf((*MockFactory)(nil).CreateHoldsStorage))
+}
+
+// CreateLocksStorage mocks base method.
+func (m *MockFactory) CreateLocksStorage() storage.LocksStorage {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateLocksStorage")
+	ret0, _ := ret[0].(storage.LocksStorage)
+	return ret0
+}
+
+// CreateLocksStorage indicates an expected call of CreateLocksStorage.
+func (mr *MockFactoryMockRecorder) CreateLocksStorage() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateLocksStorage", reflect.TypeOf((*MockFactory)(nil).CreateLocksStorage))
+}
+
+// CreateOrdersStorage mocks base method.
+func (m *MockFactory) CreateOrdersStorage() storage.OrdersStorage {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOrdersStorage")
+	ret0, _ := ret[0].(storage.OrdersStorage)
+	return ret0
+}
+
+// CreateOrdersStorage indicates an expected call of CreateOrdersStorage.
+func (mr *MockFactoryMockRecorder) CreateOrdersStorage() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOrdersStorage", reflect.TypeOf((*MockFactory)(nil).CreateOrdersStorage))
+}
+
+// CreatePointsStorage mocks base method.
+func (m *MockFactory) CreatePointsStorage() storage.PointsStorage {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePointsStorage")
+	ret0, _ := ret[0].(storage.PointsStorage)
+	return ret0
+}
+
+// CreatePointsStorage indicates an expected call of CreatePointsStorage.
+func (mr *MockFactoryMockRecorder) CreatePointsStorage() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePointsStorage", reflect.TypeOf((*MockFactory)(nil).CreatePointsStorage))
+}
+
+// CreateStatementsStorage mocks base method.
+func (m *MockFactory) CreateStatementsStorage() storage.StatementsStorage {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateStatementsStorage")
+	ret0, _ := ret[0].(storage.StatementsStorage)
+	return ret0
+}
+
+// CreateStatementsStorage indicates an expected call of CreateStatementsStorage.
+func (mr *MockFactoryMockRecorder) CreateStatementsStorage() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateStatementsStorage", reflect.TypeOf((*MockFactory)(nil).CreateStatementsStorage))
+}
+
+// CreateTransfersStorage mocks base method.
+func (m *MockFactory) CreateTransfersStorage() storage.TransfersStorage {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateTransfersStorage")
+	ret0, _ := ret[0].(storage.TransfersStorage)
+	return ret0
+}
+
+// CreateTransfersStorage indicates an expected call of CreateTransfersStorage.
+func (mr *MockFactoryMockRecorder) CreateTransfersStorage() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTransfersStorage", reflect.TypeOf((*MockFactory)(nil).CreateTransfersStorage))
+}
+
+// CreateUsersStorage mocks base method.
+func (m *MockFactory) CreateUsersStorage() storage.UsersStorage {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUsersStorage")
+	ret0, _ := ret[0].(storage.UsersStorage)
+	return ret0
+}
+
+// CreateUsersStorage indicates an expected call of CreateUsersStorage.
+func (mr *MockFactoryMockRecorder) CreateUsersStorage() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUsersStorage", reflect.TypeOf((*MockFactory)(nil).CreateUsersStorage))
+}
+
+// CreateWebhooksStorage mocks base method.
+func (m *MockFactory) CreateWebhooksStorage() storage.WebhooksStorage {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWebhooksStorage")
+	ret0, _ := ret[0].(storage.WebhooksStorage)
+	return ret0
+}
+
+// CreateWebhooksStorage indicates an expected call of CreateWebhooksStorage.
+func (mr *MockFactoryMockRecorder) CreateWebhooksStorage() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWebhooksStorage", reflect.TypeOf((*MockFactory)(nil).CreateWebhooksStorage))
 }
 
 // CreateWithdrawalsStorage mocks base method.
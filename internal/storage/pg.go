@@ -4,11 +4,13 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5/pgconn"
 
+	"github.com/bobgromozeka/yp-diploma1/internal/events"
 	"github.com/bobgromozeka/yp-diploma1/internal/hash"
 	"github.com/bobgromozeka/yp-diploma1/internal/models"
 )
@@ -29,6 +31,46 @@ type PgWithdrawalsStorage struct {
 	db *sql.DB
 }
 
+type PgAdjustmentsStorage struct {
+	db *sql.DB
+}
+
+type PgHoldsStorage struct {
+	db *sql.DB
+}
+
+type PgTransfersStorage struct {
+	db *sql.DB
+}
+
+type PgPointsStorage struct {
+	db *sql.DB
+}
+
+type PgAuditStorage struct {
+	db *sql.DB
+}
+
+type PgStatementsStorage struct {
+	db *sql.DB
+}
+
+type PgEventsStorage struct {
+	db *sql.DB
+}
+
+type PgWebhooksStorage struct {
+	db *sql.DB
+}
+
+type PgAccrualResultsStorage struct {
+	db *sql.DB
+}
+
+type PgLocksStorage struct {
+	db *sql.DB
+}
+
 type PgFactory struct {
 	db *sql.DB
 }
@@ -51,6 +93,46 @@ func (f PgFactory) CreateWithdrawalsStorage() WithdrawalsStorage {
 	return PgWithdrawalsStorage(f)
 }
 
+func (f PgFactory) CreateAdjustmentsStorage() AdjustmentsStorage {
+	return PgAdjustmentsStorage(f)
+}
+
+func (f PgFactory) CreateHoldsStorage() HoldsStorage {
+	return PgHoldsStorage(f)
+}
+
+func (f PgFactory) CreateTransfersStorage() TransfersStorage {
+	return PgTransfersStorage(f)
+}
+
+func (f PgFactory) CreatePointsStorage() PointsStorage {
+	return PgPointsStorage(f)
+}
+
+func (f PgFactory) CreateAuditStorage() AuditStorage {
+	return PgAuditStorage(f)
+}
+
+func (f PgFactory) CreateAccrualResultsStorage() AccrualResultsStorage {
+	return PgAccrualResultsStorage(f)
+}
+
+func (f PgFactory) CreateLocksStorage() LocksStorage {
+	return PgLocksStorage(f)
+}
+
+func (f PgFactory) CreateStatementsStorage() StatementsStorage {
+	return PgStatementsStorage(f)
+}
+
+func (f PgFactory) CreateEventsStorage() EventsStorage {
+	return PgEventsStorage(f)
+}
+
+func (f PgFactory) CreateWebhooksStorage() WebhooksStorage {
+	return PgWebhooksStorage(f)
+}
+
 func (s PgUsersStorage) CreateUser(ctx context.Context, login string, password string) error {
 	tx, txErr := s.db.BeginTx(ctx, nil)
 	if txErr != nil {
@@ -78,28 +160,170 @@ func (s PgUsersStorage) CreateUser(ctx context.Context, login string, password s
 		return balanceErr
 	}
 
+	auditErr := insertAuditEvent(
+		ctx, tx, models.AuditEvent{
+			Action:  models.AuditUserRegistered,
+			ActorID: &userLastInsertedID,
+			UserID:  &userLastInsertedID,
+			Login:   login,
+		},
+	)
+	if auditErr != nil {
+		return auditErr
+	}
+
 	tx.Commit()
 	return nil
 }
 
-func (s PgUsersStorage) AuthUser(ctx context.Context, login string, password string) (int64, error) {
+func (s PgUsersStorage) AuthUser(ctx context.Context, login string, password string) (models.User, error) {
 	hashedPwd := hash.Sha256([]byte(password))
-	row := s.db.QueryRowContext(ctx, "select id from users where login = $1 and password = $2", login, hashedPwd)
+	row := s.db.QueryRowContext(
+		ctx, "select id, login, role, store from users where login = $1 and password = $2", login, hashedPwd,
+	)
 
-	if row.Err() != nil {
-		return 0, row.Err()
+	return scanUser(row)
+}
+
+func (s PgUsersStorage) GetUser(ctx context.Context, ID int64) (models.User, error) {
+	row := s.db.QueryRowContext(ctx, "select id, login, role, store from users where id = $1", ID)
+
+	return scanUser(row)
+}
+
+func (s PgUsersStorage) FindUsers(ctx context.Context, loginPrefix string, limit int) ([]models.User, error) {
+	users := make([]models.User, 0)
+
+	rows, rowsErr := s.db.QueryContext(
+		ctx, "select id, login, role, store from users where login like $1 || '%' order by login limit $2",
+		escapeLike(loginPrefix), limit,
+	)
+	if rowsErr != nil {
+		return users, rowsErr
+	}
+	if rows.Err() != nil {
+		return users, rows.Err()
 	}
+	defer rows.Close()
 
-	var ID int64
+	for rows.Next() {
+		var u models.User
+		if scanErr := rows.Scan(&u.ID, &u.Login, &u.Role, &u.Store); scanErr != nil {
+			return users, scanErr
+		}
+		users = append(users, u)
+	}
+
+	return users, nil
+}
+
+func (s PgUsersStorage) SetUserRole(ctx context.Context, login string, role string) error {
+	result, updateErr := s.db.ExecContext(ctx, "update users set role = $1 where login = $2", role, login)
+	if updateErr != nil {
+		return updateErr
+	}
 
-	if scanErr := row.Scan(&ID); scanErr != nil {
+	affected, affectedErr := result.RowsAffected()
+	if affectedErr != nil {
+		return affectedErr
+	}
+	if affected < 1 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (s PgUsersStorage) RevokeRole(ctx context.Context, role string, keepLogins []string) ([]string, error) {
+	logins := make([]string, 0)
+	//nil slice is sent as null and "any(null)" matches nothing
+	if keepLogins == nil {
+		keepLogins = []string{}
+	}
+
+	rows, rowsErr := s.db.QueryContext(
+		ctx, "update users set role = $1 where role = $2 and not login = any($3::varchar[]) returning login",
+		models.RoleUser, role, keepLogins,
+	)
+	if rowsErr != nil {
+		return logins, rowsErr
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var login string
+		if scanErr := rows.Scan(&login); scanErr != nil {
+			return logins, scanErr
+		}
+		logins = append(logins, login)
+	}
+
+	return logins, rows.Err()
+}
+
+func (s PgUsersStorage) SetUserStore(ctx context.Context, login string, store string) error {
+	result, updateErr := s.db.ExecContext(ctx, "update users set store = $1 where login = $2", store, login)
+	if updateErr != nil {
+		return updateErr
+	}
+
+	affected, affectedErr := result.RowsAffected()
+	if affectedErr != nil {
+		return affectedErr
+	}
+	if affected < 1 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (s PgUsersStorage) UnbindStores(ctx context.Context, keepLogins []string) ([]string, error) {
+	logins := make([]string, 0)
+	//nil slice is sent as null and "any(null)" matches nothing
+	if keepLogins == nil {
+		keepLogins = []string{}
+	}
+
+	rows, rowsErr := s.db.QueryContext(
+		ctx, "update users set store = '' where store <> '' and not login = any($1::varchar[]) returning login",
+		keepLogins,
+	)
+	if rowsErr != nil {
+		return logins, rowsErr
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var login string
+		if scanErr := rows.Scan(&login); scanErr != nil {
+			return logins, scanErr
+		}
+		logins = append(logins, login)
+	}
+
+	return logins, rows.Err()
+}
+
+func scanUser(row *sql.Row) (models.User, error) {
+	var u models.User
+
+	if row.Err() != nil {
+		return u, row.Err()
+	}
+
+	if scanErr := row.Scan(&u.ID, &u.Login, &u.Role, &u.Store); scanErr != nil {
 		if errors.Is(scanErr, sql.ErrNoRows) {
-			return 0, ErrUserNotFound
+			return u, ErrUserNotFound
 		}
-		return 0, scanErr
+		return u, scanErr
 	}
 
-	return ID, nil
+	return u, nil
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(value)
 }
 
 func (s PgOrdersStorage) CreateOrder(ctx context.Context, number string, userID int64) error {
@@ -120,18 +344,102 @@ func (s PgOrdersStorage) CreateOrder(ctx context.Context, number string, userID
 	}
 
 	_, createErr := tx.ExecContext(
-		ctx, "insert into orders(user_id, number, status, uploaded_at) values($1,$2,$3,$4)", userID, number,
-		models.OrderFirstStatus, time.Now(),
+		ctx, "insert into orders(user_id, number, status, uploaded_at, check_requested_at) values($1,$2,$3,$4,$4)",
+		userID, number, models.OrderFirstStatus, time.Now(),
 	)
 	if createErr != nil {
 		return createErr
 	}
 
+	auditErr := insertAuditEvent(
+		ctx, tx, models.AuditEvent{
+			Action:  models.AuditOrderCreated,
+			ActorID: &userID,
+			UserID:  &userID,
+			Order:   number,
+		},
+	)
+	if auditErr != nil {
+		return auditErr
+	}
+
 	tx.Commit()
 
 	return nil
 }
 
+// CreateOrders inserts all new orders with one statement and returns upload status for every number.
+func (s PgOrdersStorage) CreateOrders(ctx context.Context, numbers []string, userID int64) (map[string]string, error) {
+	statuses := make(map[string]string, len(numbers))
+
+	tx, txErr := s.db.BeginTx(ctx, nil)
+	if txErr != nil {
+		return statuses, txErr
+	}
+	defer tx.Rollback()
+
+	rows, rowsErr := tx.QueryContext(ctx, "select number, user_id from orders where number = any($1)", numbers)
+	if rowsErr != nil {
+		return statuses, rowsErr
+	}
+	if rows.Err() != nil {
+		return statuses, rows.Err()
+	}
+
+	for rows.Next() {
+		var number string
+		var ownerID int64
+		if scanErr := rows.Scan(&number, &ownerID); scanErr != nil {
+			rows.Close()
+			return statuses, scanErr
+		}
+		if ownerID == userID {
+			statuses[number] = models.OrderUploadAlreadyCreated
+		} else {
+			statuses[number] = models.OrderUploadForeign
+		}
+	}
+	rows.Close()
+
+	newNumbers := make([]string, 0, len(numbers))
+	for _, number := range numbers {
+		if _, exists := statuses[number]; !exists {
+			newNumbers = append(newNumbers, number)
+			statuses[number] = models.OrderUploadAccepted
+		}
+	}
+
+	if len(newNumbers) > 0 {
+		_, createErr := tx.ExecContext(
+			ctx,
+			`insert into orders(user_id, number, status, uploaded_at, check_requested_at)
+					select $1, unnest($2::varchar[]), $3, $4, $4`,
+			userID, newNumbers, models.OrderFirstStatus, time.Now(),
+		)
+		if createErr != nil {
+			return statuses, createErr
+		}
+
+		for _, number := range newNumbers {
+			auditErr := insertAuditEvent(
+				ctx, tx, models.AuditEvent{
+					Action:  models.AuditOrderCreated,
+					ActorID: &userID,
+					UserID:  &userID,
+					Order:   number,
+				},
+			)
+			if auditErr != nil {
+				return statuses, auditErr
+			}
+		}
+	}
+
+	tx.Commit()
+
+	return statuses, nil
+}
+
 func (s PgOrdersStorage) GetUserOrders(ctx context.Context, userID int64) ([]models.Order, error) {
 	orders := make([]models.Order, 0)
 	rows, rowsErr := s.db.QueryContext(
@@ -159,6 +467,10 @@ func (s PgOrdersStorage) GetUserOrders(ctx context.Context, userID int64) ([]mod
 	return orders, nil
 }
 
+func (s PgOrdersStorage) GetOrder(ctx context.Context, number string) (models.Order, error) {
+	return getOrder(ctx, s.db, number)
+}
+
 func (s PgOrdersStorage) GetLatestUnprocessedOrders(ctx context.Context, count int) ([]models.Order, error) {
 	orders := make([]models.Order, 0)
 	rows, rowsErr := s.db.QueryContext(
@@ -186,31 +498,142 @@ func (s PgOrdersStorage) GetLatestUnprocessedOrders(ctx context.Context, count i
 	return orders, nil
 }
 
-func (s PgOrdersStorage) UpdateOrderStatus(ctx context.Context, number string, status string, accrual *float64) error {
+// UpdateOrderStatus credits accrual to user balance or puts it on hold according to terms.
+// UpdateOrderStatus is idempotent: results for orders in final status are ignored, so the same result
+// delivered by both poller and callback is credited once. Accrual is credited only for processed orders.
+func (s PgOrdersStorage) UpdateOrderStatus(
+	ctx context.Context, number string, status string, accrual *float64, terms models.AccrualTerms,
+) error {
 	tx, txErr := s.db.BeginTx(ctx, nil)
 	if txErr != nil {
 		return txErr
 	}
 	defer tx.Rollback()
 
-	row := tx.QueryRowContext(
-		ctx, "update orders set status = $1, accrual = $2, updated_at = $3 where number = $4 returning user_id", status,
-		accrual, time.Now(), number,
-	)
+	row := tx.QueryRowContext(ctx, "select user_id, status from orders where number = $1 for update", number)
 
 	var userID int64
+	var prevStatus string
 
-	if scanErr := row.Scan(&userID); scanErr != nil {
+	if scanErr := row.Scan(&userID, &prevStatus); scanErr != nil {
+		if errors.Is(scanErr, sql.ErrNoRows) {
+			return ErrOrderNotFound
+		}
 		return scanErr
 	}
-	if accrual != nil {
-		_, balanceErr := tx.ExecContext(
-			ctx, "update user_balances set balance = balance + $1 where user_id = $2", accrual, userID,
+
+	if prevStatus == models.OrderStatusProcessed || prevStatus == models.OrderStatusInvalid {
+		return nil
+	}
+	if status != models.OrderStatusProcessed {
+		accrual = nil
+	}
+
+	_, updateErr := tx.ExecContext(
+		ctx,
+		"update orders set status = $1, accrual = $2, updated_at = $3, check_requested_at = null where number = $4",
+		status, accrual, time.Now(), number,
+	)
+	if updateErr != nil {
+		return updateErr
+	}
+
+	if prevStatus != status {
+		eventErr := insertEvent(
+			ctx, tx, userID, events.TypeOrderStatusChanged,
+			events.OrderStatusChanged{Number: number, Status: status, Accrual: accrual},
 		)
+		if eventErr != nil {
+			return eventErr
+		}
+	}
+
+	if accrual != nil {
+		var creditErr error
+		if terms.ReleaseAt != nil {
+			creditErr = holdAccrual(ctx, tx, userID, number, *accrual, terms)
+		} else {
+			creditErr = creditAccrual(ctx, tx, userID, number, *accrual, terms.ExpiresAt)
+		}
+		if creditErr != nil {
+			return creditErr
+		}
+	}
+	tx.Commit()
+
+	return nil
+}
+
+// ClaimPriorityChecks clears first check flag of given orders and returns claimed ones.
+// Orders claimed by other replica or already updated by poller are skipped.
+func (s PgOrdersStorage) ClaimPriorityChecks(ctx context.Context, numbers []string) ([]models.Order, error) {
+	return s.claimPriorityChecks(
+		ctx, `select id from orders where number = any($1::varchar[]) and check_requested_at is not null
+				for update skip locked`,
+		numbers,
+	)
+}
+
+// ClaimStalePriorityChecks claims orders which first check was requested before requestedBefore and not done yet,
+// e.g. because replica which accepted them was stopped or its queue was full.
+func (s PgOrdersStorage) ClaimStalePriorityChecks(ctx context.Context, requestedBefore time.Time, limit int) (
+	[]models.Order, error,
+) {
+	return s.claimPriorityChecks(
+		ctx, `select id from orders where check_requested_at < $1 order by check_requested_at limit $2
+				for update skip locked`,
+		requestedBefore, limit,
+	)
+}
 
-		if balanceErr != nil {
-			return balanceErr
+func (s PgOrdersStorage) claimPriorityChecks(ctx context.Context, claimQuery string, args ...any) (
+	[]models.Order, error,
+) {
+	orders := make([]models.Order, 0)
+	rows, rowsErr := s.db.QueryContext(
+		ctx,
+		`update orders set check_requested_at = null where id in (`+claimQuery+`)
+				returning id, user_id, number, status, uploaded_at, updated_at`,
+		args...,
+	)
+	if rowsErr != nil {
+		return orders, rowsErr
+	}
+	if rows.Err() != nil {
+		return orders, rows.Err()
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var o models.Order
+		if scanErr := rows.Scan(&o.ID, &o.UserID, &o.Number, &o.Status, &o.UploadedAt, &o.UpdatedAt); scanErr != nil {
+			return orders, scanErr
 		}
+		orders = append(orders, o)
+	}
+
+	return orders, nil
+}
+
+// RequeueOrder makes not finished order the first one to be polled from accrual system.
+func (s PgOrdersStorage) RequeueOrder(ctx context.Context, number string) error {
+	tx, txErr := s.db.BeginTx(ctx, nil)
+	if txErr != nil {
+		return txErr
+	}
+	defer tx.Rollback()
+
+	order, orderErr := getOrder(ctx, tx, number)
+	if orderErr != nil {
+		return orderErr
+	}
+	if order.IsFinal() {
+		return ErrOrderFinal
+	}
+
+	_, updateErr := tx.ExecContext(ctx, "update orders set updated_at = null where number = $1", number)
+	if updateErr != nil {
+		return updateErr
 	}
 	tx.Commit()
 
@@ -218,78 +641,135 @@ func (s PgOrdersStorage) UpdateOrderStatus(ctx context.Context, number string, s
 }
 
 func (s PgWithdrawalsStorage) Withdraw(ctx context.Context, userID int64, orderNumber string, sum float64) error {
+	if sum <= 0 {
+		return ErrWrongSum
+	}
+
 	tx, txErr := s.db.BeginTx(ctx, nil)
 	if txErr != nil {
 		return txErr
 	}
 	defer tx.Rollback()
 
-	balanceRow := tx.QueryRowContext(ctx, "select balance from user_balances where user_id = $1", userID)
+	balanceRow := tx.QueryRowContext(
+		ctx, "select balance, held from user_balances where user_id = $1 for update", userID,
+	)
 
-	var balance float64
+	var balance, held float64
 
-	balanceErr := balanceRow.Scan(&balance)
+	balanceErr := balanceRow.Scan(&balance, &held)
 	if balanceErr != nil {
 		return balanceErr
 	}
 
-	if balance < sum {
+	if balance-held < sum {
 		return ErrInsufficientFunds
 	}
 
-	_, withdrawErr := tx.ExecContext(
-		ctx, "insert into withdrawals(user_id, order_number, sum, processed_at) values($1,$2,$3, $4)", userID,
-		orderNumber, sum, time.Now(),
+	if withdrawErr := withdraw(ctx, tx, userID, orderNumber, sum, balance); withdrawErr != nil {
+		return withdrawErr
+	}
+	tx.Commit()
+
+	return nil
+}
+
+// withdraw spends sum from balance. User balance must be locked and checked by caller.
+func withdraw(
+	ctx context.Context, tx *sql.Tx, userID int64, orderNumber string, sum float64, balance float64,
+) error {
+	//non-positive sum would credit balance and pass any funds check
+	if sum <= 0 {
+		return ErrWrongSum
+	}
+
+	consumptions, consumeErr := consumeAccrualLots(ctx, tx, userID, sum)
+	if consumeErr != nil {
+		return consumeErr
+	}
+
+	withdrawalRow := tx.QueryRowContext(
+		ctx, "insert into withdrawals(user_id, order_number, sum, processed_at) values($1,$2,$3, $4) returning id",
+		userID, orderNumber, sum, time.Now(),
 	)
-	if withdrawErr != nil {
+	var withdrawalID int64
+	if withdrawErr := withdrawalRow.Scan(&withdrawalID); withdrawErr != nil {
 		return withdrawErr
 	}
 
+	if recordErr := recordWithdrawalConsumptions(ctx, tx, withdrawalID, consumptions); recordErr != nil {
+		return recordErr
+	}
+
 	_, updateBalanceErr := tx.ExecContext(
 		ctx, "update user_balances set balance = balance - $1 where user_id = $2", sum, userID,
 	)
 	if updateBalanceErr != nil {
 		return updateBalanceErr
 	}
-	tx.Commit()
 
-	return nil
+	balanceAfter := balance - sum
+	auditErr := insertAuditEvent(
+		ctx, tx, models.AuditEvent{
+			Action:       models.AuditBalanceWithdrawn,
+			ActorID:      &userID,
+			UserID:       &userID,
+			Order:        orderNumber,
+			AmountBefore: &balance,
+			AmountAfter:  &balanceAfter,
+		},
+	)
+	if auditErr != nil {
+		return auditErr
+	}
+
+	return insertEvent(
+		ctx, tx, userID, events.TypeBalanceWithdrawn, events.BalanceWithdrawn{Order: orderNumber, Sum: sum},
+	)
 }
 
-func (s PgWithdrawalsStorage) GetUserBalance(ctx context.Context, userID int64) (float64, float64, error) {
+func (s PgWithdrawalsStorage) GetUserBalance(ctx context.Context, userID int64) (models.Balance, error) {
+	var b models.Balance
+
 	tx, txErr := s.db.BeginTx(ctx, nil)
 	if txErr != nil {
-		return 0, 0, txErr
+		return b, txErr
 	}
+	defer tx.Rollback()
 
-	balanceRow := tx.QueryRowContext(ctx, "select balance from user_balances where user_id = $1", userID)
-
-	var balance float64
+	balanceRow := tx.QueryRowContext(
+		ctx, "select balance - held, held, pending from user_balances where user_id = $1", userID,
+	)
 
-	if scanErr := balanceRow.Scan(&balance); scanErr != nil {
-		return 0, 0, scanErr
+	if scanErr := balanceRow.Scan(&b.Current, &b.Held, &b.Pending); scanErr != nil {
+		return b, scanErr
 	}
 
-	sumRow := tx.QueryRowContext(ctx, "select sum(sum) from withdrawals where user_id = $1", userID)
+	sumRow := tx.QueryRowContext(
+		ctx, "select sum(sum) from withdrawals where user_id = $1 and reversed_at is null", userID,
+	)
 
 	var sum *float64
 
 	if scanErr := sumRow.Scan(&sum); scanErr != nil {
-		return 0, 0, scanErr
+		return b, scanErr
 	}
 
-	if sum == nil {
-		return balance, 0, nil
+	if sum != nil {
+		b.Withdrawn = *sum
 	}
 
-	return balance, *sum, nil
+	return b, nil
 }
 
 func (s PgWithdrawalsStorage) GetUserWithdrawals(ctx context.Context, userID int64) ([]models.Withdrawal, error) {
 	var withdrawals []models.Withdrawal
 
 	withdrawalRows, withdrawalsErr := s.db.QueryContext(
-		ctx, "select id, user_id, order_number, sum, processed_at from withdrawals where user_id = $1", userID,
+		ctx,
+		`select id, user_id, order_number, sum, processed_at, reversed_at, reversal_reason from withdrawals
+				where user_id = $1`,
+		userID,
 	)
 	if withdrawalsErr != nil {
 		return withdrawals, withdrawalsErr
@@ -301,7 +781,9 @@ func (s PgWithdrawalsStorage) GetUserWithdrawals(ctx context.Context, userID int
 
 	for withdrawalRows.Next() {
 		var w models.Withdrawal
-		if scanErr := withdrawalRows.Scan(&w.ID, &w.UserID, &w.OrderNumber, &w.Sum, &w.ProcessedAt); scanErr != nil {
+		if scanErr := withdrawalRows.Scan(
+			&w.ID, &w.UserID, &w.OrderNumber, &w.Sum, &w.ProcessedAt, &w.ReversedAt, &w.ReversalReason,
+		); scanErr != nil {
 			return withdrawals, scanErr
 		}
 		withdrawals = append(withdrawals, w)
@@ -348,6 +830,51 @@ func Bootstrap(db *sql.DB) error {
 		return withdrawalsTableError
 	}
 
+	eventsTableError := createEventsTable(ctx, tx)
+	if eventsTableError != nil {
+		return eventsTableError
+	}
+
+	webhooksTablesError := createWebhooksTables(ctx, tx)
+	if webhooksTablesError != nil {
+		return webhooksTablesError
+	}
+
+	adjustmentsTableError := createAdjustmentsTable(ctx, tx)
+	if adjustmentsTableError != nil {
+		return adjustmentsTableError
+	}
+
+	holdsTableError := createHoldsTable(ctx, tx)
+	if holdsTableError != nil {
+		return holdsTableError
+	}
+
+	transfersTableError := createTransfersTable(ctx, tx)
+	if transfersTableError != nil {
+		return transfersTableError
+	}
+
+	pendingAccrualsTableError := createPendingAccrualsTable(ctx, tx)
+	if pendingAccrualsTableError != nil {
+		return pendingAccrualsTableError
+	}
+
+	accrualLotsTablesError := createAccrualLotsTables(ctx, tx)
+	if accrualLotsTablesError != nil {
+		return accrualLotsTablesError
+	}
+
+	auditEventsTableError := createAuditEventsTable(ctx, tx)
+	if auditEventsTableError != nil {
+		return auditEventsTableError
+	}
+
+	accrualResultsTableError := createAccrualResultsTable(ctx, tx)
+	if accrualResultsTableError != nil {
+		return accrualResultsTableError
+	}
+
 	tx.Commit()
 
 	return nil
@@ -361,14 +888,17 @@ func getOrder(ctx context.Context, querier Querier, number string) (models.Order
 	var o models.Order
 
 	row := querier.QueryRowContext(
-		ctx, "select id, user_id, number, status, uploaded_at, updated_at from orders where number = $1", number,
+		ctx, "select id, user_id, number, status, accrual, uploaded_at, updated_at from orders where number = $1",
+		number,
 	)
 
 	if row.Err() != nil {
 		return o, row.Err()
 	}
 
-	if scanErr := row.Scan(&o.ID, &o.UserID, &o.Number, &o.Status, &o.UploadedAt, &o.UpdatedAt); scanErr != nil {
+	if scanErr := row.Scan(
+		&o.ID, &o.UserID, &o.Number, &o.Status, &o.Accrual, &o.UploadedAt, &o.UpdatedAt,
+	); scanErr != nil {
 		if errors.Is(scanErr, sql.ErrNoRows) {
 			return o, ErrOrderNotFound
 		}
@@ -384,11 +914,27 @@ func createUsersTable(ctx context.Context, tx *sql.Tx) error {
 		`create table if not exists users(
     			id bigserial primary key,
     			login varchar(255) unique,
-    			password varchar(255)
+    			password varchar(255),
+    			role varchar(255) NOT NULL default 'user',
+    			store varchar(255) NOT NULL default ''
 			)`,
 	)
+	if usersTableError != nil {
+		return usersTableError
+	}
+
+	_, roleColumnError := tx.ExecContext(
+		ctx, "alter table users add column if not exists role varchar(255) NOT NULL default 'user'",
+	)
+	if roleColumnError != nil {
+		return roleColumnError
+	}
 
-	return usersTableError
+	_, storeColumnError := tx.ExecContext(
+		ctx, "alter table users add column if not exists store varchar(255) NOT NULL default ''",
+	)
+
+	return storeColumnError
 }
 
 func createOrdersTable(ctx context.Context, tx *sql.Tx) error {
@@ -407,8 +953,25 @@ func createOrdersTable(ctx context.Context, tx *sql.Tx) error {
                     references users(id)
 			)`,
 	)
+	if ordersTableError != nil {
+		return ordersTableError
+	}
+
+	//set while first accrual check of uploaded order is not done
+	_, checkColumnError := tx.ExecContext(
+		ctx, "alter table orders add column if not exists check_requested_at timestamp",
+	)
+	if checkColumnError != nil {
+		return checkColumnError
+	}
 
-	return ordersTableError
+	_, indexError := tx.ExecContext(
+		ctx,
+		`create index if not exists orders_check_requested_at on orders(check_requested_at)
+				where check_requested_at is not null`,
+	)
+
+	return indexError
 }
 
 func createUserBalancesTable(ctx context.Context, tx *sql.Tx) error {
@@ -418,13 +981,29 @@ func createUserBalancesTable(ctx context.Context, tx *sql.Tx) error {
     			id bigserial primary key,
     			user_id bigint,
     			balance double precision NOT NULL default 0,
+    			pending double precision NOT NULL default 0,
+    			held double precision NOT NULL default 0,
     			constraint fk_user
             		foreign key (user_id)
                     references users(id)
 			)`,
 	)
+	if userBalancesError != nil {
+		return userBalancesError
+	}
+
+	_, pendingColumnError := tx.ExecContext(
+		ctx, "alter table user_balances add column if not exists pending double precision NOT NULL default 0",
+	)
+	if pendingColumnError != nil {
+		return pendingColumnError
+	}
+
+	_, heldColumnError := tx.ExecContext(
+		ctx, "alter table user_balances add column if not exists held double precision NOT NULL default 0",
+	)
 
-	return userBalancesError
+	return heldColumnError
 }
 
 func createWithdrawalsTable(ctx context.Context, tx *sql.Tx) error {
@@ -436,11 +1015,27 @@ func createWithdrawalsTable(ctx context.Context, tx *sql.Tx) error {
     			order_number varchar(255) NOT NULL,
     			sum double precision NOT NULL,
     			processed_at timestamp NOT NULL,
+    			reversed_at timestamp,
+    			reversal_reason text NOT NULL default '',
     			constraint fk_user
             		foreign key (user_id)
                     references users(id)
 			)`,
 	)
+	if withdrawalsError != nil {
+		return withdrawalsError
+	}
+
+	_, reversedAtColumnError := tx.ExecContext(
+		ctx, "alter table withdrawals add column if not exists reversed_at timestamp",
+	)
+	if reversedAtColumnError != nil {
+		return reversedAtColumnError
+	}
+
+	_, reasonColumnError := tx.ExecContext(
+		ctx, "alter table withdrawals add column if not exists reversal_reason text NOT NULL default ''",
+	)
 
-	return withdrawalsError
+	return reasonColumnError
 }
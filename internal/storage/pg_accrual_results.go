@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/models"
+)
+
+// SaveAccrualResult keeps latest pushed result of order until it is applied and deleted.
+func (s PgAccrualResultsStorage) SaveAccrualResult(ctx context.Context, r models.AccrualResult) error {
+	_, execErr := s.db.ExecContext(
+		ctx,
+		`insert into accrual_results(order_number, status, accrual, received_at) values ($1, $2, $3, now())
+		on conflict (order_number) do update set status = excluded.status, accrual = excluded.accrual,
+		received_at = excluded.received_at`,
+		r.Order, r.Status, r.Accrual,
+	)
+
+	return execErr
+}
+
+func (s PgAccrualResultsStorage) GetAccrualResult(ctx context.Context, number string) (models.AccrualResult, error) {
+	r := models.AccrualResult{Order: number}
+
+	row := s.db.QueryRowContext(ctx, "select status, accrual from accrual_results where order_number = $1", number)
+	if scanErr := row.Scan(&r.Status, &r.Accrual); scanErr != nil {
+		if errors.Is(scanErr, sql.ErrNoRows) {
+			return r, ErrAccrualResultNotFound
+		}
+		return r, scanErr
+	}
+
+	return r, nil
+}
+
+// DeleteAccrualResult deletes applied result. Result is kept if it was replaced by result with other status meanwhile.
+func (s PgAccrualResultsStorage) DeleteAccrualResult(ctx context.Context, r models.AccrualResult) error {
+	_, execErr := s.db.ExecContext(
+		ctx, "delete from accrual_results where order_number = $1 and status = $2", r.Order, r.Status,
+	)
+
+	return execErr
+}
+
+func createAccrualResultsTable(ctx context.Context, tx *sql.Tx) error {
+	_, tableError := tx.ExecContext(
+		ctx,
+		`create table if not exists accrual_results(
+    			order_number varchar(255) primary key,
+    			status varchar(255) NOT NULL,
+    			accrual double precision,
+    			received_at timestamp NOT NULL
+			)`,
+	)
+
+	return tableError
+}
@@ -0,0 +1,238 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/events"
+	"github.com/bobgromozeka/yp-diploma1/internal/models"
+)
+
+const adjustmentColumns = `id, user_id, amount, reason, comment, status, created_by, decided_by, created_at, decided_at`
+
+// CreateAdjustment applies adjustment right away unless it requires approval of another admin.
+func (s PgAdjustmentsStorage) CreateAdjustment(
+	ctx context.Context, adjustment models.BalanceAdjustment, requireApproval bool,
+) (models.BalanceAdjustment, error) {
+	tx, txErr := s.db.BeginTx(ctx, nil)
+	if txErr != nil {
+		return adjustment, txErr
+	}
+	defer tx.Rollback()
+
+	userRow := tx.QueryRowContext(ctx, "select id from users where id = $1", adjustment.UserID)
+	var userID int64
+	if scanErr := userRow.Scan(&userID); scanErr != nil {
+		if errors.Is(scanErr, sql.ErrNoRows) {
+			return adjustment, ErrUserNotFound
+		}
+		return adjustment, scanErr
+	}
+
+	adjustment.Status = models.AdjustmentStatusPending
+	adjustment.CreatedAt = time.Now()
+	adjustment.DecidedBy = nil
+	adjustment.DecidedAt = nil
+
+	row := tx.QueryRowContext(
+		ctx,
+		`insert into balance_adjustments(user_id, amount, reason, comment, status, created_by, created_at)
+				values($1,$2,$3,$4,$5,$6,$7) returning id`,
+		adjustment.UserID, adjustment.Amount, adjustment.Reason, adjustment.Comment, adjustment.Status,
+		adjustment.CreatedBy, adjustment.CreatedAt,
+	)
+	if scanErr := row.Scan(&adjustment.ID); scanErr != nil {
+		return adjustment, scanErr
+	}
+
+	if !requireApproval {
+		var applyErr error
+		adjustment, applyErr = decideAdjustment(ctx, tx, adjustment, adjustment.CreatedBy, models.AdjustmentStatusApplied)
+		if applyErr != nil {
+			return adjustment, applyErr
+		}
+	}
+	tx.Commit()
+
+	return adjustment, nil
+}
+
+func (s PgAdjustmentsStorage) ApproveAdjustment(ctx context.Context, ID int64, adminID int64) (
+	models.BalanceAdjustment, error,
+) {
+	return s.decide(ctx, ID, adminID, models.AdjustmentStatusApplied)
+}
+
+func (s PgAdjustmentsStorage) RejectAdjustment(ctx context.Context, ID int64, adminID int64) (
+	models.BalanceAdjustment, error,
+) {
+	return s.decide(ctx, ID, adminID, models.AdjustmentStatusRejected)
+}
+
+func (s PgAdjustmentsStorage) GetUserAdjustments(ctx context.Context, userID int64) (
+	[]models.BalanceAdjustment, error,
+) {
+	adjustments := make([]models.BalanceAdjustment, 0)
+
+	rows, rowsErr := s.db.QueryContext(
+		ctx, "select "+adjustmentColumns+" from balance_adjustments where user_id = $1 order by id", userID,
+	)
+	if rowsErr != nil {
+		return adjustments, rowsErr
+	}
+	if rows.Err() != nil {
+		return adjustments, rows.Err()
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a models.BalanceAdjustment
+		if scanErr := rows.Scan(
+			&a.ID, &a.UserID, &a.Amount, &a.Reason, &a.Comment, &a.Status, &a.CreatedBy, &a.DecidedBy, &a.CreatedAt,
+			&a.DecidedAt,
+		); scanErr != nil {
+			return adjustments, scanErr
+		}
+		adjustments = append(adjustments, a)
+	}
+
+	return adjustments, nil
+}
+
+func (s PgAdjustmentsStorage) decide(ctx context.Context, ID int64, adminID int64, status string) (
+	models.BalanceAdjustment, error,
+) {
+	var a models.BalanceAdjustment
+
+	tx, txErr := s.db.BeginTx(ctx, nil)
+	if txErr != nil {
+		return a, txErr
+	}
+	defer tx.Rollback()
+
+	row := tx.QueryRowContext(
+		ctx, "select "+adjustmentColumns+" from balance_adjustments where id = $1 for update", ID,
+	)
+	if scanErr := row.Scan(
+		&a.ID, &a.UserID, &a.Amount, &a.Reason, &a.Comment, &a.Status, &a.CreatedBy, &a.DecidedBy, &a.CreatedAt,
+		&a.DecidedAt,
+	); scanErr != nil {
+		if errors.Is(scanErr, sql.ErrNoRows) {
+			return a, ErrAdjustmentNotFound
+		}
+		return a, scanErr
+	}
+
+	if a.Status != models.AdjustmentStatusPending {
+		return a, ErrAdjustmentDecided
+	}
+	if a.CreatedBy == adminID {
+		return a, ErrSelfApproval
+	}
+
+	a, decideErr := decideAdjustment(ctx, tx, a, adminID, status)
+	if decideErr != nil {
+		return a, decideErr
+	}
+	tx.Commit()
+
+	return a, nil
+}
+
+func decideAdjustment(
+	ctx context.Context, tx *sql.Tx, a models.BalanceAdjustment, adminID int64, status string,
+) (models.BalanceAdjustment, error) {
+	if status == models.AdjustmentStatusApplied {
+		balanceRow := tx.QueryRowContext(
+			ctx, "select balance, held from user_balances where user_id = $1 for update", a.UserID,
+		)
+
+		var balance, held float64
+		if scanErr := balanceRow.Scan(&balance, &held); scanErr != nil {
+			return a, scanErr
+		}
+		//held points are reserved for holds, debits can take only available ones
+		if a.Amount < 0 && balance-held+a.Amount < 0 {
+			return a, ErrInsufficientFunds
+		}
+
+		//credits are not tracked as lots, so they never expire
+		if a.Amount < 0 {
+			if _, consumeErr := consumeAccrualLots(ctx, tx, a.UserID, -a.Amount); consumeErr != nil {
+				return a, consumeErr
+			}
+		}
+
+		_, balanceErr := tx.ExecContext(
+			ctx, "update user_balances set balance = balance + $1 where user_id = $2", a.Amount, a.UserID,
+		)
+		if balanceErr != nil {
+			return a, balanceErr
+		}
+
+		eventErr := insertEvent(
+			ctx, tx, a.UserID, events.TypeBalanceAdjusted,
+			events.BalanceAdjusted{AdjustmentID: a.ID, Amount: a.Amount, Reason: a.Reason},
+		)
+		if eventErr != nil {
+			return a, eventErr
+		}
+
+		balanceAfter := balance + a.Amount
+		auditErr := insertAuditEvent(
+			ctx, tx, models.AuditEvent{
+				Action:       models.AuditBalanceAdjusted,
+				ActorID:      &adminID,
+				UserID:       &a.UserID,
+				AmountBefore: &balance,
+				AmountAfter:  &balanceAfter,
+			},
+		)
+		if auditErr != nil {
+			return a, auditErr
+		}
+	}
+
+	decidedAt := time.Now()
+	a.Status = status
+	a.DecidedBy = &adminID
+	a.DecidedAt = &decidedAt
+
+	_, updateErr := tx.ExecContext(
+		ctx, "update balance_adjustments set status = $1, decided_by = $2, decided_at = $3 where id = $4", a.Status,
+		a.DecidedBy, a.DecidedAt, a.ID,
+	)
+
+	return a, updateErr
+}
+
+func createAdjustmentsTable(ctx context.Context, tx *sql.Tx) error {
+	_, adjustmentsTableError := tx.ExecContext(
+		ctx,
+		`create table if not exists balance_adjustments(
+    			id bigserial primary key,
+    			user_id bigint NOT NULL,
+    			amount double precision NOT NULL,
+    			reason varchar(255) NOT NULL,
+    			comment text NOT NULL default '',
+    			status varchar(255) NOT NULL,
+    			created_by bigint NOT NULL,
+    			decided_by bigint,
+    			created_at timestamp NOT NULL,
+    			decided_at timestamp,
+    			constraint fk_user
+            		foreign key (user_id)
+                    references users(id),
+    			constraint fk_created_by
+            		foreign key (created_by)
+                    references users(id),
+    			constraint fk_decided_by
+            		foreign key (decided_by)
+                    references users(id)
+			)`,
+	)
+
+	return adjustmentsTableError
+}
@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/audit"
+	"github.com/bobgromozeka/yp-diploma1/internal/models"
+)
+
+const auditColumns = `id, action, actor_id, user_id, login, order_number, amount_before, amount_after, ip, user_agent,
+		request_id, created_at`
+
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func (s PgAuditStorage) RecordAuditEvent(ctx context.Context, e models.AuditEvent) error {
+	return insertAuditEvent(ctx, s.db, e)
+}
+
+func (s PgAuditStorage) FindAuditEvents(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error) {
+	auditEvents := make([]models.AuditEvent, 0)
+
+	query := "select " + auditColumns + " from audit_events where true"
+	var args []any
+	addCondition := func(condition string, value any) {
+		args = append(args, value)
+		query += " and " + condition + " $" + strconv.Itoa(len(args))
+	}
+
+	if filter.UserID != nil {
+		addCondition("user_id =", *filter.UserID)
+	}
+	if filter.Action != "" {
+		addCondition("action =", filter.Action)
+	}
+	if filter.RequestID != "" {
+		addCondition("request_id =", filter.RequestID)
+	}
+	if filter.From != nil {
+		addCondition("created_at >=", *filter.From)
+	}
+	if filter.To != nil {
+		addCondition("created_at <", *filter.To)
+	}
+	args = append(args, filter.Limit)
+	query += " order by id desc limit $" + strconv.Itoa(len(args))
+
+	rows, rowsErr := s.db.QueryContext(ctx, query, args...)
+	if rowsErr != nil {
+		return auditEvents, rowsErr
+	}
+	if rows.Err() != nil {
+		return auditEvents, rows.Err()
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e models.AuditEvent
+		if scanErr := rows.Scan(
+			&e.ID, &e.Action, &e.ActorID, &e.UserID, &e.Login, &e.Order, &e.AmountBefore, &e.AmountAfter, &e.IP,
+			&e.UserAgent, &e.RequestID, &e.CreatedAt,
+		); scanErr != nil {
+			return auditEvents, scanErr
+		}
+		auditEvents = append(auditEvents, e)
+	}
+
+	return auditEvents, nil
+}
+
+// insertAuditEvent fills request metadata from ctx. Financial actions pass their tx, so audit record
+// is written only together with balance change.
+func insertAuditEvent(ctx context.Context, execer Execer, e models.AuditEvent) error {
+	meta := audit.MetaFromContext(ctx)
+	if e.IP == "" {
+		e.IP = meta.IP
+	}
+	if e.UserAgent == "" {
+		e.UserAgent = meta.UserAgent
+	}
+	if e.RequestID == "" {
+		e.RequestID = meta.RequestID
+	}
+
+	_, insertErr := execer.ExecContext(
+		ctx,
+		`insert into audit_events(action, actor_id, user_id, login, order_number, amount_before, amount_after, ip,
+				user_agent, request_id, created_at) values($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)`,
+		e.Action, e.ActorID, e.UserID, e.Login, e.Order, e.AmountBefore, e.AmountAfter, e.IP, e.UserAgent,
+		e.RequestID, time.Now(),
+	)
+
+	return insertErr
+}
+
+func createAuditEventsTable(ctx context.Context, tx *sql.Tx) error {
+	_, auditTableError := tx.ExecContext(
+		ctx,
+		`create table if not exists audit_events(
+    			id bigserial primary key,
+    			action varchar(255) NOT NULL,
+    			actor_id bigint,
+    			user_id bigint,
+    			login varchar(255) NOT NULL default '',
+    			order_number varchar(255) NOT NULL default '',
+    			amount_before double precision,
+    			amount_after double precision,
+    			ip varchar(255) NOT NULL default '',
+    			user_agent text NOT NULL default '',
+    			request_id varchar(255) NOT NULL default '',
+    			created_at timestamp NOT NULL
+			)`,
+	)
+	if auditTableError != nil {
+		return auditTableError
+	}
+
+	_, indexError := tx.ExecContext(
+		ctx, "create index if not exists audit_events_user_id_id on audit_events(user_id, id)",
+	)
+	if indexError != nil {
+		return indexError
+	}
+
+	_, functionError := tx.ExecContext(
+		ctx,
+		`create or replace function audit_events_append_only() returns trigger as $$
+			begin
+				raise exception 'audit_events is append-only';
+			end;
+			$$ language plpgsql`,
+	)
+	if functionError != nil {
+		return functionError
+	}
+
+	_, dropTriggerError := tx.ExecContext(
+		ctx, "drop trigger if exists audit_events_append_only on audit_events",
+	)
+	if dropTriggerError != nil {
+		return dropTriggerError
+	}
+
+	_, triggerError := tx.ExecContext(
+		ctx,
+		`create trigger audit_events_append_only before update or delete on audit_events
+			for each row execute function audit_events_append_only()`,
+	)
+
+	return triggerError
+}
@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/stdlib"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/events"
+)
+
+const EventsChannel = "gophermart_events"
+
+var ErrUnexpectedConnection = errors.New("unexpected driver connection")
+
+func (s PgEventsStorage) GetUserEventsAfter(ctx context.Context, userID int64, afterID int64) ([]events.Event, error) {
+	userEvents := make([]events.Event, 0)
+
+	rows, rowsErr := s.db.QueryContext(
+		ctx,
+		"select id, user_id, type, payload, created_at from events where user_id = $1 and id > $2 order by id",
+		userID, afterID,
+	)
+	if rowsErr != nil {
+		return userEvents, rowsErr
+	}
+	if rows.Err() != nil {
+		return userEvents, rows.Err()
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e events.Event
+		if scanErr := rows.Scan(&e.ID, &e.UserID, &e.Type, &e.Payload, &e.CreatedAt); scanErr != nil {
+			return userEvents, scanErr
+		}
+		userEvents = append(userEvents, e)
+	}
+
+	return userEvents, nil
+}
+
+// Listen blocks until ctx is done or connection is broken, passing every committed event to handler.
+func (s PgEventsStorage) Listen(ctx context.Context, handler func(e events.Event)) error {
+	conn, connErr := s.db.Conn(ctx)
+	if connErr != nil {
+		return connErr
+	}
+	defer conn.Close()
+
+	return conn.Raw(
+		func(driverConn any) error {
+			stdlibConn, ok := driverConn.(*stdlib.Conn)
+			if !ok {
+				return ErrUnexpectedConnection
+			}
+			pgxConn := stdlibConn.Conn()
+
+			if _, listenErr := pgxConn.Exec(ctx, "listen "+EventsChannel); listenErr != nil {
+				return listenErr
+			}
+
+			for {
+				notification, waitErr := pgxConn.WaitForNotification(ctx)
+				if waitErr != nil {
+					return waitErr
+				}
+
+				eventID, parseErr := strconv.ParseInt(notification.Payload, 10, 64)
+				if parseErr != nil {
+					continue
+				}
+
+				e, eventErr := s.getEvent(ctx, eventID)
+				if eventErr != nil {
+					return eventErr
+				}
+
+				handler(e)
+			}
+		},
+	)
+}
+
+func (s PgEventsStorage) getEvent(ctx context.Context, ID int64) (events.Event, error) {
+	var e events.Event
+
+	row := s.db.QueryRowContext(ctx, "select id, user_id, type, payload, created_at from events where id = $1", ID)
+	if row.Err() != nil {
+		return e, row.Err()
+	}
+
+	if scanErr := row.Scan(&e.ID, &e.UserID, &e.Type, &e.Payload, &e.CreatedAt); scanErr != nil {
+		return e, scanErr
+	}
+
+	return e, nil
+}
+
+// insertEvent stores event inside tx. Notification is delivered to listeners only after tx is committed.
+func insertEvent(ctx context.Context, tx *sql.Tx, userID int64, eventType string, payload any) error {
+	encodedPayload, encodeErr := json.Marshal(payload)
+	if encodeErr != nil {
+		return encodeErr
+	}
+
+	row := tx.QueryRowContext(
+		ctx, "insert into events(user_id, type, payload, created_at) values($1,$2,$3,$4) returning id", userID,
+		eventType, encodedPayload, time.Now(),
+	)
+
+	var eventID int64
+	if scanErr := row.Scan(&eventID); scanErr != nil {
+		return scanErr
+	}
+
+	if outboxErr := enqueueWebhookDeliveries(ctx, tx, eventID, userID, eventType); outboxErr != nil {
+		return outboxErr
+	}
+
+	_, notifyErr := tx.ExecContext(ctx, "select pg_notify($1, $2)", EventsChannel, strconv.FormatInt(eventID, 10))
+
+	return notifyErr
+}
+
+func createEventsTable(ctx context.Context, tx *sql.Tx) error {
+	_, eventsTableError := tx.ExecContext(
+		ctx,
+		`create table if not exists events(
+    			id bigserial primary key,
+    			user_id bigint,
+    			type varchar(255) NOT NULL,
+    			payload jsonb NOT NULL,
+    			created_at timestamp NOT NULL,
+    			constraint fk_user
+            		foreign key (user_id)
+                    references users(id)
+			)`,
+	)
+	if eventsTableError != nil {
+		return eventsTableError
+	}
+
+	_, indexError := tx.ExecContext(ctx, "create index if not exists events_user_id_id on events(user_id, id)")
+
+	return indexError
+}
@@ -0,0 +1,212 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/models"
+)
+
+const holdColumns = `id, user_id, order_number, amount, status, created_at, expires_at, closed_at`
+
+// CreateHold reserves amount of available points, so it can't be spent by other withdrawals or holds.
+func (s PgHoldsStorage) CreateHold(
+	ctx context.Context, userID int64, orderNumber string, amount float64, expiresAt time.Time,
+) (models.Hold, error) {
+	h := models.Hold{
+		UserID:      userID,
+		OrderNumber: orderNumber,
+		Amount:      amount,
+		Status:      models.HoldStatusHeld,
+		CreatedAt:   time.Now(),
+		ExpiresAt:   expiresAt,
+	}
+
+	tx, txErr := s.db.BeginTx(ctx, nil)
+	if txErr != nil {
+		return h, txErr
+	}
+	defer tx.Rollback()
+
+	balanceRow := tx.QueryRowContext(
+		ctx, "select balance, held from user_balances where user_id = $1 for update", userID,
+	)
+	var balance, held float64
+	if scanErr := balanceRow.Scan(&balance, &held); scanErr != nil {
+		return h, scanErr
+	}
+
+	if balance-held < amount {
+		return h, ErrInsufficientFunds
+	}
+
+	row := tx.QueryRowContext(
+		ctx,
+		`insert into balance_holds(user_id, order_number, amount, status, created_at, expires_at)
+				values($1,$2,$3,$4,$5,$6) returning id`,
+		h.UserID, h.OrderNumber, h.Amount, h.Status, h.CreatedAt, h.ExpiresAt,
+	)
+	if scanErr := row.Scan(&h.ID); scanErr != nil {
+		return h, scanErr
+	}
+
+	_, heldErr := tx.ExecContext(ctx, "update user_balances set held = held + $1 where user_id = $2", amount, userID)
+	if heldErr != nil {
+		return h, heldErr
+	}
+	tx.Commit()
+
+	return h, nil
+}
+
+// CaptureHold spends held points as withdrawal for hold order. Capturing captured hold returns it as is.
+func (s PgHoldsStorage) CaptureHold(ctx context.Context, userID int64, ID int64) (models.Hold, error) {
+	return s.close(ctx, userID, ID, models.HoldStatusCaptured, time.Now())
+}
+
+// ReleaseHold makes held points available again. Releasing released hold returns it as is.
+func (s PgHoldsStorage) ReleaseHold(ctx context.Context, userID int64, ID int64) (models.Hold, error) {
+	return s.close(ctx, userID, ID, models.HoldStatusReleased, time.Now())
+}
+
+// ReleaseExpiredHolds releases up to limit holds which were neither captured nor released before expiry.
+func (s PgHoldsStorage) ReleaseExpiredHolds(ctx context.Context, now time.Time, limit int) (int, error) {
+	rows, rowsErr := s.db.QueryContext(
+		ctx,
+		"select id, user_id from balance_holds where status = $1 and expires_at <= $2 order by expires_at limit $3",
+		models.HoldStatusHeld, now, limit,
+	)
+	if rowsErr != nil {
+		return 0, rowsErr
+	}
+	if rows.Err() != nil {
+		return 0, rows.Err()
+	}
+
+	type dueHold struct {
+		ID     int64
+		UserID int64
+	}
+	var dueHolds []dueHold
+	for rows.Next() {
+		var h dueHold
+		if scanErr := rows.Scan(&h.ID, &h.UserID); scanErr != nil {
+			rows.Close()
+			return 0, scanErr
+		}
+		dueHolds = append(dueHolds, h)
+	}
+	rows.Close()
+
+	released := 0
+	for _, h := range dueHolds {
+		_, releaseErr := s.close(ctx, h.UserID, h.ID, models.HoldStatusReleased, now)
+		//hold could be closed by user after it was selected
+		if errors.Is(releaseErr, ErrHoldClosed) {
+			continue
+		}
+		if releaseErr != nil {
+			return released, releaseErr
+		}
+		released++
+	}
+
+	return released, nil
+}
+
+func (s PgHoldsStorage) close(ctx context.Context, userID int64, ID int64, status string, now time.Time) (
+	models.Hold, error,
+) {
+	var h models.Hold
+
+	tx, txErr := s.db.BeginTx(ctx, nil)
+	if txErr != nil {
+		return h, txErr
+	}
+	defer tx.Rollback()
+
+	balanceRow := tx.QueryRowContext(
+		ctx, "select balance from user_balances where user_id = $1 for update", userID,
+	)
+	var balance float64
+	if scanErr := balanceRow.Scan(&balance); scanErr != nil {
+		return h, scanErr
+	}
+
+	row := tx.QueryRowContext(
+		ctx, "select "+holdColumns+" from balance_holds where id = $1 and user_id = $2 for update", ID, userID,
+	)
+	if scanErr := row.Scan(
+		&h.ID, &h.UserID, &h.OrderNumber, &h.Amount, &h.Status, &h.CreatedAt, &h.ExpiresAt, &h.ClosedAt,
+	); scanErr != nil {
+		if errors.Is(scanErr, sql.ErrNoRows) {
+			return h, ErrHoldNotFound
+		}
+		return h, scanErr
+	}
+
+	if h.Status == status && h.Status != models.HoldStatusHeld {
+		return h, nil
+	}
+	if h.Status != models.HoldStatusHeld || (status == models.HoldStatusCaptured && !now.Before(h.ExpiresAt)) {
+		return h, ErrHoldClosed
+	}
+
+	_, heldErr := tx.ExecContext(
+		ctx, "update user_balances set held = held - $1 where user_id = $2", h.Amount, userID,
+	)
+	if heldErr != nil {
+		return h, heldErr
+	}
+
+	if status == models.HoldStatusCaptured {
+		if balance < h.Amount {
+			return h, ErrInsufficientFunds
+		}
+		if withdrawErr := withdraw(ctx, tx, userID, h.OrderNumber, h.Amount, balance); withdrawErr != nil {
+			return h, withdrawErr
+		}
+	}
+
+	h.Status = status
+	h.ClosedAt = &now
+	_, updateErr := tx.ExecContext(
+		ctx, "update balance_holds set status = $1, closed_at = $2 where id = $3", h.Status, h.ClosedAt, h.ID,
+	)
+	if updateErr != nil {
+		return h, updateErr
+	}
+	tx.Commit()
+
+	return h, nil
+}
+
+func createHoldsTable(ctx context.Context, tx *sql.Tx) error {
+	_, holdsTableError := tx.ExecContext(
+		ctx,
+		`create table if not exists balance_holds(
+    			id bigserial primary key,
+    			user_id bigint NOT NULL,
+    			order_number varchar(255) NOT NULL,
+    			amount double precision NOT NULL,
+    			status varchar(255) NOT NULL,
+    			created_at timestamp NOT NULL,
+    			expires_at timestamp NOT NULL,
+    			closed_at timestamp,
+    			constraint fk_user
+            		foreign key (user_id)
+                    references users(id)
+			)`,
+	)
+	if holdsTableError != nil {
+		return holdsTableError
+	}
+
+	_, indexError := tx.ExecContext(
+		ctx, "create index if not exists balance_holds_expires_at on balance_holds(expires_at) where status = 'HELD'",
+	)
+
+	return indexError
+}
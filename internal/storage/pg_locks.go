@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"time"
+)
+
+// RunWithLock runs fn while holding session-level advisory lock on dedicated connection. It returns false
+// right away if lock is held by other session. Lock is released by Postgres itself when connection dies,
+// so connection is checked every checkInterval and fn's ctx is canceled once it is broken.
+func (s PgLocksStorage) RunWithLock(
+	ctx context.Context, key int64, checkInterval time.Duration, fn func(lockCtx context.Context),
+) (bool, error) {
+	conn, connErr := s.db.Conn(ctx)
+	if connErr != nil {
+		return false, connErr
+	}
+	defer conn.Close()
+
+	var acquired bool
+	if scanErr := conn.QueryRowContext(ctx, "select pg_try_advisory_lock($1)", key).Scan(&acquired); scanErr != nil {
+		return false, scanErr
+	}
+	if !acquired {
+		return false, nil
+	}
+
+	lockCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		fn(lockCtx)
+		close(done)
+	}()
+
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	var lostErr error
+wait:
+	for {
+		select {
+		case <-done:
+			break wait
+		case <-ctx.Done():
+			break wait
+		case <-ticker.C:
+			if _, checkErr := conn.ExecContext(ctx, "select 1"); checkErr != nil && ctx.Err() == nil {
+				lostErr = checkErr
+				break wait
+			}
+		}
+	}
+
+	cancel()
+	<-done
+
+	if lostErr != nil {
+		//check could fail while session is still alive, closing connection makes Postgres release the lock
+		discardConn(conn)
+		return true, lostErr
+	}
+
+	//ctx may be already canceled, but lock must be released for other instances not to wait for connection close
+	_, unlockErr := conn.ExecContext(context.Background(), "select pg_advisory_unlock($1)", key)
+	if unlockErr != nil {
+		discardConn(conn)
+	}
+
+	return true, unlockErr
+}
+
+// discardConn closes underlying driver connection instead of returning it to pool, so session-level locks
+// are not kept by idle pooled connection.
+func discardConn(conn *sql.Conn) {
+	conn.Raw(
+		func(any) error {
+			return driver.ErrBadConn
+		},
+	)
+}
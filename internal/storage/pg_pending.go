@@ -0,0 +1,291 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/events"
+	"github.com/bobgromozeka/yp-diploma1/internal/models"
+)
+
+// ReleasePendingAccruals makes up to limit matured pending accruals spendable. Every accrual is released
+// in its own tx which locks user balance first, the same order as Withdraw does.
+func (s PgPointsStorage) ReleasePendingAccruals(ctx context.Context, now time.Time, limit int) (int, error) {
+	rows, rowsErr := s.db.QueryContext(
+		ctx,
+		"select id, user_id from pending_accruals where status = $1 and release_at <= $2 order by release_at limit $3",
+		models.PendingAccrualStatusPending, now, limit,
+	)
+	if rowsErr != nil {
+		return 0, rowsErr
+	}
+	if rows.Err() != nil {
+		return 0, rows.Err()
+	}
+
+	type duePending struct {
+		ID     int64
+		UserID int64
+	}
+	var duePendings []duePending
+	for rows.Next() {
+		var p duePending
+		if scanErr := rows.Scan(&p.ID, &p.UserID); scanErr != nil {
+			rows.Close()
+			return 0, scanErr
+		}
+		duePendings = append(duePendings, p)
+	}
+	rows.Close()
+
+	released := 0
+	for _, p := range duePendings {
+		if releaseErr := s.releasePendingAccrual(ctx, p.ID, p.UserID, now); releaseErr != nil {
+			return released, releaseErr
+		}
+		released++
+	}
+
+	return released, nil
+}
+
+func (s PgPointsStorage) releasePendingAccrual(ctx context.Context, ID int64, userID int64, now time.Time) error {
+	tx, txErr := s.db.BeginTx(ctx, nil)
+	if txErr != nil {
+		return txErr
+	}
+	defer tx.Rollback()
+
+	_, lockErr := tx.ExecContext(ctx, "select 1 from user_balances where user_id = $1 for update", userID)
+	if lockErr != nil {
+		return lockErr
+	}
+
+	pendingRow := tx.QueryRowContext(
+		ctx, "select order_number, amount, expires_at from pending_accruals where id = $1 and status = $2 for update",
+		ID, models.PendingAccrualStatusPending,
+	)
+	var orderNumber string
+	var amount float64
+	var expiresAt *time.Time
+	if scanErr := pendingRow.Scan(&orderNumber, &amount, &expiresAt); scanErr != nil {
+		//accrual was clawed back after it was selected
+		if errors.Is(scanErr, sql.ErrNoRows) {
+			return nil
+		}
+		return scanErr
+	}
+
+	_, pendingErr := tx.ExecContext(
+		ctx, "update user_balances set pending = pending - $1 where user_id = $2", amount, userID,
+	)
+	if pendingErr != nil {
+		return pendingErr
+	}
+
+	if creditErr := creditAccrual(ctx, tx, userID, orderNumber, amount, expiresAt); creditErr != nil {
+		return creditErr
+	}
+
+	_, releaseErr := tx.ExecContext(
+		ctx, "update pending_accruals set status = $1, released_at = $2 where id = $3",
+		models.PendingAccrualStatusReleased, now, ID,
+	)
+	if releaseErr != nil {
+		return releaseErr
+	}
+	tx.Commit()
+
+	return nil
+}
+
+// ClawbackPendingAccrual cancels accrual which is still on hold and invalidates its order.
+func (s PgOrdersStorage) ClawbackPendingAccrual(ctx context.Context, number string, reason string) error {
+	tx, txErr := s.db.BeginTx(ctx, nil)
+	if txErr != nil {
+		return txErr
+	}
+	defer tx.Rollback()
+
+	order, orderErr := getOrder(ctx, tx, number)
+	if orderErr != nil {
+		return orderErr
+	}
+
+	balanceRow := tx.QueryRowContext(
+		ctx, "select pending from user_balances where user_id = $1 for update", order.UserID,
+	)
+	var pending float64
+	if scanErr := balanceRow.Scan(&pending); scanErr != nil {
+		return scanErr
+	}
+
+	pendingRow := tx.QueryRowContext(
+		ctx, "select id, amount, status from pending_accruals where order_number = $1 for update", number,
+	)
+	var ID int64
+	var amount float64
+	var status string
+	if scanErr := pendingRow.Scan(&ID, &amount, &status); scanErr != nil {
+		if errors.Is(scanErr, sql.ErrNoRows) {
+			return ErrPendingNotFound
+		}
+		return scanErr
+	}
+	if status != models.PendingAccrualStatusPending {
+		return ErrPendingReleased
+	}
+
+	_, clawbackErr := tx.ExecContext(
+		ctx, "update pending_accruals set status = $1, clawback_reason = $2, clawed_back_at = $3 where id = $4",
+		models.PendingAccrualStatusClawedBack, reason, time.Now(), ID,
+	)
+	if clawbackErr != nil {
+		return clawbackErr
+	}
+
+	_, balanceErr := tx.ExecContext(
+		ctx, "update user_balances set pending = pending - $1 where user_id = $2", amount, order.UserID,
+	)
+	if balanceErr != nil {
+		return balanceErr
+	}
+
+	_, orderUpdateErr := tx.ExecContext(
+		ctx, "update orders set status = $1, updated_at = $2 where number = $3", models.OrderStatusInvalid,
+		time.Now(), number,
+	)
+	if orderUpdateErr != nil {
+		return orderUpdateErr
+	}
+
+	pendingAfter := pending - amount
+	auditErr := insertAuditEvent(
+		ctx, tx, models.AuditEvent{
+			Action:       models.AuditPendingClawedBack,
+			UserID:       &order.UserID,
+			Order:        number,
+			AmountBefore: &pending,
+			AmountAfter:  &pendingAfter,
+		},
+	)
+	if auditErr != nil {
+		return auditErr
+	}
+
+	eventErr := insertEvent(
+		ctx, tx, order.UserID, events.TypeOrderStatusChanged,
+		events.OrderStatusChanged{Number: number, Status: models.OrderStatusInvalid},
+	)
+	if eventErr != nil {
+		return eventErr
+	}
+	tx.Commit()
+
+	return nil
+}
+
+// creditAccrual makes accrued points spendable right away.
+func creditAccrual(
+	ctx context.Context, tx *sql.Tx, userID int64, orderNumber string, amount float64, expiresAt *time.Time,
+) error {
+	balanceRow := tx.QueryRowContext(
+		ctx, "update user_balances set balance = balance + $1 where user_id = $2 returning balance", amount, userID,
+	)
+
+	var balanceAfter float64
+	if balanceErr := balanceRow.Scan(&balanceAfter); balanceErr != nil {
+		return balanceErr
+	}
+	balanceBefore := balanceAfter - amount
+
+	if lotErr := insertAccrualLot(ctx, tx, userID, orderNumber, amount, time.Now(), expiresAt); lotErr != nil {
+		return lotErr
+	}
+
+	auditErr := insertAuditEvent(
+		ctx, tx, models.AuditEvent{
+			Action:       models.AuditBalanceAccrued,
+			UserID:       &userID,
+			Order:        orderNumber,
+			AmountBefore: &balanceBefore,
+			AmountAfter:  &balanceAfter,
+		},
+	)
+	if auditErr != nil {
+		return auditErr
+	}
+
+	return insertEvent(
+		ctx, tx, userID, events.TypeBalanceCredited, events.BalanceCredited{Order: orderNumber, Amount: amount},
+	)
+}
+
+// holdAccrual puts accrued points into pending bucket until terms.ReleaseAt.
+func holdAccrual(
+	ctx context.Context, tx *sql.Tx, userID int64, orderNumber string, amount float64, terms models.AccrualTerms,
+) error {
+	pendingRow := tx.QueryRowContext(
+		ctx, "update user_balances set pending = pending + $1 where user_id = $2 returning pending", amount, userID,
+	)
+
+	var pendingAfter float64
+	if pendingErr := pendingRow.Scan(&pendingAfter); pendingErr != nil {
+		return pendingErr
+	}
+	pendingBefore := pendingAfter - amount
+
+	_, insertErr := tx.ExecContext(
+		ctx,
+		`insert into pending_accruals(user_id, order_number, amount, status, accrued_at, release_at, expires_at)
+				values($1,$2,$3,$4,$5,$6,$7)`,
+		userID, orderNumber, amount, models.PendingAccrualStatusPending, time.Now(), terms.ReleaseAt, terms.ExpiresAt,
+	)
+	if insertErr != nil {
+		return insertErr
+	}
+
+	return insertAuditEvent(
+		ctx, tx, models.AuditEvent{
+			Action:       models.AuditPendingAccrued,
+			UserID:       &userID,
+			Order:        orderNumber,
+			AmountBefore: &pendingBefore,
+			AmountAfter:  &pendingAfter,
+		},
+	)
+}
+
+func createPendingAccrualsTable(ctx context.Context, tx *sql.Tx) error {
+	_, pendingTableError := tx.ExecContext(
+		ctx,
+		`create table if not exists pending_accruals(
+    			id bigserial primary key,
+    			user_id bigint NOT NULL,
+    			order_number varchar(255) NOT NULL unique,
+    			amount double precision NOT NULL,
+    			status varchar(255) NOT NULL,
+    			accrued_at timestamp NOT NULL,
+    			release_at timestamp NOT NULL,
+    			expires_at timestamp,
+    			released_at timestamp,
+    			clawed_back_at timestamp,
+    			clawback_reason text NOT NULL default '',
+    			constraint fk_user
+            		foreign key (user_id)
+                    references users(id)
+			)`,
+	)
+	if pendingTableError != nil {
+		return pendingTableError
+	}
+
+	_, indexError := tx.ExecContext(
+		ctx,
+		"create index if not exists pending_accruals_release_at on pending_accruals(release_at) where status = 'PENDING'",
+	)
+
+	return indexError
+}
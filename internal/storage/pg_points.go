@@ -0,0 +1,405 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/events"
+	"github.com/bobgromozeka/yp-diploma1/internal/models"
+)
+
+// GetExpiringPoints groups not consumed points expiring before until by expiry day.
+func (s PgPointsStorage) GetExpiringPoints(ctx context.Context, userID int64, until time.Time) (
+	[]models.ExpiringPoints, error,
+) {
+	expiringPoints := make([]models.ExpiringPoints, 0)
+
+	rows, rowsErr := s.db.QueryContext(
+		ctx,
+		`select sum(remaining), date_trunc('day', expires_at) as expires_day from accrual_lots
+				where user_id = $1 and remaining > 0 and expires_at <= $2
+				group by expires_day order by expires_day`,
+		userID, until,
+	)
+	if rowsErr != nil {
+		return expiringPoints, rowsErr
+	}
+	if rows.Err() != nil {
+		return expiringPoints, rows.Err()
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p models.ExpiringPoints
+		if scanErr := rows.Scan(&p.Amount, &p.ExpiresAt); scanErr != nil {
+			return expiringPoints, scanErr
+		}
+		expiringPoints = append(expiringPoints, p)
+	}
+
+	return expiringPoints, nil
+}
+
+// ExpireLots expires up to limit lots with expiry time before now. Every lot is expired in its own tx
+// which locks user balance first, the same order as Withdraw does. Points reserved by holds don't expire
+// until hold is closed, so lots of users having nothing but held points are skipped.
+func (s PgPointsStorage) ExpireLots(ctx context.Context, now time.Time, limit int) (int, error) {
+	rows, rowsErr := s.db.QueryContext(
+		ctx,
+		`select l.id, l.user_id from accrual_lots l join user_balances b on b.user_id = l.user_id
+				where l.remaining > 0 and l.expires_at <= $1 and b.balance - b.held > 0
+				order by l.expires_at limit $2`,
+		now, limit,
+	)
+	if rowsErr != nil {
+		return 0, rowsErr
+	}
+	if rows.Err() != nil {
+		return 0, rows.Err()
+	}
+
+	type dueLot struct {
+		ID     int64
+		UserID int64
+	}
+	var dueLots []dueLot
+	for rows.Next() {
+		var lot dueLot
+		if scanErr := rows.Scan(&lot.ID, &lot.UserID); scanErr != nil {
+			rows.Close()
+			return 0, scanErr
+		}
+		dueLots = append(dueLots, lot)
+	}
+	rows.Close()
+
+	expired := 0
+	for _, lot := range dueLots {
+		lotExpired, expireErr := s.expireLot(ctx, lot.ID, lot.UserID, now)
+		if expireErr != nil {
+			return expired, expireErr
+		}
+		if lotExpired {
+			expired++
+		}
+	}
+
+	return expired, nil
+}
+
+// expirableAmount is part of lot remaining which can expire without making balance lower than held amount.
+func expirableAmount(remaining float64, balance float64, held float64) float64 {
+	available := balance - held
+	if available <= 0 {
+		return 0
+	}
+	if remaining > available {
+		return available
+	}
+
+	return remaining
+}
+
+// expireLot returns false if nothing expired because lot was consumed or all available points are held.
+func (s PgPointsStorage) expireLot(ctx context.Context, lotID int64, userID int64, now time.Time) (bool, error) {
+	tx, txErr := s.db.BeginTx(ctx, nil)
+	if txErr != nil {
+		return false, txErr
+	}
+	defer tx.Rollback()
+
+	balanceRow := tx.QueryRowContext(
+		ctx, "select balance, held from user_balances where user_id = $1 for update", userID,
+	)
+	var balance, held float64
+	if scanErr := balanceRow.Scan(&balance, &held); scanErr != nil {
+		return false, scanErr
+	}
+
+	lotRow := tx.QueryRowContext(
+		ctx, "select order_number, remaining from accrual_lots where id = $1 and remaining > 0 for update", lotID,
+	)
+	var orderNumber string
+	var remaining float64
+	if scanErr := lotRow.Scan(&orderNumber, &remaining); scanErr != nil {
+		//lot was consumed by withdrawal after it was selected
+		if errors.Is(scanErr, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, scanErr
+	}
+
+	//the rest of lot expires when holds are released, captured holds consume it as the oldest lot
+	amount := expirableAmount(remaining, balance, held)
+	if amount <= 0 {
+		return false, nil
+	}
+
+	_, lotErr := tx.ExecContext(ctx, "update accrual_lots set remaining = remaining - $1 where id = $2", amount, lotID)
+	if lotErr != nil {
+		return false, lotErr
+	}
+
+	_, expirationErr := tx.ExecContext(
+		ctx, "insert into point_expirations(user_id, lot_id, order_number, amount, expired_at) values($1,$2,$3,$4,$5)",
+		userID, lotID, orderNumber, amount, now,
+	)
+	if expirationErr != nil {
+		return false, expirationErr
+	}
+
+	_, balanceErr := tx.ExecContext(
+		ctx, "update user_balances set balance = balance - $1 where user_id = $2", amount, userID,
+	)
+	if balanceErr != nil {
+		return false, balanceErr
+	}
+
+	balanceAfter := balance - amount
+	auditErr := insertAuditEvent(
+		ctx, tx, models.AuditEvent{
+			Action:       models.AuditBalanceExpired,
+			UserID:       &userID,
+			Order:        orderNumber,
+			AmountBefore: &balance,
+			AmountAfter:  &balanceAfter,
+		},
+	)
+	if auditErr != nil {
+		return false, auditErr
+	}
+
+	eventErr := insertEvent(
+		ctx, tx, userID, events.TypeBalanceExpired, events.BalanceExpired{Order: orderNumber, Amount: amount},
+	)
+	if eventErr != nil {
+		return false, eventErr
+	}
+	tx.Commit()
+
+	return true, nil
+}
+
+// insertAccrualLot tracks accrued amount, so it could expire. Nil expiresAt means points never expire.
+// Lots are consumed in accruedAt order, so restored and transferred lots keep accruedAt of the original lot.
+func insertAccrualLot(
+	ctx context.Context, tx *sql.Tx, userID int64, orderNumber string, amount float64, accruedAt time.Time,
+	expiresAt *time.Time,
+) error {
+	_, insertErr := tx.ExecContext(
+		ctx,
+		`insert into accrual_lots(user_id, order_number, amount, remaining, accrued_at, expires_at)
+				values($1,$2,$3,$3,$4,$5)`,
+		userID, orderNumber, amount, accruedAt, expiresAt,
+	)
+
+	return insertErr
+}
+
+type lotConsumption struct {
+	ID          int64
+	OrderNumber string
+	Amount      float64
+	AccruedAt   time.Time
+	ExpiresAt   *time.Time
+}
+
+// consumeAccrualLots takes amount from the oldest lots first. Balance which is not covered by lots
+// (credited before expiration was introduced or by adjustments) never expires and is taken last.
+// User balance must be locked by caller.
+func consumeAccrualLots(ctx context.Context, tx *sql.Tx, userID int64, amount float64) ([]lotConsumption, error) {
+	rows, rowsErr := tx.QueryContext(
+		ctx,
+		`select id, order_number, remaining, accrued_at, expires_at from accrual_lots
+				where user_id = $1 and remaining > 0 order by accrued_at, id`,
+		userID,
+	)
+	if rowsErr != nil {
+		return nil, rowsErr
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	var consumptions []lotConsumption
+	for rows.Next() && amount > 0 {
+		var c lotConsumption
+		var remaining float64
+		if scanErr := rows.Scan(&c.ID, &c.OrderNumber, &remaining, &c.AccruedAt, &c.ExpiresAt); scanErr != nil {
+			rows.Close()
+			return nil, scanErr
+		}
+		c.Amount = remaining
+		if amount < remaining {
+			c.Amount = amount
+		}
+		amount -= c.Amount
+		consumptions = append(consumptions, c)
+	}
+	rows.Close()
+
+	for _, c := range consumptions {
+		_, updateErr := tx.ExecContext(
+			ctx, "update accrual_lots set remaining = remaining - $1 where id = $2", c.Amount, c.ID,
+		)
+		if updateErr != nil {
+			return nil, updateErr
+		}
+	}
+
+	return consumptions, nil
+}
+
+// recordWithdrawalConsumptions remembers lots withdrawal was taken from, so reversal could restore them.
+func recordWithdrawalConsumptions(
+	ctx context.Context, tx *sql.Tx, withdrawalID int64, consumptions []lotConsumption,
+) error {
+	for _, c := range consumptions {
+		_, insertErr := tx.ExecContext(
+			ctx,
+			`insert into withdrawal_lot_consumptions(withdrawal_id, order_number, amount, accrued_at, expires_at)
+					values($1,$2,$3,$4,$5)`,
+			withdrawalID, c.OrderNumber, c.Amount, c.AccruedAt, c.ExpiresAt,
+		)
+		if insertErr != nil {
+			return insertErr
+		}
+	}
+
+	return nil
+}
+
+// restoreWithdrawalLots gives user back lots withdrawal was taken from with their original accrual and expiration
+// time, so they are consumed before newer lots. Lots which are already expired are expired again by the next
+// expiration run.
+func restoreWithdrawalLots(ctx context.Context, tx *sql.Tx, withdrawalID int64, userID int64) error {
+	rows, rowsErr := tx.QueryContext(
+		ctx,
+		`select order_number, amount, accrued_at, expires_at from withdrawal_lot_consumptions
+				where withdrawal_id = $1 order by id`,
+		withdrawalID,
+	)
+	if rowsErr != nil {
+		return rowsErr
+	}
+	if rows.Err() != nil {
+		return rows.Err()
+	}
+
+	var consumptions []lotConsumption
+	for rows.Next() {
+		var c lotConsumption
+		var accruedAt sql.NullTime
+		if scanErr := rows.Scan(&c.OrderNumber, &c.Amount, &accruedAt, &c.ExpiresAt); scanErr != nil {
+			rows.Close()
+			return scanErr
+		}
+		//consumptions recorded before accrual time was kept are restored as new lots
+		c.AccruedAt = time.Now()
+		if accruedAt.Valid {
+			c.AccruedAt = accruedAt.Time
+		}
+		consumptions = append(consumptions, c)
+	}
+	rows.Close()
+
+	for _, c := range consumptions {
+		if lotErr := insertAccrualLot(ctx, tx, userID, c.OrderNumber, c.Amount, c.AccruedAt, c.ExpiresAt); lotErr != nil {
+			return lotErr
+		}
+	}
+
+	return nil
+}
+
+func createAccrualLotsTables(ctx context.Context, tx *sql.Tx) error {
+	_, lotsTableError := tx.ExecContext(
+		ctx,
+		`create table if not exists accrual_lots(
+    			id bigserial primary key,
+    			user_id bigint NOT NULL,
+    			order_number varchar(255) NOT NULL,
+    			amount double precision NOT NULL,
+    			remaining double precision NOT NULL,
+    			accrued_at timestamp NOT NULL,
+    			expires_at timestamp,
+    			constraint fk_user
+            		foreign key (user_id)
+                    references users(id)
+			)`,
+	)
+	if lotsTableError != nil {
+		return lotsTableError
+	}
+
+	_, userIndexError := tx.ExecContext(
+		ctx,
+		"create index if not exists accrual_lots_user_id on accrual_lots(user_id, accrued_at) where remaining > 0",
+	)
+	if userIndexError != nil {
+		return userIndexError
+	}
+
+	_, expiresIndexError := tx.ExecContext(
+		ctx, "create index if not exists accrual_lots_expires_at on accrual_lots(expires_at) where remaining > 0",
+	)
+	if expiresIndexError != nil {
+		return expiresIndexError
+	}
+
+	_, expirationsTableError := tx.ExecContext(
+		ctx,
+		`create table if not exists point_expirations(
+    			id bigserial primary key,
+    			user_id bigint NOT NULL,
+    			lot_id bigint NOT NULL,
+    			order_number varchar(255) NOT NULL,
+    			amount double precision NOT NULL,
+    			expired_at timestamp NOT NULL,
+    			constraint fk_user
+            		foreign key (user_id)
+                    references users(id),
+    			constraint fk_lot
+            		foreign key (lot_id)
+                    references accrual_lots(id)
+			)`,
+	)
+	if expirationsTableError != nil {
+		return expirationsTableError
+	}
+
+	_, consumptionsTableError := tx.ExecContext(
+		ctx,
+		`create table if not exists withdrawal_lot_consumptions(
+    			id bigserial primary key,
+    			withdrawal_id bigint NOT NULL,
+    			order_number varchar(255) NOT NULL,
+    			amount double precision NOT NULL,
+    			accrued_at timestamp,
+    			expires_at timestamp,
+    			constraint fk_withdrawal
+            		foreign key (withdrawal_id)
+                    references withdrawals(id)
+			)`,
+	)
+	if consumptionsTableError != nil {
+		return consumptionsTableError
+	}
+
+	_, accruedAtColumnError := tx.ExecContext(
+		ctx, "alter table withdrawal_lot_consumptions add column if not exists accrued_at timestamp",
+	)
+	if accruedAtColumnError != nil {
+		return accruedAtColumnError
+	}
+
+	_, consumptionsIndexError := tx.ExecContext(
+		ctx,
+		`create index if not exists withdrawal_lot_consumptions_withdrawal_id
+				on withdrawal_lot_consumptions(withdrawal_id)`,
+	)
+
+	return consumptionsIndexError
+}
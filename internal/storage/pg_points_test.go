@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpirableAmount(t *testing.T) {
+	type testCase struct {
+		Name      string
+		Remaining float64
+		Balance   float64
+		Held      float64
+		Expirable float64
+	}
+
+	testCases := []testCase{
+		{
+			Name:      "No holds",
+			Remaining: 100,
+			Balance:   150,
+			Expirable: 100,
+		},
+		{
+			Name:      "Hold covered by other points",
+			Remaining: 100,
+			Balance:   150,
+			Held:      50,
+			Expirable: 100,
+		},
+		{
+			Name:      "Hold takes part of expiring lot",
+			Remaining: 100,
+			Balance:   100,
+			Held:      30,
+			Expirable: 70,
+		},
+		{
+			Name:      "Hold takes whole expiring lot",
+			Remaining: 100,
+			Balance:   100,
+			Held:      100,
+			Expirable: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(
+			tc.Name, func(t *testing.T) {
+				expirable := expirableAmount(tc.Remaining, tc.Balance, tc.Held)
+
+				assert.Equal(t, tc.Expirable, expirable)
+				//hold must still be capturable after expiry
+				assert.GreaterOrEqual(t, tc.Balance-expirable, tc.Held)
+			},
+		)
+	}
+}
@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/events"
+	"github.com/bobgromozeka/yp-diploma1/internal/models"
+)
+
+// ReverseWithdrawal restores points of withdrawal with ID. Order numbers are not unique across withdrawals, so
+// withdrawal is addressed by ID only. Reversing already reversed withdrawal changes nothing and returns it as is. Points taken from accrual lots are restored as lots with the same
+// expiration time, so reversal does not extend their life.
+func (s PgWithdrawalsStorage) ReverseWithdrawal(
+	ctx context.Context, ID int64, reason string, actorID int64,
+) (models.Withdrawal, error) {
+	var w models.Withdrawal
+
+	tx, txErr := s.db.BeginTx(ctx, nil)
+	if txErr != nil {
+		return w, txErr
+	}
+	defer tx.Rollback()
+
+	userRow := tx.QueryRowContext(
+		ctx, "select user_id from withdrawals where id = $1", ID,
+	)
+	var userID int64
+	if scanErr := userRow.Scan(&userID); scanErr != nil {
+		if errors.Is(scanErr, sql.ErrNoRows) {
+			return w, ErrWithdrawalNotFound
+		}
+		return w, scanErr
+	}
+
+	balanceRow := tx.QueryRowContext(
+		ctx, "select balance from user_balances where user_id = $1 for update", userID,
+	)
+	var balance float64
+	if scanErr := balanceRow.Scan(&balance); scanErr != nil {
+		return w, scanErr
+	}
+
+	withdrawalRow := tx.QueryRowContext(
+		ctx,
+		`select id, user_id, order_number, sum, processed_at, reversed_at, reversal_reason from withdrawals
+				where id = $1 for update`,
+		ID,
+	)
+	if scanErr := withdrawalRow.Scan(
+		&w.ID, &w.UserID, &w.OrderNumber, &w.Sum, &w.ProcessedAt, &w.ReversedAt, &w.ReversalReason,
+	); scanErr != nil {
+		return w, scanErr
+	}
+
+	if w.ReversedAt != nil {
+		return w, nil
+	}
+
+	reversedAt := time.Now()
+	w.ReversedAt = &reversedAt
+	w.ReversalReason = reason
+
+	_, reverseErr := tx.ExecContext(
+		ctx, "update withdrawals set reversed_at = $1, reversal_reason = $2 where id = $3", w.ReversedAt,
+		w.ReversalReason, w.ID,
+	)
+	if reverseErr != nil {
+		return w, reverseErr
+	}
+
+	if restoreErr := restoreWithdrawalLots(ctx, tx, w.ID, userID); restoreErr != nil {
+		return w, restoreErr
+	}
+
+	_, balanceErr := tx.ExecContext(
+		ctx, "update user_balances set balance = balance + $1 where user_id = $2", w.Sum, userID,
+	)
+	if balanceErr != nil {
+		return w, balanceErr
+	}
+
+	balanceAfter := balance + w.Sum
+	auditErr := insertAuditEvent(
+		ctx, tx, models.AuditEvent{
+			Action:       models.AuditWithdrawalReversed,
+			ActorID:      &actorID,
+			UserID:       &userID,
+			Order:        w.OrderNumber,
+			AmountBefore: &balance,
+			AmountAfter:  &balanceAfter,
+		},
+	)
+	if auditErr != nil {
+		return w, auditErr
+	}
+
+	eventErr := insertEvent(
+		ctx, tx, userID, events.TypeWithdrawalReversed,
+		events.WithdrawalReversed{Order: w.OrderNumber, Sum: w.Sum, Reason: reason},
+	)
+	if eventErr != nil {
+		return w, eventErr
+	}
+	tx.Commit()
+
+	return w, nil
+}
@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/models"
+)
+
+const StatementFetchSize = 500
+
+// statementEntriesQuery lists every balance change of user $1. Each new kind of balance change adds its own branch.
+const statementEntriesQuery = `
+	select 'accrual' as type, o.number as order_number, o.accrual as amount,
+		coalesce(p.released_at, o.updated_at, o.uploaded_at) as at, o.id, '' as reason, '' as counterparty
+	from orders o left join pending_accruals p on p.order_number = o.number
+	where o.user_id = $1 and o.accrual is not null and (p.id is null or p.status = 'RELEASED')
+	union all
+	select 'withdrawal', order_number, -sum, processed_at, id, '', ''
+	from withdrawals where user_id = $1
+	union all
+	select 'reversal', order_number, sum, reversed_at, id, reversal_reason, ''
+	from withdrawals where user_id = $1 and reversed_at is not null
+	union all
+	select 'adjustment', '', amount, decided_at, id, reason, ''
+	from balance_adjustments where user_id = $1 and status = 'APPLIED'
+	union all
+	select 'expiration', order_number, -amount, expired_at, id, '', ''
+	from point_expirations where user_id = $1
+	union all
+	select 'transfer_out', '', -t.amount, t.created_at, t.id, '', u.login
+	from transfers t join users u on u.id = t.to_user_id where t.from_user_id = $1
+	union all
+	select 'transfer_in', '', t.amount, t.created_at, t.id, '', u.login
+	from transfers t join users u on u.id = t.from_user_id where t.to_user_id = $1`
+
+// StreamUserStatement reads statement with server side cursor, so whole history is never loaded into memory.
+// Running balance is calculated over the whole history, so it is correct for any from/to range.
+func (s PgStatementsStorage) StreamUserStatement(
+	ctx context.Context, userID int64, from *time.Time, to *time.Time, fn func(e models.StatementEntry) error,
+) error {
+	tx, txErr := s.db.BeginTx(ctx, nil)
+	if txErr != nil {
+		return txErr
+	}
+	defer tx.Rollback()
+
+	_, declareErr := tx.ExecContext(
+		ctx,
+		`declare statement_cursor no scroll cursor for
+				select type, order_number, amount, balance, at, reason, counterparty from (
+					select type, order_number, amount, at, id, reason, counterparty,
+						sum(amount) over (order by at, id, type) as balance
+					from (`+statementEntriesQuery+`) entries
+				) ledger
+				where ($2::timestamp is null or at >= $2) and ($3::timestamp is null or at < $3)
+				order by at, id, type`,
+		userID, from, to,
+	)
+	if declareErr != nil {
+		return declareErr
+	}
+
+	for {
+		fetched, fetchErr := fetchStatementEntries(ctx, tx, fn)
+		if fetchErr != nil {
+			return fetchErr
+		}
+		if fetched < StatementFetchSize {
+			break
+		}
+	}
+
+	_, closeErr := tx.ExecContext(ctx, "close statement_cursor")
+	if closeErr != nil {
+		return closeErr
+	}
+	tx.Commit()
+
+	return nil
+}
+
+func fetchStatementEntries(ctx context.Context, tx *sql.Tx, fn func(e models.StatementEntry) error) (int, error) {
+	rows, rowsErr := tx.QueryContext(ctx, "fetch "+strconv.Itoa(StatementFetchSize)+" from statement_cursor")
+	if rowsErr != nil {
+		return 0, rowsErr
+	}
+	if rows.Err() != nil {
+		return 0, rows.Err()
+	}
+	defer rows.Close()
+
+	fetched := 0
+	for rows.Next() {
+		var e models.StatementEntry
+		if scanErr := rows.Scan(
+			&e.Type, &e.Order, &e.Amount, &e.Balance, &e.At, &e.Reason, &e.Counterparty,
+		); scanErr != nil {
+			return fetched, scanErr
+		}
+		fetched++
+
+		if fnErr := fn(e); fnErr != nil {
+			return fetched, fnErr
+		}
+	}
+
+	return fetched, rows.Err()
+}
@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/events"
+	"github.com/bobgromozeka/yp-diploma1/internal/models"
+)
+
+const TransferLimitWindow = time.Hour * 24
+
+// Transfer moves amount of available points from one user to user with toLogin. Transferred points keep
+// expiration dates of the lots they were taken from. dailyLimit caps sum of transfers within rolling
+// TransferLimitWindow, so it doesn't depend on time zone of server or user. Zero dailyLimit means no limit.
+func (s PgTransfersStorage) Transfer(
+	ctx context.Context, fromUserID int64, toLogin string, amount float64, dailyLimit float64,
+) (models.Transfer, error) {
+	t := models.Transfer{
+		FromUserID: fromUserID,
+		To:         toLogin,
+		Amount:     amount,
+		CreatedAt:  time.Now(),
+	}
+
+	tx, txErr := s.db.BeginTx(ctx, nil)
+	if txErr != nil {
+		return t, txErr
+	}
+	defer tx.Rollback()
+
+	recipientRow := tx.QueryRowContext(ctx, "select id from users where login = $1", toLogin)
+	if scanErr := recipientRow.Scan(&t.ToUserID); scanErr != nil {
+		if errors.Is(scanErr, sql.ErrNoRows) {
+			return t, ErrRecipientNotFound
+		}
+		return t, scanErr
+	}
+	if t.ToUserID == fromUserID {
+		return t, ErrSelfTransfer
+	}
+
+	//both balances are locked in user_id order, so opposite transfers can't deadlock
+	rows, rowsErr := tx.QueryContext(
+		ctx,
+		"select user_id, balance, held from user_balances where user_id in ($1, $2) order by user_id for update",
+		fromUserID, t.ToUserID,
+	)
+	if rowsErr != nil {
+		return t, rowsErr
+	}
+	if rows.Err() != nil {
+		return t, rows.Err()
+	}
+
+	var senderBalance, senderHeld, recipientBalance float64
+	for rows.Next() {
+		var userID int64
+		var balance, held float64
+		if scanErr := rows.Scan(&userID, &balance, &held); scanErr != nil {
+			rows.Close()
+			return t, scanErr
+		}
+		if userID == fromUserID {
+			senderBalance, senderHeld = balance, held
+		} else {
+			recipientBalance = balance
+		}
+	}
+	rows.Close()
+
+	if senderBalance-senderHeld < amount {
+		return t, ErrInsufficientFunds
+	}
+
+	if dailyLimit > 0 {
+		var transferredInWindow float64
+		windowRow := tx.QueryRowContext(
+			ctx, "select coalesce(sum(amount), 0) from transfers where from_user_id = $1 and created_at > $2",
+			fromUserID, t.CreatedAt.Add(-TransferLimitWindow),
+		)
+		if scanErr := windowRow.Scan(&transferredInWindow); scanErr != nil {
+			return t, scanErr
+		}
+		if transferredInWindow+amount > dailyLimit {
+			return t, ErrTransferLimit
+		}
+	}
+
+	consumptions, consumeErr := consumeAccrualLots(ctx, tx, fromUserID, amount)
+	if consumeErr != nil {
+		return t, consumeErr
+	}
+	for _, c := range consumptions {
+		if lotErr := insertAccrualLot(
+			ctx, tx, t.ToUserID, c.OrderNumber, c.Amount, c.AccruedAt, c.ExpiresAt,
+		); lotErr != nil {
+			return t, lotErr
+		}
+	}
+
+	row := tx.QueryRowContext(
+		ctx, "insert into transfers(from_user_id, to_user_id, amount, created_at) values($1,$2,$3,$4) returning id",
+		fromUserID, t.ToUserID, amount, t.CreatedAt,
+	)
+	if scanErr := row.Scan(&t.ID); scanErr != nil {
+		return t, scanErr
+	}
+
+	_, senderErr := tx.ExecContext(
+		ctx, "update user_balances set balance = balance - $1 where user_id = $2", amount, fromUserID,
+	)
+	if senderErr != nil {
+		return t, senderErr
+	}
+	_, recipientErr := tx.ExecContext(
+		ctx, "update user_balances set balance = balance + $1 where user_id = $2", amount, t.ToUserID,
+	)
+	if recipientErr != nil {
+		return t, recipientErr
+	}
+
+	var fromLogin string
+	if scanErr := tx.QueryRowContext(ctx, "select login from users where id = $1", fromUserID).Scan(
+		&fromLogin,
+	); scanErr != nil {
+		return t, scanErr
+	}
+
+	senderAfter := senderBalance - amount
+	recipientAfter := recipientBalance + amount
+	auditEvents := []models.AuditEvent{
+		{
+			Action:       models.AuditTransferSent,
+			ActorID:      &fromUserID,
+			UserID:       &fromUserID,
+			Login:        toLogin,
+			AmountBefore: &senderBalance,
+			AmountAfter:  &senderAfter,
+		},
+		{
+			Action:       models.AuditTransferReceived,
+			ActorID:      &fromUserID,
+			UserID:       &t.ToUserID,
+			Login:        fromLogin,
+			AmountBefore: &recipientBalance,
+			AmountAfter:  &recipientAfter,
+		},
+	}
+	for _, e := range auditEvents {
+		if auditErr := insertAuditEvent(ctx, tx, e); auditErr != nil {
+			return t, auditErr
+		}
+	}
+
+	sentErr := insertEvent(
+		ctx, tx, fromUserID, events.TypeTransferSent,
+		events.TransferSent{TransferID: t.ID, To: toLogin, Amount: amount},
+	)
+	if sentErr != nil {
+		return t, sentErr
+	}
+	receivedErr := insertEvent(
+		ctx, tx, t.ToUserID, events.TypeTransferReceived,
+		events.TransferReceived{TransferID: t.ID, From: fromLogin, Amount: amount},
+	)
+	if receivedErr != nil {
+		return t, receivedErr
+	}
+	tx.Commit()
+
+	return t, nil
+}
+
+func createTransfersTable(ctx context.Context, tx *sql.Tx) error {
+	_, transfersTableError := tx.ExecContext(
+		ctx,
+		`create table if not exists transfers(
+    			id bigserial primary key,
+    			from_user_id bigint NOT NULL,
+    			to_user_id bigint NOT NULL,
+    			amount double precision NOT NULL,
+    			created_at timestamp NOT NULL,
+    			constraint fk_from_user
+            		foreign key (from_user_id)
+                    references users(id),
+    			constraint fk_to_user
+            		foreign key (to_user_id)
+                    references users(id)
+			)`,
+	)
+	if transfersTableError != nil {
+		return transfersTableError
+	}
+
+	_, indexError := tx.ExecContext(
+		ctx, "create index if not exists transfers_from_user_id_created_at on transfers(from_user_id, created_at)",
+	)
+
+	return indexError
+}
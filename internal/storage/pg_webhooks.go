@@ -0,0 +1,341 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/models"
+)
+
+func (s PgWebhooksStorage) CreateEndpoint(ctx context.Context, endpoint models.WebhookEndpoint) (
+	models.WebhookEndpoint, error,
+) {
+	if endpoint.EventTypes == nil {
+		endpoint.EventTypes = []string{}
+	}
+	endpoint.Active = true
+	endpoint.CreatedAt = time.Now()
+
+	row := s.db.QueryRowContext(
+		ctx,
+		`insert into webhook_endpoints(user_id, url, secret, event_types, active, created_at)
+				values($1,$2,$3,$4,$5,$6) returning id`,
+		endpoint.UserID, endpoint.URL, endpoint.Secret, endpoint.EventTypes, endpoint.Active, endpoint.CreatedAt,
+	)
+
+	if scanErr := row.Scan(&endpoint.ID); scanErr != nil {
+		return endpoint, scanErr
+	}
+
+	return endpoint, nil
+}
+
+func (s PgWebhooksStorage) GetUserEndpoints(ctx context.Context, userID int64) ([]models.WebhookEndpoint, error) {
+	endpoints := make([]models.WebhookEndpoint, 0)
+
+	rows, rowsErr := s.db.QueryContext(
+		ctx,
+		`select id, user_id, url, event_types, active, created_at from webhook_endpoints
+                where user_id = $1 and active order by id`,
+		userID,
+	)
+	if rowsErr != nil {
+		return endpoints, rowsErr
+	}
+	if rows.Err() != nil {
+		return endpoints, rows.Err()
+	}
+	defer rows.Close()
+
+	typeMap := pgtype.NewMap()
+	for rows.Next() {
+		var e models.WebhookEndpoint
+		if scanErr := rows.Scan(
+			&e.ID, &e.UserID, &e.URL, typeMap.SQLScanner(&e.EventTypes), &e.Active, &e.CreatedAt,
+		); scanErr != nil {
+			return endpoints, scanErr
+		}
+		endpoints = append(endpoints, e)
+	}
+
+	return endpoints, nil
+}
+
+func (s PgWebhooksStorage) DeleteEndpoint(ctx context.Context, userID int64, ID int64) error {
+	result, updateErr := s.db.ExecContext(
+		ctx, "update webhook_endpoints set active = false where id = $1 and user_id = $2 and active", ID, userID,
+	)
+	if updateErr != nil {
+		return updateErr
+	}
+
+	affected, affectedErr := result.RowsAffected()
+	if affectedErr != nil {
+		return affectedErr
+	}
+	if affected < 1 {
+		return ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+func (s PgWebhooksStorage) GetGlobalEndpoints(ctx context.Context) ([]models.WebhookEndpoint, error) {
+	endpoints := make([]models.WebhookEndpoint, 0)
+
+	rows, rowsErr := s.db.QueryContext(
+		ctx,
+		`select id, user_id, url, event_types, active, created_at from webhook_endpoints
+                where user_id is null and active order by id`,
+	)
+	if rowsErr != nil {
+		return endpoints, rowsErr
+	}
+	if rows.Err() != nil {
+		return endpoints, rows.Err()
+	}
+	defer rows.Close()
+
+	typeMap := pgtype.NewMap()
+	for rows.Next() {
+		var e models.WebhookEndpoint
+		if scanErr := rows.Scan(
+			&e.ID, &e.UserID, &e.URL, typeMap.SQLScanner(&e.EventTypes), &e.Active, &e.CreatedAt,
+		); scanErr != nil {
+			return endpoints, scanErr
+		}
+		endpoints = append(endpoints, e)
+	}
+
+	return endpoints, nil
+}
+
+func (s PgWebhooksStorage) DeleteGlobalEndpoint(ctx context.Context, ID int64) error {
+	result, updateErr := s.db.ExecContext(
+		ctx, "update webhook_endpoints set active = false where id = $1 and user_id is null and active", ID,
+	)
+	if updateErr != nil {
+		return updateErr
+	}
+
+	affected, affectedErr := result.RowsAffected()
+	if affectedErr != nil {
+		return affectedErr
+	}
+	if affected < 1 {
+		return ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+func (s PgWebhooksStorage) GetEndpointAttempts(ctx context.Context, userID int64, endpointID int64, limit int) (
+	[]models.WebhookDeliveryAttempt, error,
+) {
+	attempts := make([]models.WebhookDeliveryAttempt, 0)
+
+	row := s.db.QueryRowContext(
+		ctx, "select id from webhook_endpoints where id = $1 and user_id = $2", endpointID, userID,
+	)
+	var foundID int64
+	if scanErr := row.Scan(&foundID); scanErr != nil {
+		if errors.Is(scanErr, sql.ErrNoRows) {
+			return attempts, ErrWebhookNotFound
+		}
+		return attempts, scanErr
+	}
+
+	rows, rowsErr := s.db.QueryContext(
+		ctx,
+		`select a.id, a.delivery_id, ev.id, ev.type, a.attempted_at, a.response_status, a.error, a.duration_ms
+				from webhook_delivery_attempts a
+				join webhook_deliveries d on d.id = a.delivery_id
+				join events ev on ev.id = d.event_id
+				where d.endpoint_id = $1 order by a.id desc limit $2`,
+		endpointID, limit,
+	)
+	if rowsErr != nil {
+		return attempts, rowsErr
+	}
+	if rows.Err() != nil {
+		return attempts, rows.Err()
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a models.WebhookDeliveryAttempt
+		if scanErr := rows.Scan(
+			&a.ID, &a.DeliveryID, &a.EventID, &a.EventType, &a.AttemptedAt, &a.ResponseStatus, &a.Error,
+			&a.DurationMs,
+		); scanErr != nil {
+			return attempts, scanErr
+		}
+		attempts = append(attempts, a)
+	}
+
+	return attempts, nil
+}
+
+// ClaimDueDeliveries leases due deliveries, so other dispatchers skip them until lease is over.
+func (s PgWebhooksStorage) ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) (
+	[]models.WebhookDelivery, error,
+) {
+	deliveries := make([]models.WebhookDelivery, 0)
+
+	rows, rowsErr := s.db.QueryContext(
+		ctx,
+		`with due as (
+					select id from webhook_deliveries
+					where status = $1 and next_attempt_at <= $2
+					order by next_attempt_at limit $3 for update skip locked
+				), claimed as (
+					update webhook_deliveries d set next_attempt_at = $4 from due where d.id = due.id
+					returning d.id, d.endpoint_id, d.event_id, d.attempts
+				)
+				select c.id, c.attempts, e.id, e.user_id, e.url, e.secret, ev.id, ev.user_id, ev.type, ev.payload,
+					ev.created_at
+				from claimed c
+				join webhook_endpoints e on e.id = c.endpoint_id
+				join events ev on ev.id = c.event_id`,
+		models.WebhookDeliveryPending, now, limit, now.Add(lease),
+	)
+	if rowsErr != nil {
+		return deliveries, rowsErr
+	}
+	if rows.Err() != nil {
+		return deliveries, rows.Err()
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var d models.WebhookDelivery
+		if scanErr := rows.Scan(
+			&d.ID, &d.Attempts, &d.Endpoint.ID, &d.Endpoint.UserID, &d.Endpoint.URL, &d.Endpoint.Secret, &d.Event.ID,
+			&d.Event.UserID, &d.Event.Type, &d.Event.Payload, &d.Event.CreatedAt,
+		); scanErr != nil {
+			return deliveries, scanErr
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, nil
+}
+
+func (s PgWebhooksStorage) RecordDeliveryAttempt(
+	ctx context.Context, attempt models.WebhookDeliveryAttempt, status string, nextAttemptAt time.Time,
+) error {
+	tx, txErr := s.db.BeginTx(ctx, nil)
+	if txErr != nil {
+		return txErr
+	}
+	defer tx.Rollback()
+
+	_, attemptErr := tx.ExecContext(
+		ctx,
+		`insert into webhook_delivery_attempts(delivery_id, attempted_at, response_status, error, duration_ms)
+				values($1,$2,$3,$4,$5)`,
+		attempt.DeliveryID, attempt.AttemptedAt, attempt.ResponseStatus, attempt.Error, attempt.DurationMs,
+	)
+	if attemptErr != nil {
+		return attemptErr
+	}
+
+	_, deliveryErr := tx.ExecContext(
+		ctx,
+		`update webhook_deliveries set attempts = attempts + 1, status = $1, next_attempt_at = $2,
+				last_attempt_at = $3 where id = $4`,
+		status, nextAttemptAt, attempt.AttemptedAt, attempt.DeliveryID,
+	)
+	if deliveryErr != nil {
+		return deliveryErr
+	}
+	tx.Commit()
+
+	return nil
+}
+
+// enqueueWebhookDeliveries is the outbox: deliveries are created in the same tx as the event itself.
+func enqueueWebhookDeliveries(ctx context.Context, tx *sql.Tx, eventID int64, userID int64, eventType string) error {
+	_, enqueueErr := tx.ExecContext(
+		ctx,
+		`insert into webhook_deliveries(endpoint_id, event_id, status, attempts, next_attempt_at)
+				select id, $1, $2, 0, $3 from webhook_endpoints
+				where active and (user_id is null or user_id = $4)
+				and (cardinality(event_types) = 0 or $5 = any(event_types))`,
+		eventID, models.WebhookDeliveryPending, time.Now(), userID, eventType,
+	)
+
+	return enqueueErr
+}
+
+func createWebhooksTables(ctx context.Context, tx *sql.Tx) error {
+	_, endpointsTableError := tx.ExecContext(
+		ctx,
+		`create table if not exists webhook_endpoints(
+    			id bigserial primary key,
+    			user_id bigint,
+    			url varchar(2048) NOT NULL,
+    			secret varchar(255) NOT NULL,
+    			event_types text[] NOT NULL default '{}',
+    			active boolean NOT NULL default true,
+    			created_at timestamp NOT NULL,
+    			constraint fk_user
+            		foreign key (user_id)
+                    references users(id)
+			)`,
+	)
+	if endpointsTableError != nil {
+		return endpointsTableError
+	}
+
+	_, deliveriesTableError := tx.ExecContext(
+		ctx,
+		`create table if not exists webhook_deliveries(
+    			id bigserial primary key,
+    			endpoint_id bigint NOT NULL,
+    			event_id bigint NOT NULL,
+    			status varchar(255) NOT NULL,
+    			attempts int NOT NULL default 0,
+    			next_attempt_at timestamp NOT NULL,
+    			last_attempt_at timestamp,
+    			constraint fk_endpoint
+            		foreign key (endpoint_id)
+                    references webhook_endpoints(id),
+    			constraint fk_event
+            		foreign key (event_id)
+                    references events(id)
+			)`,
+	)
+	if deliveriesTableError != nil {
+		return deliveriesTableError
+	}
+
+	_, deliveriesIndexError := tx.ExecContext(
+		ctx,
+		"create index if not exists webhook_deliveries_status_next on webhook_deliveries(status, next_attempt_at)",
+	)
+	if deliveriesIndexError != nil {
+		return deliveriesIndexError
+	}
+
+	_, attemptsTableError := tx.ExecContext(
+		ctx,
+		`create table if not exists webhook_delivery_attempts(
+    			id bigserial primary key,
+    			delivery_id bigint NOT NULL,
+    			attempted_at timestamp NOT NULL,
+    			response_status int,
+    			error text NOT NULL default '',
+    			duration_ms bigint NOT NULL,
+    			constraint fk_delivery
+            		foreign key (delivery_id)
+                    references webhook_deliveries(id)
+			)`,
+	)
+
+	return attemptsTableError
+}
@@ -3,39 +3,159 @@ package storage
 import (
 	"context"
 	"errors"
+	"time"
 
+	"github.com/bobgromozeka/yp-diploma1/internal/events"
 	"github.com/bobgromozeka/yp-diploma1/internal/models"
 )
 
 var (
-	ErrUserAlreadyExists   = errors.New("users already exists")
-	ErrUserNotFound        = errors.New("users not found")
-	ErrOrderNotFound       = errors.New("order not found")
-	ErrOrderAlreadyCreated = errors.New("order already created")
-	ErrOrderForeign        = errors.New("order foreign")
-	ErrInsufficientFunds   = errors.New("insufficient funds")
+	ErrUserAlreadyExists     = errors.New("users already exists")
+	ErrUserNotFound          = errors.New("users not found")
+	ErrOrderNotFound         = errors.New("order not found")
+	ErrOrderAlreadyCreated   = errors.New("order already created")
+	ErrOrderForeign          = errors.New("order foreign")
+	ErrOrderFinal            = errors.New("order is in final status")
+	ErrInsufficientFunds     = errors.New("insufficient funds")
+	ErrWebhookNotFound       = errors.New("webhook not found")
+	ErrAdjustmentNotFound    = errors.New("adjustment not found")
+	ErrAdjustmentDecided     = errors.New("adjustment is already approved or rejected")
+	ErrSelfApproval          = errors.New("adjustment can not be approved by its creator")
+	ErrPendingNotFound       = errors.New("pending accrual not found")
+	ErrPendingReleased       = errors.New("pending accrual is already released or clawed back")
+	ErrWithdrawalNotFound    = errors.New("withdrawal not found")
+	ErrHoldNotFound          = errors.New("hold not found")
+	ErrHoldClosed            = errors.New("hold is already captured, released or expired")
+	ErrRecipientNotFound     = errors.New("transfer recipient not found")
+	ErrSelfTransfer          = errors.New("points can not be transferred to yourself")
+	ErrTransferLimit         = errors.New("daily transfer limit exceeded")
+	ErrWrongSum              = errors.New("sum must be positive")
+	ErrAccrualResultNotFound = errors.New("accrual result not found")
 )
 
 type UsersStorage interface {
 	CreateUser(ctx context.Context, login string, password string) error
-	AuthUser(ctx context.Context, login string, password string) (int64, error)
+	AuthUser(ctx context.Context, login string, password string) (models.User, error)
+	GetUser(ctx context.Context, ID int64) (models.User, error)
+	FindUsers(ctx context.Context, loginPrefix string, limit int) ([]models.User, error)
+	SetUserRole(ctx context.Context, login string, role string) error
+	// RevokeRole makes users having role ordinary users, except keepLogins. Returns logins of demoted users.
+	RevokeRole(ctx context.Context, role string, keepLogins []string) ([]string, error)
+	// SetUserStore binds user to partner store, empty store means default one.
+	SetUserStore(ctx context.Context, login string, store string) error
+	// UnbindStores binds users to default store, except keepLogins. Returns logins of unbound users.
+	UnbindStores(ctx context.Context, keepLogins []string) ([]string, error)
 }
 
 type OrdersStorage interface {
 	CreateOrder(ctx context.Context, number string, userID int64) error
+	CreateOrders(ctx context.Context, numbers []string, userID int64) (map[string]string, error)
 	GetUserOrders(ctx context.Context, userID int64) ([]models.Order, error)
+	GetOrder(ctx context.Context, number string) (models.Order, error)
 	GetLatestUnprocessedOrders(ctx context.Context, count int) ([]models.Order, error)
-	UpdateOrderStatus(ctx context.Context, number string, status string, accrual *float64) error
+	UpdateOrderStatus(
+		ctx context.Context, number string, status string, accrual *float64, terms models.AccrualTerms,
+	) error
+	ClawbackPendingAccrual(ctx context.Context, number string, reason string) error
+	RequeueOrder(ctx context.Context, number string) error
+	ClaimPriorityChecks(ctx context.Context, numbers []string) ([]models.Order, error)
+	ClaimStalePriorityChecks(ctx context.Context, requestedBefore time.Time, limit int) ([]models.Order, error)
 }
 
 type WithdrawalsStorage interface {
 	Withdraw(ctx context.Context, userID int64, orderNumber string, sum float64) error
-	GetUserBalance(ctx context.Context, userID int64) (float64, float64, error)
+	GetUserBalance(ctx context.Context, userID int64) (models.Balance, error)
 	GetUserWithdrawals(ctx context.Context, userID int64) ([]models.Withdrawal, error)
+	ReverseWithdrawal(ctx context.Context, ID int64, reason string, actorID int64) (models.Withdrawal, error)
+}
+
+type AdjustmentsStorage interface {
+	CreateAdjustment(ctx context.Context, adjustment models.BalanceAdjustment, requireApproval bool) (
+		models.BalanceAdjustment, error,
+	)
+	ApproveAdjustment(ctx context.Context, ID int64, adminID int64) (models.BalanceAdjustment, error)
+	RejectAdjustment(ctx context.Context, ID int64, adminID int64) (models.BalanceAdjustment, error)
+	GetUserAdjustments(ctx context.Context, userID int64) ([]models.BalanceAdjustment, error)
+}
+
+type HoldsStorage interface {
+	CreateHold(ctx context.Context, userID int64, orderNumber string, amount float64, expiresAt time.Time) (
+		models.Hold, error,
+	)
+	CaptureHold(ctx context.Context, userID int64, ID int64) (models.Hold, error)
+	ReleaseHold(ctx context.Context, userID int64, ID int64) (models.Hold, error)
+	ReleaseExpiredHolds(ctx context.Context, now time.Time, limit int) (int, error)
+}
+
+type TransfersStorage interface {
+	Transfer(ctx context.Context, fromUserID int64, toLogin string, amount float64, dailyLimit float64) (
+		models.Transfer, error,
+	)
+}
+
+type PointsStorage interface {
+	GetExpiringPoints(ctx context.Context, userID int64, until time.Time) ([]models.ExpiringPoints, error)
+	ExpireLots(ctx context.Context, now time.Time, limit int) (int, error)
+	ReleasePendingAccruals(ctx context.Context, now time.Time, limit int) (int, error)
+}
+
+type AuditStorage interface {
+	RecordAuditEvent(ctx context.Context, e models.AuditEvent) error
+	FindAuditEvents(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error)
+}
+
+type StatementsStorage interface {
+	StreamUserStatement(
+		ctx context.Context, userID int64, from *time.Time, to *time.Time, fn func(e models.StatementEntry) error,
+	) error
+}
+
+type EventsStorage interface {
+	GetUserEventsAfter(ctx context.Context, userID int64, afterID int64) ([]events.Event, error)
+	Listen(ctx context.Context, handler func(e events.Event)) error
+}
+
+type WebhooksStorage interface {
+	CreateEndpoint(ctx context.Context, endpoint models.WebhookEndpoint) (models.WebhookEndpoint, error)
+	GetUserEndpoints(ctx context.Context, userID int64) ([]models.WebhookEndpoint, error)
+	DeleteEndpoint(ctx context.Context, userID int64, ID int64) error
+	GetGlobalEndpoints(ctx context.Context) ([]models.WebhookEndpoint, error)
+	DeleteGlobalEndpoint(ctx context.Context, ID int64) error
+	GetEndpointAttempts(ctx context.Context, userID int64, endpointID int64, limit int) (
+		[]models.WebhookDeliveryAttempt, error,
+	)
+	ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) (
+		[]models.WebhookDelivery, error,
+	)
+	RecordDeliveryAttempt(
+		ctx context.Context, attempt models.WebhookDeliveryAttempt, status string, nextAttemptAt time.Time,
+	) error
+}
+
+type AccrualResultsStorage interface {
+	SaveAccrualResult(ctx context.Context, r models.AccrualResult) error
+	GetAccrualResult(ctx context.Context, number string) (models.AccrualResult, error)
+	DeleteAccrualResult(ctx context.Context, r models.AccrualResult) error
+}
+
+type LocksStorage interface {
+	RunWithLock(ctx context.Context, key int64, checkInterval time.Duration, fn func(lockCtx context.Context)) (
+		bool, error,
+	)
 }
 
 type Factory interface {
 	CreateUsersStorage() UsersStorage
 	CreateOrdersStorage() OrdersStorage
 	CreateWithdrawalsStorage() WithdrawalsStorage
+	CreateAdjustmentsStorage() AdjustmentsStorage
+	CreateAuditStorage() AuditStorage
+	CreatePointsStorage() PointsStorage
+	CreateHoldsStorage() HoldsStorage
+	CreateTransfersStorage() TransfersStorage
+	CreateStatementsStorage() StatementsStorage
+	CreateEventsStorage() EventsStorage
+	CreateWebhooksStorage() WebhooksStorage
+	CreateAccrualResultsStorage() AccrualResultsStorage
+	CreateLocksStorage() LocksStorage
 }
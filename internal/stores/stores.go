@@ -0,0 +1,64 @@
+// Package stores binds partner store accounts to order number formats of their stores.
+package stores
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	"github.com/bobgromozeka/yp-diploma1/internal/orderformat"
+)
+
+var ErrWrongLogins = errors.New("wrong store logins")
+
+// ParseLogins reads logins of store accounts like "megastore=partner1,partner2;books=partner3" and returns store of
+// every login. Every store must be known to formats.
+func ParseLogins(spec string, formats *orderformat.Registry) (map[string]string, error) {
+	logins := make(map[string]string)
+
+	for _, storeSpec := range strings.Split(spec, ";") {
+		storeSpec = strings.TrimSpace(storeSpec)
+		if storeSpec == "" {
+			continue
+		}
+
+		store, loginsSpec, found := strings.Cut(storeSpec, "=")
+		store = strings.TrimSpace(store)
+		if !found || store == "" {
+			return nil, fmt.Errorf("%w: %q should be store=logins", ErrWrongLogins, storeSpec)
+		}
+		if !formats.Has(store) {
+			return nil, fmt.Errorf("%w: format of store %q is not configured", ErrWrongLogins, store)
+		}
+
+		for _, login := range strings.Split(loginsSpec, ",") {
+			login = strings.TrimSpace(login)
+			if login == "" {
+				continue
+			}
+			if boundStore, bound := logins[login]; bound && boundStore != store {
+				return nil, fmt.Errorf("%w: %q is bound to %s and %s", ErrWrongLogins, login, boundStore, store)
+			}
+			logins[login] = store
+		}
+	}
+
+	return logins, nil
+}
+
+// Of returns store which order numbers of user are validated in. Store is bound to account, so users can't choose
+// more lenient format. Users are not looked up when no partner stores are configured.
+func Of(ctx context.Context, d dependencies.D, userID int64) (string, error) {
+	if !d.OrderFormats.HasPartners() {
+		return orderformat.DefaultStore, nil
+	}
+
+	user, userErr := d.UsersStorage.GetUser(ctx, userID)
+	if userErr != nil {
+		return "", userErr
+	}
+
+	return user.Store, nil
+}
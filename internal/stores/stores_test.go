@@ -0,0 +1,71 @@
+package stores
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	"github.com/bobgromozeka/yp-diploma1/internal/models"
+	"github.com/bobgromozeka/yp-diploma1/internal/orderformat"
+	mockstorage "github.com/bobgromozeka/yp-diploma1/internal/storage/mock"
+	"github.com/bobgromozeka/yp-diploma1/internal/testutils"
+)
+
+func TestParseLogins(t *testing.T) {
+	formats, parseErr := orderformat.Parse("megastore=digits;books=regex:^BK[0-9]+$")
+	require.NoError(t, parseErr)
+
+	logins, loginsErr := ParseLogins(" megastore=partner1, partner2;books=partner3;", formats)
+	require.NoError(t, loginsErr)
+	assert.Equal(
+		t, map[string]string{"partner1": "megastore", "partner2": "megastore", "partner3": "books"}, logins,
+	)
+
+	logins, loginsErr = ParseLogins("", formats)
+	require.NoError(t, loginsErr)
+	assert.Empty(t, logins)
+}
+
+func TestParseWrongLogins(t *testing.T) {
+	formats, parseErr := orderformat.Parse("megastore=digits;books=regex:^BK[0-9]+$")
+	require.NoError(t, parseErr)
+
+	for _, spec := range []string{
+		"partner1",
+		"=partner1",
+		"unknown=partner1",
+		"megastore=partner1;books=partner1",
+	} {
+		_, loginsErr := ParseLogins(spec, formats)
+		assert.ErrorIs(t, loginsErr, ErrWrongLogins, spec)
+	}
+}
+
+func TestOf(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	formats, parseErr := orderformat.Parse("books=regex:^BK[0-9]+$")
+	require.NoError(t, parseErr)
+
+	uStorage := mockstorage.NewMockUsersStorage(ctrl)
+	uStorage.
+		EXPECT().
+		GetUser(testutils.MatchContext(), gomock.Eq(int64(1))).
+		Return(models.User{ID: 1, Login: "partner", Role: models.RoleUser, Store: "books"}, nil)
+
+	store, storeErr := Of(context.Background(), dependencies.D{UsersStorage: uStorage, OrderFormats: formats}, 1)
+	require.NoError(t, storeErr)
+	assert.Equal(t, "books", store)
+}
+
+func TestOfWithoutPartners(t *testing.T) {
+	//users storage must not be called
+	store, storeErr := Of(context.Background(), dependencies.D{}, 1)
+	require.NoError(t, storeErr)
+	assert.Equal(t, orderformat.DefaultStore, store)
+}
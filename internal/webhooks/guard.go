@@ -0,0 +1,65 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// ErrForbiddenAddress is returned for endpoints pointing to loopback, private, link-local or unspecified addresses.
+var ErrForbiddenAddress = errors.New("webhook endpoint address is not allowed")
+
+// IsForbiddenIP reports whether webhooks must not be delivered to ip, so users can't reach internal services.
+func IsForbiddenIP(ip net.IP) bool {
+	return ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast()
+}
+
+// CheckHost resolves host and returns ErrForbiddenAddress if any of its addresses is forbidden.
+func CheckHost(ctx context.Context, host string) error {
+	if ip := net.ParseIP(host); ip != nil {
+		if IsForbiddenIP(ip) {
+			return ErrForbiddenAddress
+		}
+		return nil
+	}
+
+	addrs, lookupErr := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if lookupErr != nil {
+		return lookupErr
+	}
+
+	for _, addr := range addrs {
+		if IsForbiddenIP(addr.IP) {
+			return ErrForbiddenAddress
+		}
+	}
+
+	return nil
+}
+
+// newGuardedDialer checks address right before connecting, host could resolve differently since registration.
+func newGuardedDialer() *net.Dialer {
+	return &net.Dialer{
+		Timeout: RequestTimeout,
+		Control: func(_ string, address string, _ syscall.RawConn) error {
+			host, _, splitErr := net.SplitHostPort(address)
+			if splitErr != nil {
+				return splitErr
+			}
+
+			ip := net.ParseIP(host)
+			if ip == nil || IsForbiddenIP(ip) {
+				return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+			}
+
+			return nil
+		},
+	}
+}
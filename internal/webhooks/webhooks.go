@@ -0,0 +1,220 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	"github.com/bobgromozeka/yp-diploma1/internal/models"
+)
+
+const (
+	EventHeader     = "X-Gophermart-Event"
+	DeliveryHeader  = "X-Gophermart-Delivery"
+	TimestampHeader = "X-Gophermart-Timestamp"
+	SignatureHeader = "X-Gophermart-Signature"
+)
+
+const (
+	RequestTimeout    = time.Second * 10
+	DeliveriesBatch   = 50
+	DeliveryWorkers   = 10
+	DeliveryLease     = time.Minute
+	MaxAttempts       = 8
+	BaseRetryDelay    = time.Second * 10
+	MaxRetryDelay     = time.Hour
+	DispatchInterval  = time.Second
+	maxLoggedBodySize = 256
+)
+
+type Dispatcher struct {
+	d   dependencies.D
+	c   *http.Client
+	now func() time.Time
+}
+
+type payload struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+func New(d dependencies.D) *Dispatcher {
+	return &Dispatcher{
+		d: d,
+		c: &http.Client{
+			Timeout:   RequestTimeout,
+			Transport: &http.Transport{DialContext: newGuardedDialer().DialContext},
+		},
+		now: time.Now,
+	}
+}
+
+func (dp *Dispatcher) SetClient(c *http.Client) {
+	dp.c = c
+}
+
+// Sign returns hex encoded HMAC-SHA256 of "timestamp.body". Receivers should compare it in constant time.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// RetryDelay grows exponentially with every failed attempt.
+func RetryDelay(attempts int) time.Duration {
+	delay := BaseRetryDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= MaxRetryDelay {
+			return MaxRetryDelay
+		}
+	}
+
+	return delay
+}
+
+// DispatchDue delivers one batch of due deliveries and returns how many of them were processed.
+func (dp *Dispatcher) DispatchDue(ctx context.Context) (int, error) {
+	deliveries, claimErr := dp.d.WebhooksStorage.ClaimDueDeliveries(ctx, dp.now(), DeliveryLease, DeliveriesBatch)
+	if claimErr != nil {
+		return 0, claimErr
+	}
+
+	deliveriesChan := make(chan models.WebhookDelivery)
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < DeliveryWorkers && i < len(deliveries); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for delivery := range deliveriesChan {
+				dp.dispatch(ctx, delivery)
+			}
+		}()
+	}
+
+	for _, delivery := range deliveries {
+		deliveriesChan <- delivery
+	}
+	close(deliveriesChan)
+	wg.Wait()
+
+	return len(deliveries), nil
+}
+
+func (dp *Dispatcher) dispatch(ctx context.Context, delivery models.WebhookDelivery) {
+	attempt := models.WebhookDeliveryAttempt{
+		DeliveryID:  delivery.ID,
+		EventID:     delivery.Event.ID,
+		EventType:   delivery.Event.Type,
+		AttemptedAt: dp.now(),
+	}
+
+	startedAt := time.Now()
+	responseStatus, sendErr := dp.send(ctx, delivery, attempt.AttemptedAt.Unix())
+	attempt.DurationMs = time.Since(startedAt).Milliseconds()
+	attempt.ResponseStatus = responseStatus
+
+	status := models.WebhookDeliveryDelivered
+	nextAttemptAt := attempt.AttemptedAt
+	if sendErr != nil {
+		attempt.Error = sendErr.Error()
+		status = models.WebhookDeliveryPending
+		nextAttemptAt = attempt.AttemptedAt.Add(RetryDelay(delivery.Attempts + 1))
+		if delivery.Attempts+1 >= MaxAttempts {
+			status = models.WebhookDeliveryFailed
+		}
+	}
+
+	recordErr := dp.d.WebhooksStorage.RecordDeliveryAttempt(ctx, attempt, status, nextAttemptAt)
+	if recordErr != nil {
+		dp.d.Logger.Errorw("Could not record webhook delivery attempt", "error", recordErr, "attempt", attempt)
+	}
+}
+
+func (dp *Dispatcher) send(ctx context.Context, delivery models.WebhookDelivery, timestamp int64) (*int, error) {
+	body, encodeErr := json.Marshal(
+		payload{
+			ID:        delivery.Event.ID,
+			Type:      delivery.Event.Type,
+			CreatedAt: delivery.Event.CreatedAt,
+			Data:      delivery.Event.Payload,
+		},
+	)
+	if encodeErr != nil {
+		return nil, encodeErr
+	}
+
+	req, reqErr := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Endpoint.URL, bytes.NewReader(body))
+	if reqErr != nil {
+		return nil, reqErr
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, delivery.Event.Type)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(delivery.Endpoint.Secret, timestamp, body))
+
+	resp, respErr := dp.c.Do(req)
+	if respErr != nil {
+		return nil, respErr
+	}
+	defer resp.Body.Close()
+
+	statusCode := resp.StatusCode
+	if statusCode < 200 || statusCode > 299 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxLoggedBodySize))
+		return &statusCode, fmt.Errorf("unexpected response status %d: %s", statusCode, respBody)
+	}
+
+	return &statusCode, nil
+}
+
+// Start dispatches deliveries until shutdownCtx is done. Batch being dispatched is finished after that
+// unless forceCtx is done as well, so attempts are sent and recorded.
+func (dp *Dispatcher) Start(shutdownCtx context.Context, forceCtx context.Context) {
+	for {
+		dispatched, dispatchErr := dp.DispatchDue(forceCtx)
+		if dispatchErr != nil && forceCtx.Err() == nil {
+			dp.d.Logger.Errorw("Could not dispatch webhooks", "error", dispatchErr)
+		}
+
+		if shutdownCtx.Err() != nil {
+			break
+		}
+
+		//full batch means there are more due deliveries, so no need to wait
+		if dispatched == DeliveriesBatch {
+			continue
+		}
+
+		select {
+		case <-shutdownCtx.Done():
+		case <-time.After(DispatchInterval):
+		}
+	}
+}
+
+func Run(shutdownCtx context.Context, forceCtx context.Context, d dependencies.D) {
+	dp := New(d)
+
+	dp.Start(shutdownCtx, forceCtx)
+
+	d.Logger.Info("Stopping webhooks dispatcher.....")
+}
@@ -0,0 +1,197 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/bobgromozeka/yp-diploma1/internal/app/dependencies"
+	"github.com/bobgromozeka/yp-diploma1/internal/events"
+	"github.com/bobgromozeka/yp-diploma1/internal/models"
+	mock_storage "github.com/bobgromozeka/yp-diploma1/internal/storage/mock"
+	"github.com/bobgromozeka/yp-diploma1/internal/testutils"
+)
+
+func TestDispatchDue(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	secret := "0123456789abcdef"
+	now := time.Date(2023, 9, 1, 12, 0, 0, 0, time.UTC)
+	event := events.Event{
+		ID:        10,
+		UserID:    1,
+		Type:      events.TypeBalanceCredited,
+		Payload:   json.RawMessage(`{"order":"4561261212345467","amount":500}`),
+		CreatedAt: now,
+	}
+
+	receivedMu := sync.Mutex{}
+	var received []*http.Request
+	var receivedBodies [][]byte
+	receiver := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				receivedMu.Lock()
+				received = append(received, r)
+				receivedBodies = append(receivedBodies, body)
+				receivedMu.Unlock()
+				w.WriteHeader(http.StatusOK)
+			},
+		),
+	)
+	defer receiver.Close()
+
+	failingReceiver := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+		),
+	)
+	defer failingReceiver.Close()
+
+	deliveries := []models.WebhookDelivery{
+		{
+			ID:       1,
+			Attempts: 0,
+			Endpoint: models.WebhookEndpoint{ID: 1, URL: receiver.URL, Secret: secret},
+			Event:    event,
+		},
+		{
+			ID:       2,
+			Attempts: 2,
+			Endpoint: models.WebhookEndpoint{ID: 2, URL: failingReceiver.URL, Secret: secret},
+			Event:    event,
+		},
+		{
+			ID:       3,
+			Attempts: MaxAttempts - 1,
+			Endpoint: models.WebhookEndpoint{ID: 2, URL: failingReceiver.URL, Secret: secret},
+			Event:    event,
+		},
+	}
+
+	wStorage := mock_storage.NewMockWebhooksStorage(ctrl)
+	wStorage.
+		EXPECT().
+		ClaimDueDeliveries(testutils.MatchContext(), gomock.Eq(now), gomock.Eq(DeliveryLease), gomock.Eq(DeliveriesBatch)).
+		Return(deliveries, nil)
+
+	type recorded struct {
+		attempt       models.WebhookDeliveryAttempt
+		status        string
+		nextAttemptAt time.Time
+	}
+	recordedMu := sync.Mutex{}
+	recordedAttempts := map[int64]recorded{}
+	wStorage.
+		EXPECT().
+		RecordDeliveryAttempt(testutils.MatchContext(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Times(3).
+		DoAndReturn(
+			func(_ context.Context, attempt models.WebhookDeliveryAttempt, status string, next time.Time) error {
+				recordedMu.Lock()
+				recordedAttempts[attempt.DeliveryID] = recorded{attempt, status, next}
+				recordedMu.Unlock()
+				return nil
+			},
+		)
+
+	d := dependencies.D{
+		WebhooksStorage: wStorage,
+		Logger:          zap.NewExample().Sugar(),
+	}
+
+	dp := New(d)
+	//receivers listen on loopback which is refused by dispatcher's own client
+	dp.SetClient(http.DefaultClient)
+	dp.now = func() time.Time {
+		return now
+	}
+
+	dispatched, dispatchErr := dp.DispatchDue(context.Background())
+	assert.NoError(t, dispatchErr)
+	assert.Equal(t, 3, dispatched)
+
+	if assert.Len(t, received, 1) {
+		r := received[0]
+		assert.Equal(t, events.TypeBalanceCredited, r.Header.Get(EventHeader))
+		assert.Equal(t, "1", r.Header.Get(DeliveryHeader))
+		assert.Equal(t, strconv.FormatInt(now.Unix(), 10), r.Header.Get(TimestampHeader))
+		assert.Equal(t, Sign(secret, now.Unix(), receivedBodies[0]), r.Header.Get(SignatureHeader))
+		assert.JSONEq(
+			t,
+			`{"id":10,"type":"balance.credited","created_at":"2023-09-01T12:00:00Z",
+			"data":{"order":"4561261212345467","amount":500}}`,
+			string(receivedBodies[0]),
+		)
+	}
+
+	assert.Equal(t, models.WebhookDeliveryDelivered, recordedAttempts[1].status)
+	assert.Equal(t, http.StatusOK, *recordedAttempts[1].attempt.ResponseStatus)
+	assert.Empty(t, recordedAttempts[1].attempt.Error)
+
+	assert.Equal(t, models.WebhookDeliveryPending, recordedAttempts[2].status)
+	assert.Equal(t, now.Add(RetryDelay(3)), recordedAttempts[2].nextAttemptAt)
+	assert.Equal(t, http.StatusInternalServerError, *recordedAttempts[2].attempt.ResponseStatus)
+	assert.NotEmpty(t, recordedAttempts[2].attempt.Error)
+
+	assert.Equal(t, models.WebhookDeliveryFailed, recordedAttempts[3].status)
+}
+
+func TestRetryDelay(t *testing.T) {
+	assert.Equal(t, BaseRetryDelay, RetryDelay(1))
+	assert.Equal(t, BaseRetryDelay*4, RetryDelay(3))
+	assert.Equal(t, MaxRetryDelay, RetryDelay(50))
+}
+
+func TestSign(t *testing.T) {
+	assert.Equal(
+		t,
+		"sha256=cbdbaf976f09cb45915b74bbbd4bb7349363bc7b173c2a3b9067ea37c7d7098b",
+		Sign("secret", 1693569600, []byte(`{}`)),
+	)
+}
+
+func TestDispatcherRefusesForbiddenAddresses(t *testing.T) {
+	receiver := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			},
+		),
+	)
+	defer receiver.Close()
+
+	dp := New(dependencies.D{Logger: zap.NewExample().Sugar()})
+
+	delivery := models.WebhookDelivery{
+		ID:       1,
+		Event:    events.Event{ID: 10, Type: events.TypeBalanceCredited, Payload: json.RawMessage(`{}`)},
+		Endpoint: models.WebhookEndpoint{URL: receiver.URL, Secret: "0123456789abcdef"},
+	}
+
+	responseStatus, sendErr := dp.send(context.Background(), delivery, time.Now().Unix())
+	assert.Nil(t, responseStatus)
+	assert.ErrorIs(t, sendErr, ErrForbiddenAddress)
+}
+
+func TestCheckHost(t *testing.T) {
+	for _, host := range []string{"127.0.0.1", "localhost", "10.1.2.3", "192.168.0.1", "169.254.169.254", "0.0.0.0", "::1"} {
+		assert.ErrorIs(t, CheckHost(context.Background(), host), ErrForbiddenAddress, host)
+	}
+
+	assert.NoError(t, CheckHost(context.Background(), "203.0.113.10"))
+}